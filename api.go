@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"simple-plan/plan"
+)
+
+// Обмеження API рендерингу.
+const (
+	apiMaxDPI = 600.0
+	apiMinDPI = 10.0
+	// apiMaxPixels - найбільша площа растру в пікселях (A4 приблизно при 450 dpi)
+	apiMaxPixels = 20_000_000
+)
+
+// apiServer - HTTP API для перетворення HTML/SVG планів у SVG, PNG або PDF.
+type apiServer struct {
+	maxBody int64
+	timeout time.Duration
+	// sem обмежує кількість одночасних обробок: розбір, віддзеркалення і рендеринг (rsvg-convert чи oksvg)
+	sem chan struct{}
+}
+
+// apiErrorBody - структура JSON-відповіді з помилкою.
+type apiErrorBody struct {
+	Error apiError `json:"error"`
+}
+
+// apiError описує помилку: машиночитаний код і людське повідомлення.
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// newAPIServer створює API з лімітом розміру тіла, тайм-аутом і кількістю паралельних рендерингів.
+func newAPIServer(maxBody int64, timeout time.Duration, concurrency int) *apiServer {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &apiServer{
+		maxBody: maxBody,
+		timeout: timeout,
+		sem:     make(chan struct{}, concurrency),
+	}
+}
+
+// handler повертає маршрути API.
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/render", s.handleRender)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"status": "ok", "rsvg": plan.RsvgAvailable()})
+	})
+	return mux
+}
+
+// writeAPIError надсилає JSON-помилку з відповідним HTTP-статусом.
+func writeAPIError(w http.ResponseWriter, status int, code, format string, args ...any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(apiErrorBody{apiError{Code: code, Message: fmt.Sprintf(format, args...)}})
+}
+
+// handleRender приймає HTML або SVG у тілі POST-запиту.
+// Параметри запиту: selector (простий CSS-селектор, за замовчуванням svg),
+// format (svg, png, pdf; за замовчуванням png), dpi (за замовчуванням 96), mirror (true/false),
+// mirror_numbers (keep, renumber - номери дверей після віддзеркалення).
+func (s *apiServer) handleRender(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeAPIError(w, http.StatusMethodNotAllowed, "method_not_allowed", "дозволено лише POST")
+		return
+	}
+
+	q := r.URL.Query()
+	selector := q.Get("selector")
+	if selector == "" {
+		selector = "svg"
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = "png"
+	}
+	if format != "svg" && format != "png" && format != "pdf" {
+		writeAPIError(w, http.StatusBadRequest, "bad_request", "невідомий формат %q (допустимі: svg, png, pdf)", format)
+		return
+	}
+	dpi := 96.0
+	if v := q.Get("dpi"); v != "" {
+		d, err := strconv.ParseFloat(v, 64)
+		if err != nil || d < apiMinDPI || d > apiMaxDPI {
+			writeAPIError(w, http.StatusBadRequest, "bad_request", "dpi має бути числом від %v до %v", apiMinDPI, apiMaxDPI)
+			return
+		}
+		dpi = d
+	}
+	mirror := false
+	if v := q.Get("mirror"); v != "" {
+		m, err := strconv.ParseBool(v)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "bad_request", "mirror має бути true або false")
+			return
+		}
+		mirror = m
+	}
+	numbers := q.Get("mirror_numbers")
+	if numbers != "" && !slices.Contains(plan.MirrorNumberings, numbers) {
+		writeAPIError(w, http.StatusBadRequest, "bad_request", "mirror_numbers має бути одним із: %s", strings.Join(plan.MirrorNumberings, ", "))
+		return
+	}
+	if numbers != "" && !mirror {
+		writeAPIError(w, http.StatusBadRequest, "bad_request", "mirror_numbers має сенс лише разом з mirror=true")
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeAPIError(w, http.StatusRequestEntityTooLarge, "payload_too_large", "тіло запиту перевищує %d байт", s.maxBody)
+			return
+		}
+		writeAPIError(w, http.StatusBadRequest, "bad_request", "помилка читання тіла запиту: %v", err)
+		return
+	}
+
+	// Слот і тайм-аут беруться до розбору: великий чи складний план не повинен обходити ліміти
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+	if !s.acquire(ctx) {
+		writeAPIError(w, http.StatusServiceUnavailable, "busy", "усі слоти рендерингу зайняті, спробуйте пізніше")
+		return
+	}
+	defer func() { <-s.sem }()
+
+	d, err := plan.Extractor{Selector: selector, InjectSymbols: true}.Extract(ctx, bytes.NewReader(data))
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		s.writeTimeout(w)
+		return
+	case errors.Is(err, plan.ErrNoSVG):
+		writeAPIError(w, http.StatusUnprocessableEntity, "no_svg", "не знайдено SVG за селектором %q", selector)
+		return
+	case err != nil:
+		writeAPIError(w, http.StatusUnprocessableEntity, "parse_error", "%v", err)
+		return
+	}
+	if mirror {
+		if _, err := plan.MirrorNumbered(ctx, d, numbers); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				s.writeTimeout(w)
+				return
+			}
+			writeAPIError(w, http.StatusUnprocessableEntity, "mirror_failed", "%v", err)
+			return
+		}
+	}
+
+	var svgBuf bytes.Buffer
+	if err := (plan.Serializer{}).Serialize(ctx, &svgBuf, d); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			s.writeTimeout(w)
+			return
+		}
+		writeAPIError(w, http.StatusInternalServerError, "render_failed", "%v", err)
+		return
+	}
+	if format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+		w.Write(svgBuf.Bytes())
+		return
+	}
+
+	// Розмір растру перевіряється до рендерингу, щоб великий viewBox чи dpi не вичерпали пам'ять
+	width, height, ok := plan.PixelSize(d, dpi)
+	if !ok {
+		writeAPIError(w, http.StatusUnprocessableEntity, "parse_error", "не вдалося визначити розмір SVG (немає width/height чи viewBox)")
+		return
+	}
+	if width <= 0 || height <= 0 || float64(width)*float64(height) > apiMaxPixels {
+		writeAPIError(w, http.StatusRequestEntityTooLarge, "image_too_large",
+			"растр %dx%d перевищує ліміт %d пікселів; зменште dpi", width, height, apiMaxPixels)
+		return
+	}
+
+	out, contentType, err := s.rasterize(ctx, svgBuf.Bytes(), format, dpi, width)
+	if err != nil {
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			s.writeTimeout(w)
+		case errors.Is(err, plan.ErrRendererMissing):
+			writeAPIError(w, http.StatusNotImplemented, "renderer_missing", "%v", err)
+		default:
+			writeAPIError(w, http.StatusInternalServerError, "render_failed", "%v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(out)
+}
+
+// acquire займає слот обробки: вільний - одразу, інакше чекає не довше за тайм-аут запиту.
+func (s *apiServer) acquire(ctx context.Context) bool {
+	select {
+	case s.sem <- struct{}{}:
+		return true
+	default:
+	}
+	select {
+	case s.sem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// writeTimeout повідомляє, що запит не встиг обробитися за тайм-аут.
+func (s *apiServer) writeTimeout(w http.ResponseWriter) {
+	writeAPIError(w, http.StatusGatewayTimeout, "timeout", "рендеринг не завершився за %s", s.timeout)
+}
+
+// errPDFUnsupported повертається, коли PDF запитано без встановленого rsvg-convert.
+var errPDFUnsupported = plan.Errorf("%w: PDF потребує rsvg-convert, який не встановлено на сервері", plan.ErrRendererMissing)
+
+// rasterize перетворює серіалізований SVG у PNG або PDF; width - ширина PNG у пікселях.
+func (s *apiServer) rasterize(ctx context.Context, svgData []byte, format string, dpi float64, width int) ([]byte, string, error) {
+	contentType := "image/png"
+	if format == plan.FormatPDF {
+		contentType = "application/pdf"
+	}
+
+	opts := plan.RenderOptions{Format: format, DPI: dpi}
+	if format == plan.FormatPNG {
+		opts.Width = width
+	}
+
+	renderer := plan.DefaultRenderer()
+	if _, ok := renderer.(plan.OksvgRenderer); ok && format == plan.FormatPDF {
+		return nil, "", errPDFUnsupported
+	}
+	var buf bytes.Buffer
+	if err := renderer.Render(ctx, &buf, bytes.NewReader(svgData), opts); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), contentType, nil
+}
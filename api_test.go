@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// apiPlan - найменший HTML з планом для запитів до API.
+const apiPlan = `<html><body><div id="plan"><svg viewBox="0 0 200 100">
+	<style>.wall { stroke: #000; stroke-width: 4; } .doors { stroke: #FFF; stroke-width: 6; } .door-number { font-size: 10px; }</style>
+	<polyline class="wall" points="0,0 200,0 200,100 0,100 0,0"/>
+	<line class="doors" x1="40" y1="100" x2="70" y2="100"/>
+	<text class="door-number" x="50" y="90">1</text>
+</svg></div></body></html>`
+
+// renderRequest виконує запит до API і повертає відповідь.
+func renderRequest(t *testing.T, s *apiServer, method, query, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/render?"+query, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, req)
+	return rec
+}
+
+// apiErrorCode повертає код помилки з JSON-відповіді.
+func apiErrorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body apiErrorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("відповідь не є JSON-помилкою: %q", rec.Body.String())
+	}
+	return body.Error.Code
+}
+
+func TestAPIRenderErrors(t *testing.T) {
+	cases := []struct {
+		name, method, query, body string
+		status                    int
+		code                      string
+	}{
+		{"метод", http.MethodGet, "", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"формат", http.MethodPost, "format=bmp", apiPlan, http.StatusBadRequest, "bad_request"},
+		{"dpi", http.MethodPost, "dpi=5", apiPlan, http.StatusBadRequest, "bad_request"},
+		{"mirror", http.MethodPost, "mirror=maybe", apiPlan, http.StatusBadRequest, "bad_request"},
+		{"mirror_numbers", http.MethodPost, "mirror=true&mirror_numbers=shuffle", apiPlan, http.StatusBadRequest, "bad_request"},
+		{"mirror_numbers без mirror", http.MethodPost, "mirror_numbers=keep", apiPlan, http.StatusBadRequest, "bad_request"},
+		{"завелике тіло", http.MethodPost, "", apiPlan + strings.Repeat(" ", 4096), http.StatusRequestEntityTooLarge, "payload_too_large"},
+		{"завеликий растр", http.MethodPost, "dpi=600", `<svg width="5000" height="5000"></svg>`, http.StatusRequestEntityTooLarge, "image_too_large"},
+		{"без SVG", http.MethodPost, "", "<html><body>план</body></html>", http.StatusUnprocessableEntity, "no_svg"},
+		{"селектор", http.MethodPost, "selector=%23nope", apiPlan, http.StatusUnprocessableEntity, "no_svg"},
+	}
+	s := newAPIServer(int64(len(apiPlan)+100), 10*time.Second, 1)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := renderRequest(t, s, c.method, c.query, c.body)
+			if rec.Code != c.status || apiErrorCode(t, rec) != c.code {
+				t.Errorf("статус %d, відповідь %s; очікувалось %d %s", rec.Code, rec.Body.String(), c.status, c.code)
+			}
+		})
+	}
+	if len(s.sem) != 0 {
+		t.Errorf("після запитів зайнято %d слотів", len(s.sem))
+	}
+}
+
+func TestAPIRenderBusy(t *testing.T) {
+	s := newAPIServer(1<<20, 50*time.Millisecond, 1)
+	s.sem <- struct{}{}
+	// Розбір теж чекає на слот, тож навіть SVG без растеризації отримує 503
+	rec := renderRequest(t, s, http.MethodPost, "format=svg", apiPlan)
+	if rec.Code != http.StatusServiceUnavailable || apiErrorCode(t, rec) != "busy" {
+		t.Errorf("статус %d, відповідь %s; очікувалось 503 busy", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAPIRenderTimeout(t *testing.T) {
+	// Тайм-аут діє з початку розбору, а не лише на растеризацію
+	s := newAPIServer(1<<20, time.Nanosecond, 1)
+	rec := renderRequest(t, s, http.MethodPost, "format=svg", apiPlan)
+	if rec.Code != http.StatusGatewayTimeout || apiErrorCode(t, rec) != "timeout" {
+		t.Errorf("статус %d, відповідь %s; очікувалось 504 timeout", rec.Code, rec.Body.String())
+	}
+	if len(s.sem) != 0 {
+		t.Error("слот не звільнено після тайм-ауту")
+	}
+}
+
+func TestAPIRenderMirrorNumbers(t *testing.T) {
+	s := newAPIServer(1<<20, 10*time.Second, 1)
+	for _, numbers := range []string{"", "keep", "renumber"} {
+		rec := renderRequest(t, s, http.MethodPost, "format=svg&selector=%23plan&mirror=true&mirror_numbers="+numbers, apiPlan)
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "image/svg+xml") {
+			t.Errorf("mirror_numbers=%q: статус %d, %s", numbers, rec.Code, rec.Body.String())
+			continue
+		}
+		if !strings.Contains(rec.Body.String(), `class="door-number"`) {
+			t.Errorf("mirror_numbers=%q: номер дверей зник", numbers)
+		}
+	}
+
+	rec := renderRequest(t, s, http.MethodPost, "format=png&dpi=48", apiPlan)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("PNG: статус %d, %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"simple-plan/plan"
+)
+
+const (
+	// manifestFilename - маніфест проєкту, який збирається за замовчуванням
+	manifestFilename = "simple-plan.json"
+	// buildCacheDir - кеш збірки (поруч з маніфестом): svg/ - витягнуті плани,
+	// render/ - растри та PDF, state.json - ключі останньої збірки
+	buildCacheDir = ".simple-plan-cache"
+	// buildCacheVersion змінюється разом з форматом кешу; зміни обробки планів між збірками
+	// програми відстежує buildToolKey
+	buildCacheVersion = "1"
+)
+
+// buildState - ключі вхідних даних на момент останньої збірки.
+type buildState struct {
+	// Plans - назва плану → ключ витягнутого SVG
+	Plans map[string]string `json:"plans"`
+	// Outputs - шлях виходу → ключ його вмісту
+	Outputs map[string]string `json:"outputs"`
+}
+
+// buildOptions - параметри збірки маніфесту.
+type buildOptions struct {
+	only  []string
+	force bool   // ігнорувати кеш і стан, перезібрати все
+	prune bool   // після збірки видалити з кешу записи, не потрібні поточному маніфесту
+	cache string // каталог кешу; порожній - buildCacheDir поруч з маніфестом
+}
+
+// buildCache - кеш витягнутих SVG і відрендерених файлів з ключами-хешами.
+type buildCache struct {
+	dir      string
+	renderer plan.Renderer
+	version  string // версія рендерера, входить у ключі растрів
+	tool     string // версія програми і бібліотеки символів, входить у всі ключі
+	force    bool
+}
+
+// runBuild збирає плани з маніфесту.
+func runBuild(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	manifest := fs.String("manifest", manifestFilename, "JSON маніфест проєкту")
+	only := fs.String("only", "", "зібрати лише ці плани (назви через кому)")
+	force := fs.Bool("force", false, "перезібрати все, не зважаючи на кеш")
+	prune := fs.Bool("prune", false, "видалити з кешу записи, не потрібні поточному маніфесту")
+	cache := fs.String("cache", "", "каталог кешу (за замовчуванням "+buildCacheDir+" поруч з маніфестом)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	return buildManifest(*manifest, buildOptions{only: splitList(*only), force: *force, prune: *prune, cache: *cache})
+}
+
+// buildManifest збирає плани маніфесту. Вихід перезаписується лише тоді, коли змінився
+// його ключ (файл плану, опис у маніфесті, шаблон, параметри, версія рендерера, ревізія програми
+// і бібліотека символів) або файл відсутній;
+// витягнуті SVG і відрендерені файли беруться з кешу, якщо їх уже було зібрано.
+func buildManifest(filename string, opts buildOptions) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return plan.Errorf("помилка читання маніфесту: %w", err)
+	}
+	m, err := plan.LoadManifest(f)
+	f.Close()
+	if err != nil {
+		return plan.Errorf("%s: %w", filename, err)
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range opts.only {
+		selected[name] = true
+	}
+	for name := range selected {
+		found := false
+		for _, p := range m.Plans {
+			found = found || p.Name == name
+		}
+		if !found {
+			return usageErrorf("план %q відсутній у маніфесті %s", name, filename)
+		}
+	}
+
+	dir := filepath.Dir(filename)
+	cacheDir := opts.cache
+	if cacheDir == "" {
+		cacheDir = filepath.Join(dir, buildCacheDir)
+	}
+	renderer := plan.DefaultRenderer()
+	cache := &buildCache{dir: cacheDir, renderer: renderer, version: rendererVersion(renderer), tool: buildToolKey(), force: opts.force}
+	statePath := filepath.Join(cacheDir, "state.json")
+	state := loadBuildState(statePath)
+
+	if !plan.RsvgAvailable() {
+		slog.Warn("rsvg-convert не встановлено: PNG буде створено через oksvg (без тексту), PDF недоступний")
+	}
+
+	built, skipped := 0, 0
+	for _, p := range m.Plans {
+		if len(selected) > 0 && !selected[p.Name] {
+			continue
+		}
+		rebuilt, err := cache.buildPlan(p, dir, state)
+		if err != nil {
+			return plan.Errorf("план %q: %w", p.Name, err)
+		}
+		if !rebuilt {
+			skipped++
+			slog.Debug("план без змін", "plan", p.Name)
+			continue
+		}
+		built++
+		// Стан зберігаємо після кожного плану, щоб перервана збірка не повторювала готове
+		if err := saveBuildState(statePath, state); err != nil {
+			return err
+		}
+	}
+	slog.Info("збірку завершено", "built", built, "unchanged", skipped)
+
+	if opts.prune {
+		removed, err := cache.prune(m, dir, state)
+		if err != nil {
+			return err
+		}
+		if err := saveBuildState(statePath, state); err != nil {
+			return err
+		}
+		slog.Info("кеш очищено", "removed", removed)
+	}
+	return nil
+}
+
+// buildPlan збирає виходи одного плану і повертає, чи було перезаписано хоч один файл.
+func (c *buildCache) buildPlan(p plan.PlanSpec, dir string, state *buildState) (bool, error) {
+	source, err := os.ReadFile(resolvePath(dir, p.Source))
+	if err != nil {
+		return false, plan.Errorf("помилка читання файлу плану: %w", err)
+	}
+	var tmplText string
+	if p.Title != nil && p.Title.Template != "" {
+		if tmplText, err = plan.LoadTemplate(resolvePath(dir, p.Title.Template)); err != nil {
+			return false, err
+		}
+	}
+
+	// Ключ SVG не залежить від виходів: зміна розміру PNG не потребує повторного витягнення
+	spec := p
+	spec.Outputs = nil
+	specJSON, _ := json.Marshal(spec)
+	svgKey := hashParts(buildCacheVersion, c.tool, string(specJSON), string(source), tmplText)
+	state.Plans[p.Name] = svgKey
+
+	var svg []byte
+	extract := func() error {
+		if svg != nil {
+			return nil
+		}
+		var err error
+		svg, err = c.get("svg", svgKey+".svg", func() ([]byte, error) {
+			ctx := context.Background()
+			d, err := p.Build(ctx, bytes.NewReader(source), tmplText)
+			if err != nil {
+				return nil, err
+			}
+			var buf bytes.Buffer
+			if err := (plan.Serializer{}).Serialize(ctx, &buf, d); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		})
+		return err
+	}
+
+	rebuilt := false
+	for _, o := range p.Outputs {
+		path := resolvePath(dir, o.Path)
+		key := c.outputKey(svgKey, o)
+		if !c.force && state.Outputs[path] == key {
+			if _, err := os.Stat(path); err == nil {
+				continue
+			}
+		}
+
+		if err := extract(); err != nil {
+			return false, err
+		}
+		data := svg
+		switch o.Format {
+		case plan.FormatSVG:
+			if o.Optimize == nil {
+				break
+			}
+			data, err = c.get("export", key+".svg", func() ([]byte, error) {
+				ctx := context.Background()
+				d, err := plan.Extractor{}.Extract(ctx, bytes.NewReader(svg))
+				if err != nil {
+					return nil, err
+				}
+				var buf bytes.Buffer
+				if err := (plan.Serializer{Optimize: o.Optimize}).Serialize(ctx, &buf, d); err != nil {
+					return nil, plan.Errorf("%s: %w", o.Path, err)
+				}
+				return buf.Bytes(), nil
+			})
+			if err != nil {
+				return false, err
+			}
+		case plan.FormatDXF, plan.FormatGeoJSON:
+			data, err = c.get("export", key+"."+o.Format, func() ([]byte, error) {
+				ctx := context.Background()
+				d, err := plan.Extractor{}.Extract(ctx, bytes.NewReader(svg))
+				if err != nil {
+					return nil, err
+				}
+				var buf bytes.Buffer
+				if o.Format == plan.FormatGeoJSON {
+					var ref plan.GeoReference
+					if o.Geo != nil {
+						ref = *o.Geo
+					}
+					err = plan.ExportGeoJSON(ctx, &buf, d, ref)
+				} else {
+					err = plan.ExportDXF(ctx, &buf, d, plan.DXFExportOptions{Units: o.Units})
+				}
+				if err != nil {
+					return nil, plan.Errorf("%s: %w", o.Path, err)
+				}
+				return buf.Bytes(), nil
+			})
+			if err != nil {
+				return false, err
+			}
+		default:
+			data, err = c.get("render", key+"."+o.Format, func() ([]byte, error) {
+				var buf bytes.Buffer
+				if err := c.renderer.Render(context.Background(), &buf, bytes.NewReader(svg), o.RenderOptions()); err != nil {
+					return nil, plan.Errorf("%s: %w", o.Path, err)
+				}
+				return buf.Bytes(), nil
+			})
+			if err != nil {
+				return false, err
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return false, plan.Errorf("%w: помилка створення каталогу для %s: %w", plan.ErrWrite, o.Path, err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return false, plan.Errorf("%w файлу %s: %w", plan.ErrWrite, o.Path, err)
+		}
+		state.Outputs[path] = key
+		rebuilt = true
+		slog.Info("вихід збережено", "plan", p.Name, "file", o.Path)
+	}
+	return rebuilt, nil
+}
+
+// outputKey повертає ключ вмісту виходу: для SVG - ключ плану (з параметрами оптимізації), для DXF - ще й одиниці,
+// для GeoJSON - географічна прив'язка, для растрів і PDF - параметри рендерингу та версія рендерера.
+func (c *buildCache) outputKey(svgKey string, o plan.OutputSpec) string {
+	switch o.Format {
+	case plan.FormatSVG:
+		if o.Optimize == nil {
+			return svgKey
+		}
+		opts, _ := json.Marshal(o.Optimize)
+		return hashParts(svgKey, "optimize", string(opts))
+	case plan.FormatDXF:
+		return hashParts(svgKey, o.Format, o.Units)
+	case plan.FormatGeoJSON:
+		geo, _ := json.Marshal(o.Geo)
+		return hashParts(svgKey, o.Format, string(geo))
+	}
+	opts, _ := json.Marshal(o.RenderOptions())
+	return hashParts(svgKey, string(opts), c.version)
+}
+
+// get повертає файл name з підкаталогу kind кешу або створює його функцією build і зберігає.
+func (c *buildCache) get(kind, name string, build func() ([]byte, error)) ([]byte, error) {
+	path := filepath.Join(c.dir, kind, name)
+	if !c.force {
+		if data, err := os.ReadFile(path); err == nil {
+			return data, nil
+		}
+	}
+	data, err := build()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, plan.Errorf("%w: помилка створення кешу %s: %w", plan.ErrWrite, c.dir, err)
+	}
+	// Запис через тимчасовий файл, щоб перервана збірка не залишила в кеші обрізаний файл
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return nil, plan.Errorf("%w кешу %s: %w", plan.ErrWrite, path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, plan.Errorf("%w кешу %s: %w", plan.ErrWrite, path, err)
+	}
+	return data, nil
+}
+
+// prune видаляє з кешу файли, ключі яких не потрібні поточному маніфесту,
+// а зі стану - плани й виходи, яких у маніфесті вже немає. Повертає кількість видалених файлів.
+func (c *buildCache) prune(m *plan.Manifest, dir string, state *buildState) (int, error) {
+	keep := make(map[string]bool)
+	plans := make(map[string]bool)
+	outputs := make(map[string]bool)
+	for _, p := range m.Plans {
+		plans[p.Name] = true
+		svgKey, ok := state.Plans[p.Name]
+		if ok {
+			keep[svgKey] = true
+		}
+		for _, o := range p.Outputs {
+			path := resolvePath(dir, o.Path)
+			outputs[path] = true
+			if ok {
+				keep[c.outputKey(svgKey, o)] = true
+			}
+			if key, ok := state.Outputs[path]; ok {
+				keep[key] = true
+			}
+		}
+	}
+	for name := range state.Plans {
+		if !plans[name] {
+			delete(state.Plans, name)
+		}
+	}
+	for path := range state.Outputs {
+		if !outputs[path] {
+			delete(state.Outputs, path)
+		}
+	}
+
+	removed := 0
+	for _, kind := range []string{"svg", "render", "export"} {
+		entries, err := os.ReadDir(filepath.Join(c.dir, kind))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			key, _, _ := strings.Cut(e.Name(), ".")
+			if keep[key] && !strings.HasSuffix(e.Name(), ".tmp") {
+				continue
+			}
+			if err := os.Remove(filepath.Join(c.dir, kind, e.Name())); err != nil {
+				return removed, plan.Errorf("помилка очищення кешу: %w", err)
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// resolvePath повертає шлях відносно каталогу маніфесту.
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// buildToolKey повертає відбиток програми для ключів кешу: ревізію git, з якої її зібрано
+// (з позначкою -dirty для незакомічених змін), або версію модуля, і хеш бібліотеки символів.
+// Так кеш, створений іншою збіркою програми, не використовується.
+func buildToolKey() string {
+	revision := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Version != "" {
+			revision = info.Main.Version
+		}
+		var vcs, modified string
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				vcs = s.Value
+			case "vcs.modified":
+				modified = s.Value
+			}
+		}
+		if vcs != "" {
+			revision = vcs
+			if modified == "true" {
+				revision += "-dirty"
+			}
+		}
+	}
+	return hashParts(revision, plan.LibraryHash())
+}
+
+// rendererVersion повертає версію рендерера, якщо він її повідомляє.
+func rendererVersion(r plan.Renderer) string {
+	if v, ok := r.(interface{ Version() string }); ok {
+		return v.Version()
+	}
+	return fmt.Sprintf("%T", r)
+}
+
+// hashParts повертає SHA-256 послідовності частин (з довжинами, щоб межі частин не зсувались).
+func hashParts(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d\n%s", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadBuildState читає стан попередньої збірки; відсутній чи пошкоджений стан означає повну збірку.
+func loadBuildState(path string) *buildState {
+	state := &buildState{}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, state)
+	}
+	if state.Plans == nil {
+		state.Plans = make(map[string]string)
+	}
+	if state.Outputs == nil {
+		state.Outputs = make(map[string]string)
+	}
+	return state
+}
+
+// saveBuildState записує стан збірки.
+func saveBuildState(path string, state *buildState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return plan.Errorf("%w: помилка створення кешу: %w", plan.ErrWrite, err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return plan.Errorf("%w стану збірки %s: %w", plan.ErrWrite, path, err)
+	}
+	return nil
+}
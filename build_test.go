@@ -0,0 +1,195 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"simple-plan/plan"
+)
+
+// buildProject - каталог з планом і маніфестом для тестів збірки.
+type buildProject struct {
+	dir, manifest, source, output string
+}
+
+// newBuildProject створює в тимчасовому каталозі план plan.html і маніфест з виходом out/plan.svg.
+func newBuildProject(t *testing.T) *buildProject {
+	t.Helper()
+	dir := t.TempDir()
+	p := &buildProject{
+		dir:      dir,
+		manifest: filepath.Join(dir, manifestFilename),
+		source:   filepath.Join(dir, "plan.html"),
+		output:   filepath.Join(dir, "out", "plan.svg"),
+	}
+	p.writeSource(t, "10")
+	p.writeManifest(t, "")
+	return p
+}
+
+// writeSource записує план зі стіною на висоті y.
+func (p *buildProject) writeSource(t *testing.T, y string) {
+	t.Helper()
+	svg := `<svg viewBox="0 0 100 100"><line class="wall" x1="0" y1="` + y + `" x2="100" y2="` + y + `"/></svg>`
+	if err := os.WriteFile(p.source, []byte(svg), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeManifest записує маніфест; extra - додаткові поля опису плану.
+func (p *buildProject) writeManifest(t *testing.T, extra string) {
+	t.Helper()
+	m := `{"plans": [{"name": "plan", "source": "plan.html", ` + extra + `"outputs": [{"path": "out/plan.svg"}]}]}`
+	if err := os.WriteFile(p.manifest, []byte(m), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// build збирає маніфест і повертає вміст виходу.
+func (p *buildProject) build(t *testing.T, opts buildOptions) string {
+	t.Helper()
+	if err := buildManifest(p.manifest, opts); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(p.output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// stale підміняє вихід маркером: якщо збірка план пропустить, маркер лишиться.
+func (p *buildProject) stale(t *testing.T) {
+	t.Helper()
+	if err := os.WriteFile(p.output, []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildManifestSkipsUnchanged(t *testing.T) {
+	p := newBuildProject(t)
+	if got := p.build(t, buildOptions{}); !strings.Contains(got, `y1="10"`) {
+		t.Fatalf("вихід першої збірки:\n%s", got)
+	}
+
+	// Нічого не змінилось - вихід не перезаписується
+	p.stale(t)
+	if got := p.build(t, buildOptions{}); got != "stale" {
+		t.Errorf("незмінений план перезібрано:\n%s", got)
+	}
+
+	// Змінився файл плану
+	p.writeSource(t, "20")
+	if got := p.build(t, buildOptions{}); !strings.Contains(got, `y1="20"`) {
+		t.Errorf("після зміни плану вихід не оновлено:\n%s", got)
+	}
+
+	// Змінився опис плану в маніфесті
+	p.stale(t)
+	p.writeManifest(t, `"mirror": true, `)
+	if got := p.build(t, buildOptions{}); got == "stale" {
+		t.Error("після зміни маніфесту план не перезібрано")
+	}
+
+	// Вихід видалено - збирається знову, хоча ключ той самий
+	if err := os.Remove(p.output); err != nil {
+		t.Fatal(err)
+	}
+	p.build(t, buildOptions{})
+}
+
+func TestBuildManifestOnly(t *testing.T) {
+	p := newBuildProject(t)
+	if err := buildManifest(p.manifest, buildOptions{only: []string{"other"}}); exitCode(err) != exitUsage {
+		t.Errorf("невідомий план у -only: %v, очікувалась помилка виклику", err)
+	}
+	if _, err := os.Stat(p.output); err == nil {
+		t.Error("план поза -only зібрано")
+	}
+}
+
+// cachedSVGs повертає файли кешу витягнутих SVG проєкту.
+func (p *buildProject) cachedSVGs(t *testing.T) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(p.dir, buildCacheDir, "svg", "*.svg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestBuildCache(t *testing.T) {
+	p := newBuildProject(t)
+	p.build(t, buildOptions{})
+	cached := p.cachedSVGs(t)
+	if len(cached) != 1 {
+		t.Fatalf("у кеші %d SVG, очікувався 1", len(cached))
+	}
+
+	// Відсутній вихід відновлюється з кешу, план не витягується вдруге
+	if err := os.WriteFile(cached[0], []byte("cached"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(p.output); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.build(t, buildOptions{}); got != "cached" {
+		t.Errorf("вихід не взято з кешу:\n%s", got)
+	}
+
+	// -force не зважає ні на стан, ні на кеш
+	p.stale(t)
+	if got := p.build(t, buildOptions{force: true}); !strings.Contains(got, `y1="10"`) {
+		t.Errorf("-force не перезібрав план:\n%s", got)
+	}
+
+	// Після зміни плану старий запис лишається в кеші, доки його не видалить -prune
+	p.writeSource(t, "20")
+	p.build(t, buildOptions{})
+	if n := len(p.cachedSVGs(t)); n != 2 {
+		t.Fatalf("у кеші %d SVG, очікувалось 2", n)
+	}
+	p.build(t, buildOptions{prune: true})
+	cached = p.cachedSVGs(t)
+	if len(cached) != 1 {
+		t.Fatalf("після -prune у кеші %d SVG, очікувався 1", len(cached))
+	}
+	data, err := os.ReadFile(cached[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `y1="20"`) {
+		t.Errorf("-prune видалив актуальний запис, лишився:\n%s", data)
+	}
+}
+
+func TestBuildCacheKeyTool(t *testing.T) {
+	// Інша ревізія програми чи бібліотека символів дає інший ключ для тих самих вхідних даних
+	p := newBuildProject(t)
+	f, err := os.Open(p.manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := plan.LoadManifest(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := make(map[string]bool)
+	for _, tool := range []string{buildToolKey(), "other"} {
+		c := &buildCache{dir: t.TempDir(), renderer: plan.OksvgRenderer{}, tool: tool}
+		state := loadBuildState(filepath.Join(c.dir, "state.json"))
+		if _, err := c.buildPlan(m.Plans[0], p.dir, state); err != nil {
+			t.Fatal(err)
+		}
+		keys[state.Plans["plan"]] = true
+	}
+	if len(keys) != 2 {
+		t.Error("ключ SVG не залежить від версії програми")
+	}
+	if buildToolKey() != buildToolKey() || plan.LibraryHash() == "" {
+		t.Error("відбиток програми має бути сталим і непорожнім")
+	}
+}
@@ -0,0 +1,1171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"simple-plan/plan"
+)
+
+// runCommand виконує підкоманду CLI за її назвою.
+func runCommand(name string, args []string) error {
+	switch name {
+	case "legend":
+		return runLegend(args)
+	case "title":
+		return runTitle(args)
+	case "symbols":
+		return runSymbols(args)
+	case "lint":
+		return runLint(args)
+	case "area":
+		return runArea(args)
+	case "labels":
+		return runLabels(args)
+	case "collisions":
+		return runCollisions(args)
+	case "doors":
+		return runDoors(args)
+	case "walls":
+		return runWalls(args)
+	case "dxf":
+		return runDXF(args)
+	case "export":
+		return runExport(args)
+	case "imdf":
+		return runIMDF(args)
+	case "optimize":
+		return runOptimize(args)
+	case "serve":
+		return runServe(args)
+	case "api":
+		return runAPI(args)
+	case "diff":
+		return runDiff(args)
+	case "building":
+		return runBuilding(args)
+	case "build":
+		return runBuild(args)
+	case "help", "-h", "--help":
+		printUsage()
+		return nil
+	default:
+		printUsage()
+		return usageErrorf("невідома команда %q", name)
+	}
+}
+
+// printUsage виводить список доступних підкоманд.
+func printUsage() {
+	fmt.Println("Використання:")
+	fmt.Println("  simple-plan                 зібрати плани з simple-plan.json або, без маніфесту, витягнути SVG з HTML і створити PNG")
+	fmt.Println("  simple-plan build [опції]   зібрати плани з маніфесту (лише змінені)")
+	fmt.Println("  simple-plan legend [опції]  згенерувати легенду з використаних символів")
+	fmt.Println("  simple-plan title [опції]   заповнити рамку та титульний блок з метаданих")
+	fmt.Println("  simple-plan symbols [опції] показати бібліотеку символів або додати потрібні у <defs>")
+	fmt.Println("  simple-plan lint [опції] файли...  перевірити плани на типові помилки")
+	fmt.Println("  simple-plan area [опції]    площі та периметри контурів (CSV/JSON)")
+	fmt.Println("  simple-plan labels [опції]  розмістити підписи кімнат у візуальних центрах кімнат")
+	fmt.Println("  simple-plan collisions [опції] знайти (і з -fix розвести) підписи, що перекривають стіни чи інші підписи")
+	fmt.Println("  simple-plan doors [опції]   перенумерувати двері (ltr, clockwise; префікс поверху)")
+	fmt.Println("  simple-plan walls [опції]   злити дублікати й колінеарні відрізки стін, з'єднати їх у ламані")
+	fmt.Println("  simple-plan dxf [опції]     імпортувати креслення ASCII DXF у SVG (шари → класи плану)")
+	fmt.Println("  simple-plan export [опції]  експортувати план у DXF для CAD або в GeoJSON з географічною прив'язкою")
+	fmt.Println("  simple-plan imdf [опції]    пакет IMDF (venue, level, unit, opening, amenity) для навігаційних застосунків")
+	fmt.Println("  simple-plan optimize [опції] зменшити SVG для публікації (коментарі, стилі, defs, числа, лінії → шляхи)")
+	fmt.Println("  simple-plan serve [опції]   локальний перегляд з автооновленням при зміні файлів")
+	fmt.Println("  simple-plan api [опції]     HTTP API рендерингу HTML/SVG у SVG, PNG чи PDF")
+	fmt.Println("  simple-plan diff [опції] старий новий  порівняти дві версії плану")
+	fmt.Println("  simple-plan building [опції] плани всіх поверхів будівлі та зведений аркуш")
+	fmt.Println()
+	fmt.Println("Журнал (перед командою; повідомлення пишуться у stderr):")
+	fmt.Println("  -quiet                      лише попередження та помилки")
+	fmt.Println("  -verbose                    налагоджувальні повідомлення")
+	fmt.Println("  -log-format json            журнал у JSON (для CI)")
+	fmt.Println("  -out -                      (legend, title, symbols) записати SVG у stdout; -in - читає stdin")
+	fmt.Println("  -lang en                    мова повідомлень про помилки (uk, en; або SIMPLE_PLAN_LANG)")
+	fmt.Println()
+	fmt.Println("Коди виходу: 0 - успіх, 1 - інша помилка, 2 - неправильний виклик, 3 - немає <svg>,")
+	fmt.Println("4 - помилка парсингу, 5 - рендерер недоступний, 6 - помилка запису")
+}
+
+// loadSVG читає HTML або SVG файл ("-" - stdin) і повертає перший <svg> елемент як документ плану.
+func loadSVG(filename string) (*plan.Document, error) {
+	var r io.Reader = os.Stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, plan.Errorf("помилка читання файлу %s: %w", filename, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	d, err := plan.Extractor{}.Extract(context.Background(), r)
+	if err != nil {
+		return nil, plan.Errorf("%s: %w", filename, err)
+	}
+	return d, nil
+}
+
+// saveSVG серіалізує документ у файл ("-" - stdout, для передачі далі конвеєром).
+func saveSVG(d *plan.Document, filename string) error {
+	var buf bytes.Buffer
+	if err := (plan.Serializer{}).Serialize(context.Background(), &buf, d); err != nil {
+		return err
+	}
+	if filename == "-" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		return plan.Errorf("%w файлу %s: %w", plan.ErrWrite, filename, err)
+	}
+	return nil
+}
+
+// splitList розбиває список через кому, відкидаючи порожні елементи.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// runLegend генерує легенду для плану та зберігає SVG.
+func runLegend(args []string) error {
+	fs := flag.NewFlagSet("legend", flag.ContinueOnError)
+	in := fs.String("in", inputFilename, "вхідний HTML/SVG файл")
+	out := fs.String("out", targetSVGFilename, "вихідний SVG файл")
+	placement := fs.String("placement", "bottom-left", "розміщення: "+strings.Join(plan.LegendPlacements, ", "))
+	title := fs.String("title", plan.DefaultLegendTitle, "заголовок легенди")
+	namesFile := fs.String("names", "", "JSON файл з підписами символів {\"id\": \"підпис\"}")
+	order := fs.String("order", "", "порядок символів через кому (id)")
+	columns := fs.Int("columns", 1, "кількість колонок")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts := plan.LegendOptions{
+		Title:     *title,
+		Order:     splitList(*order),
+		Placement: *placement,
+		Columns:   *columns,
+	}
+	if *namesFile != "" {
+		data, err := os.ReadFile(*namesFile)
+		if err != nil {
+			return plan.Errorf("помилка читання файлу підписів: %w", err)
+		}
+		if err := json.Unmarshal(data, &opts.Names); err != nil {
+			return plan.Errorf("%w файлу підписів %s: %w", plan.ErrParse, *namesFile, err)
+		}
+	}
+
+	svg, err := loadSVG(*in)
+	if err != nil {
+		return err
+	}
+	if _, err := plan.InjectLibrarySymbols(svg); err != nil {
+		return err
+	}
+
+	count, err := plan.GenerateLegend(svg, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := saveSVG(svg, *out); err != nil {
+		return err
+	}
+
+	slog.Info("легенду додано, SVG збережено", "items", count, "file", *out)
+	return nil
+}
+
+// runTitle заповнює шаблон рамки та титульного блоку метаданими плану.
+func runTitle(args []string) error {
+	fs := flag.NewFlagSet("title", flag.ContinueOnError)
+	in := fs.String("in", inputFilename, "вхідний HTML/SVG файл")
+	out := fs.String("out", targetSVGFilename, "вихідний SVG файл")
+	metaFile := fs.String("meta", "", "JSON файл з метаданими плану")
+	tmplFile := fs.String("template", "", "власний шаблон (text/template); за замовчуванням вбудований")
+
+	var meta plan.Metadata
+	overrides := map[string]*string{
+		"title":             fs.String("title", "", "заголовок плану"),
+		"subtitle":          fs.String("subtitle", "", "підзаголовок"),
+		"address":           fs.String("address", "", "адреса будівлі"),
+		"floor":             fs.String("floor", "", "поверх"),
+		"date":              fs.String("date", "", "дата затвердження"),
+		"approved-by":       fs.String("approved-by", "", "ПІБ того, хто затверджує"),
+		"approved-position": fs.String("approved-position", "", "посада того, хто затверджує"),
+		"logo":              fs.String("logo", "", "шлях або URL логотипу"),
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *metaFile != "" {
+		data, err := os.ReadFile(*metaFile)
+		if err != nil {
+			return plan.Errorf("помилка читання метаданих: %w", err)
+		}
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return plan.Errorf("%w метаданих %s: %w", plan.ErrParse, *metaFile, err)
+		}
+	}
+
+	// Явно задані прапорці мають пріоритет над файлом метаданих
+	fields := map[string]*string{
+		"title":             &meta.Title,
+		"subtitle":          &meta.Subtitle,
+		"address":           &meta.Address,
+		"floor":             &meta.Floor,
+		"date":              &meta.Date,
+		"approved-by":       &meta.ApprovedBy,
+		"approved-position": &meta.ApprovedPosition,
+		"logo":              &meta.Logo,
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if field, ok := fields[f.Name]; ok {
+			*field = *overrides[f.Name]
+		}
+	})
+
+	tmplText, err := plan.LoadTemplate(*tmplFile)
+	if err != nil {
+		return err
+	}
+
+	svg, err := loadSVG(*in)
+	if err != nil {
+		return err
+	}
+
+	if err := plan.ApplyTitleBlock(svg, meta, tmplText); err != nil {
+		return err
+	}
+
+	if err := saveSVG(svg, *out); err != nil {
+		return err
+	}
+
+	slog.Info("титульний блок заповнено, SVG збережено", "file", *out)
+	return nil
+}
+
+// runSymbols виводить вбудовану бібліотеку символів або додає у файл символи, яких бракує.
+func runSymbols(args []string) error {
+	fs := flag.NewFlagSet("symbols", flag.ContinueOnError)
+	list := fs.Bool("list", false, "показати id символів бібліотеки")
+	in := fs.String("in", inputFilename, "вхідний HTML/SVG файл")
+	out := fs.String("out", targetSVGFilename, "вихідний SVG файл")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *list {
+		for _, id := range plan.LibrarySymbolIDs() {
+			fmt.Printf("%-22s %s\n", id, plan.DefaultSymbolNames[id])
+		}
+		return nil
+	}
+
+	svg, err := loadSVG(*in)
+	if err != nil {
+		return err
+	}
+
+	injected, err := plan.InjectLibrarySymbols(svg)
+	if err != nil {
+		return err
+	}
+
+	if err := saveSVG(svg, *out); err != nil {
+		return err
+	}
+
+	if len(injected) == 0 {
+		slog.Info("усі символи вже визначені, SVG збережено", "file", *out)
+	} else {
+		slog.Info("додано символи з бібліотеки, SVG збережено", "symbols", strings.Join(injected, ", "), "file", *out)
+	}
+	return nil
+}
+
+// runLint перевіряє плани і повертає помилку, якщо знайдено проблеми рівня error
+// (або warning з -werror), щоб CI міг зупинити збірку.
+func runLint(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	format := fs.String("format", "text", "формат виводу: text або json")
+	werror := fs.Bool("werror", false, "вважати попередження помилками")
+	disable := fs.String("disable", "", "вимкнені правила через кому: "+strings.Join(plan.LintRuleIDs(), ", "))
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{inputFilename}
+	}
+	disabled := make(map[string]bool)
+	for _, id := range splitList(*disable) {
+		if !slices.Contains(plan.LintRuleIDs(), id) {
+			return usageErrorf("невідоме правило %q (%s)", id, strings.Join(plan.LintRuleIDs(), ", "))
+		}
+		disabled[id] = true
+	}
+
+	var all []plan.Diagnostic
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return plan.Errorf("помилка читання файлу %s: %w", file, err)
+		}
+		diags, err := plan.Lint(file, data, disabled)
+		if err != nil {
+			return err
+		}
+		all = append(all, diags...)
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if all == nil {
+			all = []plan.Diagnostic{}
+		}
+		if err := enc.Encode(all); err != nil {
+			return err
+		}
+	case "text":
+		plan.WriteDiagnostics(os.Stdout, all)
+	default:
+		return usageErrorf("невідомий формат %q", *format)
+	}
+
+	errCount := plan.CountSeverity(all, plan.SeverityError)
+	warnings := plan.CountSeverity(all, plan.SeverityWarning)
+	if *format == "text" {
+		fmt.Printf("Помилок: %d, попереджень: %d\n", errCount, warnings)
+	}
+	if errCount > 0 || (*werror && warnings > 0) {
+		return plan.Errorf("перевірку не пройдено")
+	}
+	return nil
+}
+
+// runArea виводить таблицю площ і периметрів контурів плану.
+func runArea(args []string) error {
+	fs := flag.NewFlagSet("area", flag.ContinueOnError)
+	in := fs.String("in", inputFilename, "вхідний HTML/SVG файл")
+	out := fs.String("out", "", "вихідний файл (за замовчуванням stdout)")
+	format := fs.String("format", "csv", "формат: csv або json")
+	scale := fs.Float64("scale", plan.DefaultMetersPerUnit, "метрів в одній одиниці viewBox")
+	classes := fs.String("classes", "room", "класи контурів кімнат через кому (кімната без контуру - область між стінами)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *scale <= 0 {
+		return usageErrorf("масштаб має бути додатним, отримано %v", *scale)
+	}
+
+	svg, err := loadSVG(*in)
+	if err != nil {
+		return err
+	}
+	rooms := plan.MeasureRooms(svg, splitList(*classes), *scale)
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return plan.Errorf("%w: помилка створення файлу %s: %w", plan.ErrWrite, *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "csv":
+		return plan.WriteRoomsCSV(w, rooms)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(rooms)
+	default:
+		return usageErrorf("невідомий формат %q", *format)
+	}
+}
+
+// runLabels розміщує підписи .room-name у центрах кімнат, обмежених стінами.
+func runLabels(args []string) error {
+	fs := flag.NewFlagSet("labels", flag.ContinueOnError)
+	in := fs.String("in", inputFilename, "вхідний HTML/SVG файл")
+	out := fs.String("out", targetSVGFilename, "вихідний SVG файл")
+	cell := fs.Float64("cell", 0, "крок сітки в одиницях viewBox (0 - за замовчуванням)")
+	shrink := fs.Bool("shrink", false, "зменшувати шрифт, якщо підпис не вміщується в кімнату")
+	minFont := fs.Float64("min-font", 0, "найменший розмір шрифту для -shrink (0 - за замовчуванням)")
+	doorGap := fs.Float64("door-gap", 0, "найбільший розрив між стінами, що вважається дверним прорізом (0 - за замовчуванням)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	svg, err := loadSVG(*in)
+	if err != nil {
+		return err
+	}
+	placements := plan.PlaceRoomLabels(svg, plan.LabelOptions{
+		Cell:        *cell,
+		Shrink:      *shrink,
+		MinFontSize: *minFont,
+		DoorGap:     *doorGap,
+	})
+	logLabelPlacements(placements)
+	if err := saveSVG(svg, *out); err != nil {
+		return err
+	}
+	slog.Info("підписи розміщено, SVG збережено", "file", *out)
+	return nil
+}
+
+// logLabelPlacements виводить у журнал, куди перенесено підписи і які не вмістилися.
+func logLabelPlacements(placements []plan.LabelPlacement) {
+	for _, p := range placements {
+		switch {
+		case !p.Placed:
+			slog.Debug("підпис не в замкненій кімнаті, лишається на місці", "label", p.Label, "path", p.Path)
+		case !p.Fits:
+			slog.Warn("підпис не вміщується в кімнату", "label", p.Label, "x", p.X, "y", p.Y, "font-size", p.FontSize)
+		default:
+			slog.Debug("підпис розміщено", "label", p.Label, "x", p.X, "y", p.Y, "font-size", p.FontSize)
+		}
+	}
+}
+
+// runCollisions шукає підписи, що перекривають інші підписи, стіни чи двері; з -fix зсуває їх
+// на найближче вільне місце і зберігає SVG. Помилка повертається, якщо лишилися нерозведені перетини.
+func runCollisions(args []string) error {
+	fs := flag.NewFlagSet("collisions", flag.ContinueOnError)
+	in := fs.String("in", inputFilename, "вхідний HTML/SVG файл")
+	out := fs.String("out", targetSVGFilename, "вихідний SVG файл (з -fix; \"-\" - stdout)")
+	fix := fs.Bool("fix", false, "зсунути підписи на вільні місця і зберегти SVG")
+	format := fs.String("format", "text", "формат звіту: text або json")
+	classes := fs.String("classes", strings.Join(plan.DefaultCollisionClasses, ","), "класи підписів у порядку пріоритету")
+	maxShift := fs.Float64("max-shift", 0, "найбільше зміщення підпису (0 - за замовчуванням)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return usageErrorf("невідомий формат %q", *format)
+	}
+
+	svg, err := loadSVG(*in)
+	if err != nil {
+		return err
+	}
+	opts := plan.CollisionOptions{Classes: splitList(*classes), MaxShift: *maxShift}
+	var collisions []plan.LabelCollision
+	// При -fix -out - stdout зайнятий SVG, тож звіт пишеться в stderr
+	var w io.Writer = os.Stdout
+	if *fix {
+		collisions = plan.ResolveLabelCollisions(svg, opts)
+		if err := saveSVG(svg, *out); err != nil {
+			return err
+		}
+		if *out == "-" {
+			w = os.Stderr
+		}
+	} else {
+		collisions = plan.FindLabelCollisions(svg, opts)
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if collisions == nil {
+			collisions = []plan.LabelCollision{}
+		}
+		if err := enc.Encode(collisions); err != nil {
+			return err
+		}
+	} else {
+		plan.WriteCollisions(w, collisions)
+	}
+
+	unresolved := 0
+	for _, c := range collisions {
+		if !c.Resolved {
+			unresolved++
+		}
+	}
+	if *fix {
+		slog.Info("підписи розведено, SVG збережено", "file", *out, "moved", len(collisions)-unresolved, "unresolved", unresolved)
+	}
+	if unresolved > 0 {
+		return plan.Errorf("перевірку не пройдено: нерозведених перекриттів підписів - %d", unresolved)
+	}
+	return nil
+}
+
+// runDoors нумерує двері за обраним правилом і виводить відповідність старих і нових номерів.
+func runDoors(args []string) error {
+	fs := flag.NewFlagSet("doors", flag.ContinueOnError)
+	in := fs.String("in", inputFilename, "вхідний HTML/SVG файл")
+	out := fs.String("out", targetSVGFilename, "вихідний SVG файл (\"-\" - stdout)")
+	order := fs.String("order", plan.DoorOrderLTR, "правило нумерації: "+strings.Join(plan.DoorOrders, ", "))
+	start := fs.Int("start", 1, "перший номер")
+	prefix := fs.String("prefix", "", "префікс поверху (2 → 201, 202, ...)")
+	gaps := fs.Bool("gaps", false, "нумерувати також прорізи в стінах без line.doors")
+	minGap := fs.Float64("min-gap", 30, "найменша ширина прорізу для -gaps, одиниць viewBox")
+	maxGap := fs.Float64("max-gap", 100, "найбільша ширина прорізу для -gaps, одиниць viewBox")
+	format := fs.String("format", "text", "формат звіту: text або json")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if !slices.Contains(plan.DoorOrders, *order) {
+		return usageErrorf("невідоме правило нумерації %q (%s)", *order, strings.Join(plan.DoorOrders, ", "))
+	}
+	if *format != "text" && *format != "json" {
+		return usageErrorf("невідомий формат %q", *format)
+	}
+	if *minGap <= 0 || *maxGap < *minGap {
+		return usageErrorf("ширина прорізу має бути 0 < -min-gap <= -max-gap, отримано %v і %v", *minGap, *maxGap)
+	}
+
+	svg, err := loadSVG(*in)
+	if err != nil {
+		return err
+	}
+	numbers, err := plan.NumberDoors(svg, plan.DoorNumbering{
+		Order: *order, Start: *start, Prefix: *prefix, Gaps: *gaps, MinDoorGap: *minGap, DoorGap: *maxGap,
+	})
+	if err != nil {
+		return err
+	}
+	if err := saveSVG(svg, *out); err != nil {
+		return err
+	}
+
+	// При -out - stdout зайнятий SVG, тож відповідність номерів пишеться в stderr
+	var w io.Writer = os.Stdout
+	if *out == "-" {
+		w = os.Stderr
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if numbers == nil {
+			numbers = []plan.DoorNumber{}
+		}
+		if err := enc.Encode(numbers); err != nil {
+			return err
+		}
+	} else {
+		plan.WriteDoorNumbers(w, numbers)
+	}
+
+	orphans, renumbered, invalid := 0, 0, 0
+	for _, n := range numbers {
+		switch {
+		case n.Invalid:
+			invalid++
+		case n.Orphan && n.Old != n.Number:
+			renumbered++
+		case n.Orphan:
+			orphans++
+		}
+	}
+	if orphans > 0 {
+		slog.Warn("номери, що не стоять біля жодних дверей, лишилися без змін", "count", orphans)
+	}
+	if renumbered > 0 {
+		slog.Warn("номери, що не стоять біля жодних дверей і збігалися з новими, продовжують нумерацію", "count", renumbered)
+	}
+	if invalid > 0 {
+		slog.Warn("підписи .door-number, що не є номерами, лишилися без змін", "count", invalid)
+	}
+	slog.Info("двері перенумеровано, SVG збережено", "doors", len(numbers)-orphans-renumbered-invalid, "file", *out)
+	return nil
+}
+
+// runWalls очищує геометрію стін і виводить звіт.
+func runWalls(args []string) error {
+	fs := flag.NewFlagSet("walls", flag.ContinueOnError)
+	in := fs.String("in", inputFilename, "вхідний HTML/SVG файл")
+	out := fs.String("out", targetSVGFilename, "вихідний SVG файл (\"-\" - stdout)")
+	tolerance := fs.Float64("tolerance", 1, "допуск з'єднання кінців і закриття розривів, одиниць viewBox")
+	keepLines := fs.Bool("keep-lines", false, "лише зливати колінеарні відрізки, не з'єднуючи стіни в <polyline>")
+	format := fs.String("format", "text", "формат звіту: text або json")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *tolerance <= 0 {
+		return usageErrorf("допуск має бути додатним, отримано %v", *tolerance)
+	}
+	if *format != "text" && *format != "json" {
+		return usageErrorf("невідомий формат %q", *format)
+	}
+
+	svg, err := loadSVG(*in)
+	if err != nil {
+		return err
+	}
+	report := plan.MergeWalls(svg, plan.WallMergeOptions{Tolerance: *tolerance, KeepLines: *keepLines})
+	if err := saveSVG(svg, *out); err != nil {
+		return err
+	}
+
+	// При -out - stdout зайнятий SVG, тож звіт пишеться в stderr
+	var w io.Writer = os.Stdout
+	if *out == "-" {
+		w = os.Stderr
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		plan.WriteWallReport(w, report)
+	}
+	slog.Info("стіни очищено, SVG збережено", "before", report.Before, "after", report.After, "file", *out)
+	return nil
+}
+
+// runDXF імпортує креслення DXF у SVG і виводить звіт по шарах.
+func runDXF(args []string) error {
+	fs := flag.NewFlagSet("dxf", flag.ContinueOnError)
+	in := fs.String("in", "plan.dxf", "вхідний ASCII DXF файл")
+	out := fs.String("out", "plan.svg", "вихідний SVG файл (\"-\" - stdout)")
+	layers := fs.String("layers", "", "відповідність шарів класам: A-WALL=wall,MEBLI=,... (шаблони з *; порожній клас - пропустити шар)")
+	scale := fs.Float64("scale", 0, "одиниць viewBox в одиниці DXF (0 - сантиметри за $INSUNITS)")
+	margin := fs.Float64("margin", 0, "поле навколо плану (0 - за замовчуванням)")
+	format := fs.String("format", "text", "формат звіту по шарах: text або json")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return usageErrorf("невідомий формат %q", *format)
+	}
+	opts := plan.DXFOptions{Scale: *scale, Margin: *margin, Layers: make(map[string]string)}
+	for _, item := range splitList(*layers) {
+		layer, class, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(layer) == "" {
+			return usageErrorf("некоректна відповідність шару %q (очікується ШАР=клас)", item)
+		}
+		opts.Layers[strings.TrimSpace(layer)] = strings.TrimSpace(class)
+	}
+
+	f, err := os.Open(*in)
+	if err != nil {
+		return plan.Errorf("помилка читання файлу %s: %w", *in, err)
+	}
+	defer f.Close()
+	svg, report, err := plan.ImportDXF(context.Background(), f, opts)
+	// При -out - stdout зайнятий SVG, тож звіт по шарах пишеться в stderr
+	var w io.Writer = os.Stdout
+	if *out == "-" {
+		w = os.Stderr
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if report == nil {
+			report = []plan.DXFLayer{}
+		}
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		for _, l := range report {
+			if l.Class == "" {
+				fmt.Fprintf(w, "%s: шар без класу, пропущено %d\n", l.Name, l.Skipped)
+				continue
+			}
+			fmt.Fprintf(w, "%s → %s: %d", l.Name, l.Class, l.Entities)
+			if l.Skipped > 0 {
+				fmt.Fprintf(w, " (пропущено %d)", l.Skipped)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if err != nil {
+		return plan.Errorf("%s: %w", *in, err)
+	}
+	if err := saveSVG(svg, *out); err != nil {
+		return err
+	}
+	slog.Info("креслення DXF імпортовано, SVG збережено", "layers", len(report), "file", *out)
+	return nil
+}
+
+// runExport експортує план у формат для інших програм; формат визначається з -format або розширення -out.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	in := fs.String("in", inputFilename, "вхідний HTML/SVG файл")
+	out := fs.String("out", "plan.dxf", "вихідний файл (\"-\" - stdout)")
+	format := fs.String("format", "", "формат: dxf або geojson (за замовчуванням - з розширення -out)")
+	units := fs.String("units", "cm", "одиниці креслення DXF: "+strings.Join(plan.DXFUnits, ", "))
+	unitSize := fs.Float64("unit-size", 1, "сантиметрів в одиниці viewBox")
+	geo := geoFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*out)), ".")
+	}
+	if *format != plan.FormatDXF && *format != plan.FormatGeoJSON {
+		return usageErrorf("невідомий формат експорту %q (dxf, geojson)", *format)
+	}
+	if !slices.Contains(plan.DXFUnits, *units) {
+		return usageErrorf("невідомі одиниці %q (%s)", *units, strings.Join(plan.DXFUnits, ", "))
+	}
+	if *unitSize <= 0 {
+		return usageErrorf("розмір одиниці має бути додатним, отримано %v", *unitSize)
+	}
+	ref, err := geo(*unitSize)
+	if err != nil {
+		return err
+	}
+
+	svg, err := loadSVG(*in)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	switch *format {
+	case plan.FormatGeoJSON:
+		err = plan.ExportGeoJSON(context.Background(), &buf, svg, ref)
+	default:
+		// Символи бібліотеки, на які є <use>, мають стати блоками креслення
+		if _, err := plan.InjectLibrarySymbols(svg); err != nil {
+			return err
+		}
+		err = plan.ExportDXF(context.Background(), &buf, svg, plan.DXFExportOptions{Units: *units, UnitSize: *unitSize})
+	}
+	if err != nil {
+		return err
+	}
+	if *out == "-" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		return plan.Errorf("%w файлу %s: %w", plan.ErrWrite, *out, err)
+	}
+	slog.Info("план експортовано", "format", *format, "file", *out)
+	return nil
+}
+
+// runOptimize записує оптимізований SVG для публікації і виводить звіт про розмір.
+func runOptimize(args []string) error {
+	fs := flag.NewFlagSet("optimize", flag.ContinueOnError)
+	in := fs.String("in", targetSVGFilename, "вхідний HTML/SVG файл")
+	out := fs.String("out", "mirror.min.svg", "вихідний SVG файл (\"-\" - stdout)")
+	precision := fs.Int("precision", 2, "знаків після коми в координатах")
+	keepLines := fs.Bool("keep-lines", false, "не перетворювати <line> на <path> (для подальшої обробки цим інструментом)")
+	format := fs.String("format", "text", "формат звіту: text або json")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *precision < 0 {
+		return usageErrorf("кількість знаків не може бути від'ємною, отримано %d", *precision)
+	}
+	if *format != "text" && *format != "json" {
+		return usageErrorf("невідомий формат %q", *format)
+	}
+
+	svg, err := loadSVG(*in)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	report, err := plan.WriteOptimized(context.Background(), &buf, svg, plan.OptimizeOptions{Precision: *precision, KeepLines: *keepLines})
+	if err != nil {
+		return err
+	}
+	// При -out - stdout зайнятий SVG, тож звіт пишеться в stderr
+	var w io.Writer = os.Stdout
+	if *out == "-" {
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		w = os.Stderr
+	} else if err := os.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		return plan.Errorf("%w файлу %s: %w", plan.ErrWrite, *out, err)
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		plan.WriteOptimizeReport(w, report)
+	}
+	if *out != "-" {
+		slog.Info("оптимізований SVG збережено", "file", *out, "bytes", report.After)
+	}
+	return nil
+}
+
+// geoFlags додає прапорці географічної прив'язки й повертає функцію, що збирає GeoReference
+// після розбору; unitSize - сантиметрів в одиниці viewBox.
+func geoFlags(fs *flag.FlagSet) func(unitSize float64) (plan.GeoReference, error) {
+	lat := fs.Float64("lat", 0, "широта точки прив'язки")
+	lon := fs.Float64("lon", 0, "довгота точки прив'язки")
+	origin := fs.String("origin", "0,0", "точка прив'язки на плані x,y (координати viewBox)")
+	rotation := fs.Float64("rotation", 0, "азимут осі \"вгору\" плану, градусів за годинниковою стрілкою")
+	return func(unitSize float64) (plan.GeoReference, error) {
+		ref := plan.GeoReference{Lat: *lat, Lon: *lon, Rotation: *rotation, MetersPerUnit: unitSize / 100}
+		xy := splitList(*origin)
+		if len(xy) != 2 {
+			return ref, usageErrorf("точка прив'язки має бути x,y, отримано %q", *origin)
+		}
+		var errX, errY error
+		ref.OriginX, errX = strconv.ParseFloat(xy[0], 64)
+		ref.OriginY, errY = strconv.ParseFloat(xy[1], 64)
+		if errX != nil || errY != nil {
+			return ref, usageErrorf("точка прив'язки має бути x,y, отримано %q", *origin)
+		}
+		return ref, nil
+	}
+}
+
+// runIMDF експортує план або всі поверхи проєкту будівлі в каталог пакета IMDF.
+func runIMDF(args []string) error {
+	fs := flag.NewFlagSet("imdf", flag.ContinueOnError)
+	in := fs.String("in", inputFilename, "вхідний HTML/SVG файл плану поверху")
+	project := fs.String("project", "", "JSON файл проєкту будівлі: експортувати всі поверхи (замість -in)")
+	outDir := fs.String("out", "imdf", "каталог пакета")
+	level := fs.Int("level", 1, "номер поверху плану -in (1 - наземний)")
+	levelName := fs.String("level-name", "", "назва поверху (за замовчуванням \"N поверх\")")
+	venue := fs.String("venue", "", "назва закладу (обов'язкова)")
+	building := fs.String("building", "", "назва будівлі (за замовчуванням - як у проєкті або -venue)")
+	category := fs.String("category", "", "категорія закладу IMDF (за замовчуванням businesscampus)")
+	address := fs.String("address", "", "вулиця і номер будинку (обов'язкова)")
+	locality := fs.String("locality", "", "населений пункт (обов'язковий)")
+	province := fs.String("province", "", "регіон за ISO 3166-2, наприклад UA-30")
+	country := fs.String("country", "UA", "країна за ISO 3166-1 alpha-2")
+	postalCode := fs.String("postal-code", "", "поштовий індекс")
+	language := fs.String("language", "uk", "мова назв у пакеті")
+	unitSize := fs.Float64("unit-size", 1, "сантиметрів в одиниці viewBox")
+	geo := geoFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	for _, f := range []struct{ name, value string }{{"venue", *venue}, {"address", *address}, {"locality", *locality}} {
+		if f.value == "" {
+			return usageErrorf("не вказано -%s", f.name)
+		}
+	}
+	if *unitSize <= 0 {
+		return usageErrorf("розмір одиниці має бути додатним, отримано %v", *unitSize)
+	}
+	ref, err := geo(*unitSize)
+	if err != nil {
+		return err
+	}
+	opts := plan.IMDFOptions{
+		Geo: ref, Venue: *venue, Building: *building, Category: *category,
+		Address: *address, Locality: *locality, Province: *province, Country: *country,
+		PostalCode: *postalCode, Language: *language,
+	}
+
+	var levels []plan.IMDFLevel
+	if *project != "" {
+		f, err := os.Open(*project)
+		if err != nil {
+			return plan.Errorf("помилка читання проєкту: %w", err)
+		}
+		b, err := plan.LoadBuilding(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		floors, err := plan.LoadFloors(context.Background(), b, filepath.Dir(*project))
+		if err != nil {
+			return err
+		}
+		if opts.Building == "" {
+			opts.Building = b.Name
+		}
+		for _, fp := range floors {
+			levels = append(levels, plan.IMDFLevel{Doc: fp.Doc, Ordinal: fp.Floor.Ordinal(), Name: fp.Floor.Title(), ShortName: strconv.Itoa(fp.Floor.Level), Stairs: fp.Stairs})
+		}
+	} else {
+		svg, err := loadSVG(*in)
+		if err != nil {
+			return err
+		}
+		floor := plan.Floor{Level: *level, Name: *levelName}
+		levels = append(levels, plan.IMDFLevel{Doc: svg, Ordinal: floor.Ordinal(), Name: floor.Title(), ShortName: strconv.Itoa(*level)})
+	}
+
+	pkg, err := plan.IMDF(levels, opts)
+	if err != nil {
+		return err
+	}
+	for _, w := range pkg.Warnings {
+		slog.Warn(w)
+	}
+	if err := pkg.WriteDir(*outDir); err != nil {
+		return err
+	}
+	counts := make([]any, 0, 2*len(pkg.Files))
+	for _, kind := range []string{"level", "unit", "opening", "amenity"} {
+		counts = append(counts, kind, len(pkg.Files[kind+".geojson"].Features))
+	}
+	slog.Info("пакет IMDF збережено", append([]any{"dir", *outDir}, counts...)...)
+	return nil
+}
+
+// runServe запускає локальний сервер перегляду, який стежить за файлами планів.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", "localhost:8080", "адреса HTTP-сервера")
+	in := fs.String("in", "full.html", "файли планів через кому")
+	outDir := fs.String("out", ".preview", "каталог для згенерованих файлів")
+	width := fs.Int("width", 2450, "ширина PNG у пікселях")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	srv, err := newPreviewServer(splitList(*in), *outDir, *width)
+	if err != nil {
+		return err
+	}
+	srv.rebuild()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go srv.watch(ctx)
+
+	httpServer := &http.Server{Addr: *addr, Handler: srv.handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("перегляд доступний (Ctrl+C для зупинки)", "url", "http://"+*addr)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return plan.Errorf("помилка HTTP-сервера: %w", err)
+	}
+	return nil
+}
+
+// runAPI запускає HTTP API рендерингу для інших сервісів.
+func runAPI(args []string) error {
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	addr := fs.String("addr", "localhost:8090", "адреса HTTP-сервера")
+	maxBody := fs.Int64("max-body", 10<<20, "максимальний розмір тіла запиту в байтах")
+	timeout := fs.Duration("timeout", 30*time.Second, "тайм-аут рендерингу одного запиту")
+	concurrency := fs.Int("concurrency", 2, "кількість одночасних рендерингів")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	api := newAPIServer(*maxBody, *timeout, *concurrency)
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           api.handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       *timeout,
+		WriteTimeout:      *timeout + 10*time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("API рендерингу доступне (Ctrl+C для зупинки)", "url", "http://"+*addr+"/render")
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return plan.Errorf("помилка HTTP-сервера: %w", err)
+	}
+	return nil
+}
+
+// runDiff порівнює дві версії плану: структурні зміни виводяться списком,
+// а накладення растрів (додане зеленим, видалене червоним) зберігається у PNG.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	out := fs.String("out", "diff.png", "PNG з накладенням версій (порожній рядок - не створювати)")
+	format := fs.String("format", "text", "формат списку змін: text або json")
+	width := fs.Int("width", 2450, "ширина растру в пікселях")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageErrorf("потрібно два файли: diff [опції] старий новий")
+	}
+
+	before, err := loadSVG(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	after, err := loadSVG(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	for _, d := range []*plan.Document{before, after} {
+		if _, err := plan.InjectLibrarySymbols(d); err != nil {
+			return err
+		}
+	}
+
+	changes := plan.Diff(before, after)
+	switch *format {
+	case "json":
+		if changes == nil {
+			changes = []plan.Change{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(changes); err != nil {
+			return err
+		}
+	case "text":
+		plan.WriteChanges(os.Stdout, changes)
+		fmt.Printf("Змін: %d\n", len(changes))
+	default:
+		return usageErrorf("невідомий формат %q", *format)
+	}
+
+	if *out == "" {
+		return nil
+	}
+	imgBefore, err := renderImage(before, *width)
+	if err != nil {
+		return err
+	}
+	imgAfter, err := renderImage(after, *width)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, plan.DiffOverlay(imgBefore, imgAfter)); err != nil {
+		return plan.Errorf("%w PNG: %w", plan.ErrWrite, err)
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		return plan.Errorf("%w файлу %s: %w", plan.ErrWrite, *out, err)
+	}
+	slog.Info("накладення версій збережено", "file", *out)
+	return nil
+}
+
+// renderImage рендерить документ у растр заданої ширини рендерером за замовчуванням.
+func renderImage(d *plan.Document, width int) (image.Image, error) {
+	var svg, out bytes.Buffer
+	ctx := context.Background()
+	if err := (plan.Serializer{}).Serialize(ctx, &svg, d); err != nil {
+		return nil, err
+	}
+	opts := plan.RenderOptions{Format: plan.FormatPNG, Width: width}
+	if err := plan.DefaultRenderer().Render(ctx, &out, &svg, opts); err != nil {
+		return nil, err
+	}
+	img, err := png.Decode(&out)
+	if err != nil {
+		return nil, plan.Errorf("%w PNG: %w", plan.ErrParse, err)
+	}
+	return img, nil
+}
+
+// runBuilding обробляє проєкт багатоповерхової будівлі: зв'язує сходові клітки між поверхами,
+// будує маршрути евакуації (на нижньому поверсі від сходів до виходів, на верхніх - від кімнат
+// до сходів) і зберігає плани поверхів та зведений аркуш.
+func runBuilding(args []string) error {
+	fs := flag.NewFlagSet("building", flag.ContinueOnError)
+	project := fs.String("project", "building.json", "JSON файл проєкту будівлі")
+	outDir := fs.String("out", "building", "каталог для результатів")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	f, err := os.Open(*project)
+	if err != nil {
+		return plan.Errorf("помилка читання проєкту: %w", err)
+	}
+	b, err := plan.LoadBuilding(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	plans, err := plan.LoadFloors(context.Background(), b, filepath.Dir(*project))
+	if err != nil {
+		return err
+	}
+	warnings := plan.AnnotateFloors(plans)
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return plan.Errorf("%w: помилка створення каталогу %s: %w", plan.ErrWrite, *outDir, err)
+	}
+	for _, fp := range plans {
+		name := filepath.Join(*outDir, fmt.Sprintf("floor-%d.svg", fp.Floor.Level))
+		if err := saveSVG(fp.Doc, name); err != nil {
+			return err
+		}
+		ids := make([]string, len(fp.Stairs))
+		for i, st := range fp.Stairs {
+			ids[i] = st.ID
+		}
+		slog.Info("план поверху збережено", "floor", fp.Floor.Title(), "file", name, "stairs", strings.Join(ids, ", "))
+		for _, r := range fp.Routes {
+			length := strconv.FormatFloat(r.Length, 'f', 0, 64)
+			if r.From != "" {
+				slog.Info("маршрут до сходів", "floor", fp.Floor.Title(), "room", r.From, "stair", r.Stair, "length", length)
+				continue
+			}
+			slog.Info("маршрут до виходу", "stair", r.Stair, "length", length)
+		}
+	}
+
+	overview := filepath.Join(*outDir, "overview.svg")
+	if err := saveSVG(plan.Overview(b.Name, plans), overview); err != nil {
+		return err
+	}
+	slog.Info("зведений аркуш збережено", "file", overview)
+
+	for _, w := range warnings {
+		slog.Warn(w)
+	}
+	return nil
+}
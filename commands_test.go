@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLintDisableUnknownRule(t *testing.T) {
+	err := runLint([]string{"-disable", "label-outside-room,no-such-rule", "full.html"})
+	if !errors.Is(err, errUsage) || exitCode(err) != exitUsage {
+		t.Errorf("невідоме правило в -disable: %v, очікувалась помилка виклику", err)
+	}
+}
+
+func TestDiffOverlayFile(t *testing.T) {
+	dir := t.TempDir()
+	before, after := filepath.Join(dir, "before.html"), filepath.Join(dir, "after.html")
+	// Стіну в новій версії перенесено нижче: стара позначається червоним, нова - зеленим
+	for file, y := range map[string]string{before: "20", after: "80"} {
+		svg := `<svg viewBox="0 0 100 100"><line stroke="#000" stroke-width="6" x1="10" y1="` + y + `" x2="90" y2="` + y + `"/></svg>`
+		if err := os.WriteFile(file, []byte(svg), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(dir, "diff.png")
+	if err := runDiff([]string{"-out", out, "-width", "100", before, after}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 100 || b.Dy() != 100 {
+		t.Errorf("розмір накладення %v", b.Size())
+	}
+	for _, c := range []struct {
+		y       int
+		r, g, b uint32
+	}{{20, 0xE0, 0, 0}, {80, 0, 0xA0, 0}, {50, 0xFF, 0xFF, 0xFF}} {
+		r, g, b, _ := img.At(50, c.y).RGBA()
+		if r>>8 != c.r || g>>8 != c.g || b>>8 != c.b {
+			t.Errorf("піксель (50, %d): #%02x%02x%02x, очікувалось #%02x%02x%02x", c.y, r>>8, g>>8, b>>8, c.r, c.g, c.b)
+		}
+	}
+
+	if err := runDiff([]string{before}); exitCode(err) != exitUsage {
+		t.Errorf("diff з одним файлом: %v, очікувалась помилка виклику", err)
+	}
+}
@@ -1,128 +1,97 @@
 package main
 
 import (
-	"fmt"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
 	"os"
-	"regexp"
-	"strconv"
-)
-
-const viewBoxWidth = 2450
-
-var groupWidths = map[float64]float64{
-	50:   920,
-	970:  920,
-	1890: 520,
-}
-
-func main() {
-	// Читаємо весь файл одразу для обробки багаторядкових елементів
-	data, err := os.ReadFile("full.html")
-	if err != nil {
-		fmt.Printf("Помилка: %v\n", err)
-		return
-	}
-
-	content := string(data)
-
-	// Знаходимо всі групи під'їздів і обробляємо їх вміст
-	groupRe := regexp.MustCompile(`(?s)<g transform="translate\(([0-9.]+), ([0-9.]+)\)">(.*?)</g>`)
+	"slices"
+	"strings"
 
-	content = groupRe.ReplaceAllStringFunc(content, func(match string) string {
-		parts := groupRe.FindStringSubmatch(match)
-		if len(parts) < 4 {
-			return match
-		}
-
-		offset, _ := strconv.ParseFloat(parts[1], 64)
-		groupContent := parts[3]
-
-		width, ok := groupWidths[offset]
-		if !ok {
-			return match // Залишаємо без змін для невідомих груп
-		}
-
-		// Дзеркалимо polygon points (включаючи багаторядкові)
-		polygonRe := regexp.MustCompile(`(?s)points="([^"]*)"`)
-		groupContent = polygonRe.ReplaceAllStringFunc(groupContent, func(pm string) string {
-			pointsMatch := polygonRe.FindStringSubmatch(pm)
-			if len(pointsMatch) < 2 {
-				return pm
-			}
+	"golang.org/x/net/html"
 
-			pointsStr := pointsMatch[1]
-			// Витягуємо всі числа
-			coordRe := regexp.MustCompile(`([0-9.]+),([0-9.]+)`)
-
-			result := coordRe.ReplaceAllStringFunc(pointsStr, func(coord string) string {
-				coordParts := coordRe.FindStringSubmatch(coord)
-				if len(coordParts) < 3 {
-					return coord
-				}
-				x, _ := strconv.ParseFloat(coordParts[1], 64)
-				y := coordParts[2]
-				newX := width - x
-				return fmt.Sprintf("%.0f,%s", newX, y)
-			})
-
-			return fmt.Sprintf(`points="%s"`, result)
-		})
-
-		// Дзеркалимо line x1, x2
-		x1Re := regexp.MustCompile(`x1="([0-9.]+)"`)
-		groupContent = x1Re.ReplaceAllStringFunc(groupContent, func(m string) string {
-			val := x1Re.FindStringSubmatch(m)[1]
-			x, _ := strconv.ParseFloat(val, 64)
-			return fmt.Sprintf(`x1="%.1f"`, width-x)
-		})
-
-		x2Re := regexp.MustCompile(`x2="([0-9.]+)"`)
-		groupContent = x2Re.ReplaceAllStringFunc(groupContent, func(m string) string {
-			val := x2Re.FindStringSubmatch(m)[1]
-			x, _ := strconv.ParseFloat(val, 64)
-			return fmt.Sprintf(`x2="%.1f"`, width-x)
-		})
+	"simple-plan/plan"
+)
 
-		// Дзеркалимо позицію самої групи (translate)
-		// Нова позиція = viewBoxWidth - стара_позиція - ширина_групи
-		newOffset := viewBoxWidth - offset - width
+// Коди виходу - ті самі категорії, що й в основній програмі simple-plan.
+const (
+	exitFailure = 1
+	exitUsage   = 2
+	exitNoSVG   = 3
+	exitParse   = 4
+	exitWrite   = 6
+)
 
-		// Повертаємо оновлену групу з новим translate
-		return fmt.Sprintf(`<g transform="translate(%.0f, %s)">%s</g>`, newOffset, parts[2], groupContent)
-	})
+func main() {
+	numbers := flag.String("numbers", plan.MirrorKeepNumbers, "номери дверей після віддзеркалення: "+strings.Join(plan.MirrorNumberings, ", ")+
+		" (keep - за тими самими дверима, renumber - у порядку читання, як на вихідному плані)")
+	flag.Parse()
 
-	// Дзеркалимо text елементи поза групами (room-numbers)
-	textXRe := regexp.MustCompile(`<text\s+x="([0-9.]+)"`)
+	if !slices.Contains(plan.MirrorNumberings, *numbers) {
+		slog.Error("невідомий спосіб нумерації", "numbers", *numbers)
+		os.Exit(exitUsage)
+	}
+	if err := run(*numbers); err != nil {
+		slog.Error(err.Error())
+		os.Exit(exitCode(err))
+	}
+}
 
-	// Знаходимо групу room-numbers і обробляємо її окремо
-	roomNumbersRe := regexp.MustCompile(`(?s)(<g id="room-numbers">)(.*?)(</g>)`)
-	content = roomNumbersRe.ReplaceAllStringFunc(content, func(match string) string {
-		parts := roomNumbersRe.FindStringSubmatch(match)
-		if len(parts) < 4 {
-			return match
-		}
+// exitCode повертає код виходу за категорією помилки.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, plan.ErrNoSVG):
+		return exitNoSVG
+	case errors.Is(err, plan.ErrParse):
+		return exitParse
+	case errors.Is(err, plan.ErrWrite):
+		return exitWrite
+	}
+	return exitFailure
+}
 
-		roomContent := parts[2]
+// run віддзеркалює full.html у mirror.html.
+func run(numbers string) error {
+	in, err := os.Open("full.html")
+	if err != nil {
+		return plan.Errorf("помилка читання файлу %s: %w", "full.html", err)
+	}
+	defer in.Close()
 
-		// Дзеркалимо всі text x координати та додаємо text-anchor="end"
-		roomContent = textXRe.ReplaceAllStringFunc(roomContent, func(tm string) string {
-			val := textXRe.FindStringSubmatch(tm)[1]
-			x, _ := strconv.ParseFloat(val, 64)
-			return fmt.Sprintf(`<text x="%.0f" text-anchor="end"`, viewBoxWidth-x)
-		})
+	doc, err := html.Parse(in)
+	if err != nil {
+		return plan.Errorf("%w HTML: %w", plan.ErrParse, err)
+	}
+	d, err := plan.Extractor{}.ExtractNode(doc)
+	if err != nil {
+		return err
+	}
 
-		return parts[1] + roomContent + parts[3]
-	})
+	// Групи під'їздів, лінії, символи та підписи room-numbers віддзеркалює бібліотека
+	mapping, err := plan.MirrorNumbered(context.Background(), d, numbers)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if err := html.Render(&out, doc); err != nil {
+		return plan.Errorf("%w HTML: %w", plan.ErrWrite, err)
+	}
 
 	// Зберігаємо результат
-	err = os.WriteFile("mirror.html", []byte(content), 0644)
-	if err != nil {
-		fmt.Printf("Помилка запису: %v\n", err)
-		return
+	if err := os.WriteFile("mirror.html", out.Bytes(), 0644); err != nil {
+		return plan.Errorf("%w файлу %s: %w", plan.ErrWrite, "mirror.html", err)
 	}
 
-	fmt.Println("✅ Успішно створено mirror.html!")
-	fmt.Println("   - Polygon контури дзеркально відображені")
-	fmt.Println("   - Всі лінії перераховані")
-	fmt.Println("   - Текстові підписи в правильних позиціях")
+	// Відповідність старих і нових номерів - у stdout
+	plan.WriteDoorNumbers(os.Stdout, mapping)
+	renumbered := 0
+	for _, n := range mapping {
+		if !n.Orphan && !n.Invalid {
+			renumbered++
+		}
+	}
+	slog.Info("створено дзеркальний план", "file", "mirror.html", "renumbered", renumbered)
+	return nil
 }
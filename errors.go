@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"simple-plan/plan"
+)
+
+// Коди виходу програми.
+const (
+	exitOK       = 0
+	exitFailure  = 1 // інші помилки, зокрема непройдена перевірка lint
+	exitUsage    = 2 // невідома команда чи неправильні прапорці
+	exitNoSVG    = 3 // у вхідному файлі немає <svg>
+	exitParse    = 4 // не вдалося розібрати HTML, SVG, JSON чи шаблон
+	exitRenderer = 5 // рендерер недоступний або не підтримує формат
+	exitWrite    = 6 // не вдалося записати результат
+)
+
+// errUsage позначає помилки виклику: невідома команда, неправильні прапорці чи аргументи.
+var errUsage = errors.New("неправильний виклик")
+
+// usageErrorf повертає помилку виклику з поясненням.
+func usageErrorf(format string, args ...any) error {
+	return plan.Errorf("%w: %w", errUsage, plan.Errorf(format, args...))
+}
+
+// parseFlags розбирає прапорці підкоманди; помилки розбору - помилки виклику.
+// flag.ErrHelp повертається як є, щоб -h завершувався без помилки.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return plan.Errorf("%w: %w", errUsage, err)
+	}
+	return nil
+}
+
+// exitCode повертає код виходу для помилки.
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, plan.ErrNoSVG):
+		return exitNoSVG
+	case errors.Is(err, plan.ErrRendererMissing), errors.Is(err, plan.ErrUnsupportedFormat):
+		return exitRenderer
+	case errors.Is(err, plan.ErrParse):
+		return exitParse
+	case errors.Is(err, plan.ErrWrite):
+		return exitWrite
+	}
+	return exitFailure
+}
+
+// errorLanguages - мови повідомлень про помилки (-lang).
+var errorLanguages = []string{"uk", "en"}
+
+// errorMessages - переклади повідомлень про помилки: ключ - український шаблон plan.Errorf
+// (чи текст сталої помилки), значення - шаблон мовою перекладу з тими самими аргументами.
+// Причини від ОС і бібліотек (вони англійською) лишаються як є.
+var errorMessages = map[string]map[string]string{
+	"en": {
+		// Категорії помилок
+		"не вдалося знайти тег <svg> у HTML-документі": "no <svg> element found in the HTML document",
+		"помилка парсингу":                             "parse error",
+		"помилка запису":                               "write error",
+		"рендерер недоступний":                         "renderer is not available",
+		"формат не підтримується рендерером":           "format is not supported by the renderer",
+		"неправильний виклик":                          "invalid usage",
+
+		// Виклик CLI
+		"невідома команда %q":                                                "unknown command %q",
+		"невідома мова %q":                                                   "unknown language %q",
+		"невідомий формат %q":                                                "unknown format %q",
+		"невідомий формат журналу %q (text або json)":                        "unknown log format %q (text or json)",
+		"невідомий формат експорту %q (dxf, geojson)":                        "unknown export format %q (dxf, geojson)",
+		"невідоме правило нумерації %q (%s)":                                 "unknown numbering order %q (%s)",
+		"невідоме правило %q (%s)":                                           "unknown rule %q (%s)",
+		"невідомі одиниці %q (%s)":                                           "unknown units %q (%s)",
+		"не вказано -%s":                                                     "-%s is required",
+		"прапорці -quiet і -verbose несумісні":                               "flags -quiet and -verbose are mutually exclusive",
+		"потрібно два файли: diff [опції] старий новий":                      "two files are required: diff [options] old new",
+		"масштаб має бути додатним, отримано %v":                             "scale must be positive, got %v",
+		"допуск має бути додатним, отримано %v":                              "tolerance must be positive, got %v",
+		"розмір одиниці має бути додатним, отримано %v":                      "unit size must be positive, got %v",
+		"кількість знаків не може бути від'ємною, отримано %d":               "precision cannot be negative, got %d",
+		"точка прив'язки має бути x,y, отримано %q":                          "origin must be x,y, got %q",
+		"некоректна відповідність шару %q (очікується ШАР=клас)":             "invalid layer mapping %q (expected LAYER=class)",
+		"ширина прорізу має бути 0 < -min-gap <= -max-gap, отримано %v і %v": "gap width must satisfy 0 < -min-gap <= -max-gap, got %v and %v",
+		"план %q відсутній у маніфесті %s":                                   "plan %q is not in manifest %s",
+		"перевірку не пройдено":                                              "check failed",
+		"перевірку не пройдено: нерозведених перекриттів підписів - %d":      "check failed: %d unresolved label overlaps",
+		"помилка HTTP-сервера: %w":                                           "HTTP server error: %w",
+
+		// Читання файлів
+		"помилка читання файлу %s: %w":                        "cannot read file %s: %w",
+		"помилка читання файлу плану: %w":                     "cannot read plan file: %w",
+		"помилка читання файлу підписів: %w":                  "cannot read labels file: %w",
+		"помилка читання маніфесту: %w":                       "cannot read manifest: %w",
+		"помилка читання метаданих: %w":                       "cannot read metadata: %w",
+		"помилка читання проєкту: %w":                         "cannot read building project: %w",
+		"помилка читання шаблону %s: %w":                      "cannot read template %s: %w",
+		"помилка читання SVG: %w":                             "cannot read SVG: %w",
+		"не вдалося створити або перевірити вхідний файл: %w": "cannot create or check the input file: %w",
+
+		// Розбір
+		"%w маніфесту: %w":               "%w (manifest): %w",
+		"%w метаданих %s: %w":            "%w (metadata %s): %w",
+		"%w файлу підписів %s: %w":       "%w (labels file %s): %w",
+		"%w проєкту будівлі: %w":         "%w (building project): %w",
+		"%w шаблону: %w":                 "%w (template): %w",
+		"%w результату шаблону: %w":      "%w (template output): %w",
+		"%w символу %s з бібліотеки: %w": "%w (symbol %s from the library): %w",
+		"%w результату шаблону: шаблон не створив жодного елемента":               "%w (template output): the template produced no element",
+		"%w DXF: двійковий DXF не підтримується, збережіть файл як ASCII DXF":     "%w (DXF): binary DXF is not supported, save the file as ASCII DXF",
+		"%w DXF: немає об'єктів на шарах, зіставлених з класами плану (шари: %s)": "%w (DXF): no entities on layers mapped to plan classes (layers: %s)",
+		"%w DXF: рядок %d: немає значення для коду %d":                            "%w (DXF): line %d: no value for group code %d",
+		"%w DXF: рядок %d: очікувався код групи, отримано %q":                     "%w (DXF): line %d: expected a group code, got %q",
+		"помилка заповнення шаблону: %w":                                          "cannot fill the template: %w",
+
+		// Запис і рендеринг
+		"%w файлу %s: %w":        "%w (file %s): %w",
+		"%w кешу %s: %w":         "%w (cache %s): %w",
+		"%w стану збірки %s: %w": "%w (build state %s): %w",
+		"%w: помилка створення каталогу %s: %w":                           "%w: cannot create directory %s: %w",
+		"%w: помилка створення каталогу для %s: %w":                       "%w: cannot create directory for %s: %w",
+		"%w: помилка створення кешу %s: %w":                               "%w: cannot create cache %s: %w",
+		"%w: помилка створення кешу: %w":                                  "%w: cannot create cache: %w",
+		"%w: помилка створення файлу %s: %w":                              "%w: cannot create file %s: %w",
+		"помилка очищення кешу: %w":                                       "cannot clean the cache: %w",
+		"помилка рендерингу SVG-вузла: %w":                                "cannot render SVG node: %w",
+		"помилка конвертації через rsvg-convert: %w\nВивід: %s":           "rsvg-convert failed: %w\nOutput: %s",
+		"%w: PDF потребує rsvg-convert, який не встановлено на сервері":   "%w: PDF requires rsvg-convert, which is not installed on the server",
+		"не вдалося визначити розмір SVG (немає width/height чи viewBox)": "cannot determine the SVG size (no width/height or viewBox)",
+		"SVG не має коректного viewBox або width/height":                  "SVG has no valid viewBox or width/height",
+
+		// Операції над планом
+		"невідоме розміщення легенди %q (допустимі: %s)":                                    "unknown legend placement %q (allowed: %s)",
+		"на плані не використовується жодного символу з <defs>":                             "the plan uses no symbol from <defs>",
+		"невідомий спосіб нумерації після віддзеркалення %q (%s)":                           "unknown numbering after mirroring %q (%s)",
+		"після віддзеркалення біля дверей %d номерів замість %d: перенумерування неможливе": "after mirroring %d numbers are next to doors instead of %d: cannot renumber",
+		"невідомі одиниці DXF %q (%s)":                                                      "unknown DXF units %q (%s)",
+		"найменша ширина прорізу %s більша за найбільшу %s":                                 "the minimum gap width %s is larger than the maximum %s",
+
+		// Маніфест і проєкт будівлі
+		"маніфест не містить планів":                                        "the manifest contains no plans",
+		"план %d: не вказано назву (name)":                                  "plan %d: no name",
+		"план %q вказано двічі":                                             "plan %q is listed twice",
+		"план %q: %w":                                                       "plan %q: %w",
+		"план %q: не вказано файл плану (source)":                           "plan %q: no plan file (source)",
+		"план %q: не вказано жодного виходу (outputs)":                      "plan %q: no outputs",
+		"план %q: вихід %d без шляху (path)":                                "plan %q: output %d has no path",
+		"план %q: невідомий формат %q для %s (svg, png, pdf, dxf, geojson)": "plan %q: unknown format %q for %s (svg, png, pdf, dxf, geojson)",
+		"файл %s записують плани %q і %q":                                   "file %s is written by plans %q and %q",
+		"проєкт будівлі не містить поверхів":                                "the building project contains no floors",
+		"поверх %d: не вказано файл плану (source)":                         "floor %d: no plan file (source)",
+		"поверх %d вказано двічі":                                           "floor %d is listed twice",
+		"поверх %d: %w":      "floor %d: %w",
+		"поверх %d (%s): %w": "floor %d (%s): %w",
+
+		// IMDF
+		"пакет IMDF: немає жодного поверху":             "IMDF package: no levels",
+		"пакет IMDF не пройшов перевірку:\n%w":          "IMDF package failed validation:\n%w",
+		"manifest.json: не вказано version чи language": "manifest.json: version or language is missing",
+		"%s: id %q не є UUID":                           "%s: id %q is not a UUID",
+		"%s: id %s уже використано в %s":                "%s: id %s is already used in %s",
+		"%s: невідомий feature_type %q":                 "%s: unknown feature_type %q",
+		"%s: немає властивості %s":                      "%s: property %s is missing",
+		"%s: властивість %s не може бути порожньою":     "%s: property %s cannot be empty",
+		"%s: геометрія має бути null":                   "%s: geometry must be null",
+		"%s: геометрія має бути %s":                     "%s: geometry must be %s",
+		"%s: %s посилається на відсутній %s %s":         "%s: %s refers to a missing %s %s",
+		"%s: country %q має бути кодом ISO 3166-1 (UA)": "%s: country %q must be an ISO 3166-1 code (UA)",
+		"%s: ordinal %v уже має інший поверх":           "%s: ordinal %v is already used by another level",
+		"%s.geojson: немає жодного об'єкта":             "%s.geojson: no features",
+		"level %s: %d з %d прорізів позначено головними входами (pedestrian.principal), очікується не більше %d": "level %s: %d of %d openings are principal entrances (pedestrian.principal), expected at most %d",
+	},
+}
+
+// localizeError повертає текст помилки мовою lang.
+func localizeError(err error, lang string) string {
+	messages := errorMessages[lang]
+	if len(messages) == 0 {
+		return err.Error()
+	}
+	return translateError(err, messages).Error()
+}
+
+// translateError перекладає помилку за каталогом: шаблон plan.Error замінюється перекладом
+// з тими самими аргументами, вкладені помилки перекладаються рекурсивно, сталі - за текстом.
+func translateError(err error, messages map[string]string) error {
+	switch e := err.(type) {
+	case *plan.Error:
+		format, ok := messages[e.Format]
+		if !ok {
+			format = e.Format
+		}
+		args := make([]any, len(e.Args))
+		for i, arg := range e.Args {
+			if inner, ok := arg.(error); ok {
+				arg = translateError(inner, messages)
+			}
+			args[i] = arg
+		}
+		return fmt.Errorf(format, args...)
+	case interface{ Unwrap() []error }:
+		// errors.Join: помилки через новий рядок; інші обгортки лишаються як є
+		inner := e.Unwrap()
+		if errors.Join(inner...).Error() != err.Error() {
+			break
+		}
+		list := make([]error, len(inner))
+		for i, e := range inner {
+			list[i] = translateError(e, messages)
+		}
+		return errors.Join(list...)
+	}
+	if text, ok := messages[err.Error()]; ok {
+		return errors.New(text)
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+
+	"simple-plan/plan"
+)
+
+// errorConstructors - функції, перший аргумент яких є шаблоном повідомлення про помилку.
+var errorConstructors = map[string]bool{"Errorf": true, "usageErrorf": true, "report": true, "New": true}
+
+// TestErrorMessagesTranslated перевіряє, що кожен український шаблон помилки в коді має переклад.
+func TestErrorMessagesTranslated(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	planFiles, _ := filepath.Glob(filepath.Join("plan", "*.go"))
+	for _, name := range append(files, planFiles...) {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			var fn string
+			switch f := call.Fun.(type) {
+			case *ast.Ident:
+				fn = f.Name
+			case *ast.SelectorExpr:
+				fn = f.Sel.Name
+			}
+			format, ok := stringLiteral(call.Args[0])
+			if !errorConstructors[fn] || !ok || !strings.ContainsFunc(format, isCyrillic) {
+				return true
+			}
+			for lang, messages := range errorMessages {
+				if _, ok := messages[format]; !ok {
+					t.Errorf("%s: немає перекладу (%s) для %q", name, lang, format)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// stringLiteral повертає значення рядкового літерала, зокрема склеєного з кількох через +.
+func stringLiteral(e ast.Expr) (string, bool) {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		s, err := strconv.Unquote(e.Value)
+		return s, err == nil
+	case *ast.BinaryExpr:
+		x, okX := stringLiteral(e.X)
+		y, okY := stringLiteral(e.Y)
+		return x + y, okX && okY
+	}
+	return "", false
+}
+
+func isCyrillic(r rune) bool {
+	return unicode.Is(unicode.Cyrillic, r)
+}
+
+func TestLocalizeError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{usageErrorf("масштаб має бути додатним, отримано %v", -1.0), "invalid usage: scale must be positive, got -1"},
+		{plan.Errorf("план %q: %w", "hall", plan.Errorf("%w файлу %s: %w", plan.ErrWrite, "a.svg", errors.New("permission denied"))),
+			`plan "hall": write error (file a.svg): permission denied`},
+		{plan.Errorf("пакет IMDF не пройшов перевірку:\n%w", errors.Join(
+			plan.Errorf("%s: немає властивості %s", "unit 1", "name"),
+			plan.Errorf("%s.geojson: немає жодного об'єкта", "venue"))),
+			"IMDF package failed validation:\nunit 1: property name is missing\nvenue.geojson: no features"},
+	}
+	for _, c := range cases {
+		if got := localizeError(c.err, "en"); got != c.want {
+			t.Errorf("localizeError(%q) = %q, очікувалось %q", c.err, got, c.want)
+		}
+	}
+
+	// Переклад не змінює категорію помилки
+	err := usageErrorf("невідомий формат %q", "bmp")
+	if exitCode(err) != exitUsage || localizeError(err, "uk") != `неправильний виклик: невідомий формат "bmp"` {
+		t.Errorf("помилка виклику: код %d, текст %q", exitCode(err), localizeError(err, "uk"))
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"simple-plan/plan"
+)
+
+// Еталонні файли лежать у testdata/golden; перегенерувати їх:
+//
+//	go test -run TestGolden -update
+var update = flag.Bool("update", false, "перезаписати еталонні SVG і PNG у testdata/golden")
+
+const (
+	goldenDir   = "testdata/golden"
+	failuresDir = "testdata/failures"
+
+	// goldenWidth - ширина растру; невелика, щоб еталони займали мало місця
+	goldenWidth = 800
+
+	// pixelTolerance - допустима різниця яскравості пікселя (0-255), що не вважається відмінністю
+	pixelTolerance = 24
+	// maxDiffRatio - допустима частка відмінних пікселів (згладжування, округлення)
+	maxDiffRatio = 0.002
+)
+
+// goldenFixture - вхідний документ для еталонного тесту.
+type goldenFixture struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// goldenFixtures повертає плани з кореня репозиторію та вбудований приклад exampleHTMLContent.
+func goldenFixtures() []goldenFixture {
+	fixtures := []goldenFixture{{
+		name: "example",
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(exampleHTMLContent)), nil
+		},
+	}}
+	for _, file := range []string{"plan1.html", "plan2.html", "full.html"} {
+		fixtures = append(fixtures, goldenFixture{
+			name: strings.TrimSuffix(file, ".html"),
+			open: func() (io.ReadCloser, error) { return os.Open(file) },
+		})
+	}
+	return fixtures
+}
+
+// goldenRenderer - рендерер, растр якого порівнюється з еталоном name.<renderer>.png.
+type goldenRenderer struct {
+	name     string
+	renderer plan.Renderer
+}
+
+// goldenRenderers повертає рендерери для порівняння в сталому порядку. rsvg-convert свідомо
+// не порівнюється: його растр залежить від версії librsvg і встановлених шрифтів, тож еталон
+// з однієї машини не збігся б на іншій. Вбудований oksvg дає однаковий результат усюди.
+func goldenRenderers() []goldenRenderer {
+	return []goldenRenderer{{"oksvg", plan.OksvgRenderer{}}}
+}
+
+func TestGolden(t *testing.T) {
+	ctx := context.Background()
+	for _, fx := range goldenFixtures() {
+		for _, mirrored := range []bool{false, true} {
+			name := fx.name
+			if mirrored {
+				name += ".mirror"
+			}
+			t.Run(name, func(t *testing.T) {
+				svg := extractFixture(t, fx, mirrored)
+				compareGoldenSVG(t, name+".svg", svg)
+
+				for _, r := range goldenRenderers() {
+					t.Run(r.name, func(t *testing.T) {
+						var buf bytes.Buffer
+						opts := plan.RenderOptions{Format: plan.FormatPNG, Width: goldenWidth}
+						if err := r.renderer.Render(ctx, &buf, bytes.NewReader(svg), opts); err != nil {
+							t.Fatalf("%s: рендеринг %s: %v", name, r.name, err)
+						}
+						compareGoldenPNG(t, name+"."+r.name+".png", buf.Bytes())
+					})
+				}
+			})
+		}
+	}
+}
+
+// extractFixture витягує SVG з фікстури і, за потреби, віддзеркалює його.
+func extractFixture(t *testing.T, fx goldenFixture, mirrored bool) []byte {
+	t.Helper()
+	ctx := context.Background()
+
+	rc, err := fx.open()
+	if err != nil {
+		t.Fatalf("відкриття фікстури %s: %v", fx.name, err)
+	}
+	defer rc.Close()
+
+	d, err := plan.Extractor{InjectSymbols: true}.Extract(ctx, rc)
+	if err != nil {
+		t.Fatalf("витягнення SVG з %s: %v", fx.name, err)
+	}
+	if mirrored {
+		if err := plan.Mirror(ctx, d); err != nil {
+			t.Fatalf("віддзеркалення %s: %v", fx.name, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (plan.Serializer{}).Serialize(ctx, &buf, d); err != nil {
+		t.Fatalf("серіалізація %s: %v", fx.name, err)
+	}
+	return buf.Bytes()
+}
+
+// compareGoldenSVG порівнює SVG з еталоном побайтово (з точністю до закінчень рядків).
+func compareGoldenSVG(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join(goldenDir, name)
+	if *update {
+		writeGolden(t, path, got)
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("немає еталона %s (запустіть go test -update): %v", path, err)
+	}
+	wantLines := strings.Split(strings.ReplaceAll(string(want), "\r\n", "\n"), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	for i := 0; i < max(len(wantLines), len(gotLines)); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			writeFailure(t, name, got)
+			t.Errorf("%s відрізняється від еталона у рядку %d:\n  очікувалось: %s\n  отримано:    %s", name, i+1, w, g)
+			return
+		}
+	}
+}
+
+// compareGoldenPNG порівнює растр з еталоном попіксельно з допуском на яскравість
+// і частку відмінних пікселів; при невдачі зберігає отримане зображення та карту відмінностей.
+func compareGoldenPNG(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join(goldenDir, name)
+	if *update {
+		writeGolden(t, path, got)
+		return
+	}
+
+	wantData, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("немає еталона %s (запустіть go test -update): %v", path, err)
+	}
+	want, err := png.Decode(bytes.NewReader(wantData))
+	if err != nil {
+		t.Fatalf("декодування еталона %s: %v", path, err)
+	}
+	img, err := png.Decode(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("декодування результату %s: %v", name, err)
+	}
+
+	if want.Bounds().Size() != img.Bounds().Size() {
+		writeFailure(t, name, got)
+		t.Errorf("%s: розмір %v, очікувався %v", name, img.Bounds().Size(), want.Bounds().Size())
+		return
+	}
+
+	diff, count := diffImages(want, img)
+	total := want.Bounds().Dx() * want.Bounds().Dy()
+	if ratio := float64(count) / float64(total); ratio > maxDiffRatio {
+		writeFailure(t, name, got)
+		var buf bytes.Buffer
+		png.Encode(&buf, diff)
+		writeFailure(t, strings.TrimSuffix(name, ".png")+".diff.png", buf.Bytes())
+		t.Errorf("%s: відрізняється %d пікселів з %d (%.3f%%, допустимо %.3f%%); див. %s",
+			name, count, total, ratio*100, maxDiffRatio*100, failuresDir)
+	}
+}
+
+// diffImages повертає карту відмінностей (еталон блідо-сірим, відмінні пікселі червоним)
+// і кількість пікселів, яскравість яких відрізняється більше ніж на pixelTolerance.
+func diffImages(want, got image.Image) (*image.RGBA, int) {
+	b := want.Bounds()
+	gb := got.Bounds()
+	diff := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	count := 0
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			lw := luma(want.At(b.Min.X+x, b.Min.Y+y))
+			lg := luma(got.At(gb.Min.X+x, gb.Min.Y+y))
+			d := lw - lg
+			if d < 0 {
+				d = -d
+			}
+			if d > pixelTolerance {
+				count++
+				diff.Set(x, y, color.RGBA{0xFF, 0, 0, 0xFF})
+				continue
+			}
+			// Блідий еталон як фон, щоб було видно, де саме відмінності
+			v := uint8(192 + lw/4)
+			diff.Set(x, y, color.RGBA{v, v, v, 0xFF})
+		}
+	}
+	return diff, count
+}
+
+// luma повертає яскравість кольору (0-255) з урахуванням прозорості на білому фоні.
+func luma(c color.Color) int {
+	r, g, b, a := c.RGBA()
+	white := 0xFFFF - a
+	r, g, b = r+white, g+white, b+white
+	return int((299*r + 587*g + 114*b) / 1000 >> 8)
+}
+
+// writeGolden записує еталонний файл.
+func writeGolden(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("оновлено еталон %s", path)
+}
+
+// writeFailure зберігає отриманий результат для перегляду після невдалого порівняння.
+func writeFailure(t *testing.T, name string, data []byte) {
+	t.Helper()
+	path := filepath.Join(failuresDir, name)
+	if err := os.MkdirAll(failuresDir, 0755); err != nil {
+		t.Logf("не вдалося створити %s: %v", failuresDir, err)
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Logf("не вдалося зберегти %s: %v", path, err)
+		return
+	}
+	t.Logf("результат збережено у %s", path)
+}
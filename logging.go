@@ -0,0 +1,34 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+)
+
+// setupLogging налаштовує журнал за замовчуванням: повідомлення пишуться у w (stderr),
+// щоб stdout лишався для даних (SVG, списки, звіти). quiet лишає тільки попередження й помилки,
+// verbose додає налагоджувальні повідомлення; format - text або json (для CI).
+func setupLogging(w io.Writer, quiet, verbose bool, format string) error {
+	level := slog.LevelInfo
+	switch {
+	case quiet && verbose:
+		return usageErrorf("прапорці -quiet і -verbose несумісні")
+	case quiet:
+		level = slog.LevelWarn
+	case verbose:
+		level = slog.LevelDebug
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return usageErrorf("невідомий формат журналу %q (text або json)", format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"simple-plan/plan"
+)
+
+// restoreLogger повертає журнал за замовчуванням після тесту, що його змінює.
+func restoreLogger(t *testing.T) {
+	t.Helper()
+	old := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(old) })
+}
+
+func TestSetupLogging(t *testing.T) {
+	restoreLogger(t)
+	cases := []struct {
+		name           string
+		quiet, verbose bool
+		want, hidden   []string
+	}{
+		{"за замовчуванням", false, false, []string{"info", "warn"}, []string{"debug"}},
+		{"-quiet", true, false, []string{"warn", "error"}, []string{"debug", "info"}},
+		{"-verbose", false, true, []string{"debug", "info", "warn"}, nil},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := setupLogging(&buf, c.quiet, c.verbose, "text"); err != nil {
+			t.Fatal(err)
+		}
+		slog.Debug("debug")
+		slog.Info("info")
+		slog.Warn("warn")
+		slog.Error("error")
+		for _, msg := range c.want {
+			if !strings.Contains(buf.String(), "msg="+msg) {
+				t.Errorf("%s: немає повідомлення %s у журналі:\n%s", c.name, msg, buf.String())
+			}
+		}
+		for _, msg := range c.hidden {
+			if strings.Contains(buf.String(), "msg="+msg) {
+				t.Errorf("%s: повідомлення %s не мало потрапити в журнал:\n%s", c.name, msg, buf.String())
+			}
+		}
+	}
+
+	// JSON: кожен рядок - окремий об'єкт з рівнем, повідомленням і атрибутами
+	var buf bytes.Buffer
+	if err := setupLogging(&buf, false, false, "json"); err != nil {
+		t.Fatal(err)
+	}
+	slog.Warn("увага", "file", "plan.html")
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("журнал не JSON: %v\n%s", err, buf.String())
+	}
+	if entry["level"] != "WARN" || entry["msg"] != "увага" || entry["file"] != "plan.html" {
+		t.Errorf("запис журналу %v", entry)
+	}
+
+	for _, c := range []struct {
+		quiet, verbose bool
+		format         string
+	}{{true, true, "text"}, {false, false, "xml"}} {
+		if err := setupLogging(io.Discard, c.quiet, c.verbose, c.format); exitCode(err) != exitUsage {
+			t.Errorf("quiet=%v verbose=%v format=%s: %v, очікувалась помилка виклику", c.quiet, c.verbose, c.format, err)
+		}
+	}
+}
+
+// captureOutput виконує f, перехоплюючи stdout і stderr (журнал пишеться в stderr, як у main).
+func captureOutput(t *testing.T, f func() error) (stdout, stderr string, err error) {
+	t.Helper()
+	restoreLogger(t)
+	oldOut, oldErr := os.Stdout, os.Stderr
+	t.Cleanup(func() { os.Stdout, os.Stderr = oldOut, oldErr })
+
+	outR, outW, perr := os.Pipe()
+	if perr != nil {
+		t.Fatal(perr)
+	}
+	errR, errW, perr := os.Pipe()
+	if perr != nil {
+		t.Fatal(perr)
+	}
+	os.Stdout, os.Stderr = outW, errW
+	if err := setupLogging(errW, false, true, "text"); err != nil {
+		t.Fatal(err)
+	}
+
+	outC, errC := make(chan string), make(chan string)
+	go func() { data, _ := io.ReadAll(outR); outC <- string(data) }()
+	go func() { data, _ := io.ReadAll(errR); errC <- string(data) }()
+	err = f()
+	outW.Close()
+	errW.Close()
+	os.Stdout, os.Stderr = oldOut, oldErr
+	return <-outC, <-errC, err
+}
+
+// stdoutSVGCommands - команди, що з -out - пишуть SVG у stdout; report - частина звіту,
+// яка має потрапити в stderr.
+var stdoutSVGCommands = []struct {
+	name   string
+	run    func([]string) error
+	args   []string
+	report string
+}{
+	{"legend", runLegend, []string{"-in", "plan1.html"}, ""},
+	{"title", runTitle, []string{"-in", "plan1.html"}, ""},
+	{"symbols", runSymbols, []string{"-in", "plan1.html"}, ""},
+	{"optimize", runOptimize, []string{"-in", "plan1.html"}, "байт"},
+	{"collisions", runCollisions, []string{"-in", "plan1.html", "-fix", "-format", "json"}, "[]"},
+	{"doors", runDoors, []string{"-in", "full.html"}, "osb: не номер дверей"},
+	{"walls", runWalls, []string{"-in", "plan1.html"}, "ламаних"},
+}
+
+func TestOutStdoutPureSVG(t *testing.T) {
+	for _, c := range stdoutSVGCommands {
+		t.Run(c.name, func(t *testing.T) {
+			checkStdoutSVG(t, func() error { return c.run(append(c.args, "-out", "-")) }, c.report)
+		})
+	}
+
+	// Вхідне креслення для dxf створює export
+	t.Run("dxf", func(t *testing.T) {
+		dxf := filepath.Join(t.TempDir(), "plan.dxf")
+		if _, _, err := captureOutput(t, func() error {
+			return runExport([]string{"-in", "plan1.html", "-format", "dxf", "-out", dxf})
+		}); err != nil {
+			t.Fatal(err)
+		}
+		checkStdoutSVG(t, func() error { return runDXF([]string{"-in", dxf, "-out", "-"}) }, "doors → doors")
+	})
+}
+
+// checkStdoutSVG виконує команду і перевіряє, що stdout - лише SVG для конвеєра,
+// а звіт (його частина report) і журнал пішли в stderr.
+func checkStdoutSVG(t *testing.T, run func() error, report string) {
+	t.Helper()
+	stdout, stderr, err := captureOutput(t, run)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	svg := strings.TrimSpace(stdout)
+	if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") || strings.Count(svg, "<svg") != 1 {
+		t.Errorf("stdout не є чистим SVG:\nпочаток: %.80q\nкінець: %q", svg, svg[max(0, len(svg)-80):])
+	}
+	if !strings.Contains(stderr, report) {
+		t.Errorf("звіт %q не потрапив у stderr:\n%s", report, stderr)
+	}
+	if _, err := (plan.Extractor{}).Extract(context.Background(), strings.NewReader(stdout)); err != nil {
+		t.Errorf("stdout не розбирається як SVG: %v", err)
+	}
+}
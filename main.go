@@ -2,16 +2,19 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"image"
-	"image/png"
 	"io"
+	"log/slog"
 	"os"
-	"os/exec"
+	"slices"
+	"strings"
 
-	"github.com/srwiley/oksvg"
-	"github.com/srwiley/rasterx"
 	"golang.org/x/net/html"
+
+	"simple-plan/plan"
 )
 
 // Приклад HTML-документа, який ми будемо використовувати як вміст файлу.
@@ -41,19 +44,19 @@ const exampleHTMLContent = `
 </html>
 `
 
+// Файли конвеєра без маніфесту і типові значення прапорців підкоманд.
+// Набір планів проєкту описується в маніфесті simple-plan.json.
 const (
-	// 1. Визначаємо ім'я вхідного файлу
-	inputFilename = "mirror.html" //"full.html" //"plan2.html" //"plan1.html"
-	// 1. Визначаємо ім'я вихідного файлу
-	targetSVGFilename = "mirror.svg" //"full.svg" //"2.svg" //"1.svg"
-	targetPNGFilename = "mirror.png" //"full.png" //"2.png" //"1.png"
+	inputFilename     = "mirror.html"
+	targetSVGFilename = "mirror.svg"
+	targetPNGFilename = "mirror.png"
 )
 
 // ensureFileExists перевіряє, чи існує файл, і якщо ні, створює його з прикладом вмісту.
 func ensureFileExists(filename string) error {
 	_, err := os.Stat(filename)
 	if os.IsNotExist(err) {
-		fmt.Printf("Файл %s не знайдено. Створюємо його з тестовим вмістом...\n", filename)
+		slog.Info("вхідний файл не знайдено, створюємо його з тестовим вмістом", "file", filename)
 		return os.WriteFile(filename, []byte(exampleHTMLContent), 0644)
 	}
 	return err // Повертає nil, якщо файл існує, або іншу помилку Stat
@@ -64,15 +67,13 @@ func parseContent(reader io.Reader) (*html.Node, error) {
 	// Парсинг HTML
 	doc, err := html.Parse(reader)
 	if err != nil {
-		return nil, fmt.Errorf("Помилка парсингу HTML: %v", err)
+		return nil, plan.Errorf("%w HTML: %w", plan.ErrParse, err)
 	}
 
-	fmt.Println("--- Результати парсингу з файлу ---")
-
 	// 2. Обхід дерева DOM для пошуку потрібного елемента (<title>)
 	foundTitle := findTag(doc, "title")
 	if foundTitle != "" {
-		fmt.Printf("Знайдено заголовок сторінки (<title>): %s\n", foundTitle)
+		slog.Debug("знайдено заголовок сторінки", "title", foundTitle)
 	}
 
 	// 3. Пошук елемента за класом 'main-content'
@@ -95,9 +96,9 @@ func parseContent(reader io.Reader) (*html.Node, error) {
 	traverse(doc, searchNode)
 
 	if paragraphContent != "" {
-		fmt.Printf("Знайдено вміст параграфа: %s\n", paragraphContent)
+		slog.Debug("знайдено вміст параграфа", "text", paragraphContent)
 	} else {
-		fmt.Println("Елемент з класом 'main-content' не знайдено.")
+		slog.Debug("елемент з класом 'main-content' не знайдено")
 	}
 
 	return doc, nil
@@ -105,126 +106,28 @@ func parseContent(reader io.Reader) (*html.Node, error) {
 
 // extractAndSaveSVG знаходить перший SVG-елемент у дереві та зберігає його у вказаний файл.
 func extractAndSaveSVG(doc *html.Node, outputFilename string) error {
-	var svgNode *html.Node
-
-	// Рекурсивна функція пошуку першого SVG-вузла
-	var findSVG func(*html.Node)
-	findSVG = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "svg" {
-			svgNode = n
-			return
-		}
-		// Обхід дочірніх елементів
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			// Якщо ми вже знайшли SVG-вузол, зупиняємо пошук у цій гілці.
-			if svgNode != nil {
-				return
-			}
-			findSVG(c)
-		}
-	}
-
-	findSVG(doc)
-
-	if svgNode == nil {
-		return fmt.Errorf("Не вдалося знайти тег <svg> у HTML-документі")
+	// Додаємо з бібліотеки символи, які використовуються, але не визначені в документі
+	d, err := plan.Extractor{InjectSymbols: true}.ExtractNode(doc)
+	if err != nil {
+		return err
 	}
 
 	// Серіалізуємо SVG-вузол з правильним форматуванням для SVG
 	var buf bytes.Buffer
-	if err := renderSVG(&buf, svgNode); err != nil {
-		return fmt.Errorf("Помилка рендерингу SVG-вузла: %v", err)
+	if err := (plan.Serializer{}).Serialize(context.Background(), &buf, d); err != nil {
+		return err
 	}
 
 	// Зберігаємо серіалізований вміст у файл
-	err := os.WriteFile(outputFilename, buf.Bytes(), 0644)
+	err = os.WriteFile(outputFilename, buf.Bytes(), 0644)
 	if err != nil {
-		return fmt.Errorf("Помилка запису файлу %s: %v", outputFilename, err)
+		return plan.Errorf("%w файлу %s: %w", plan.ErrWrite, outputFilename, err)
 	}
 
-	fmt.Printf("\n--> Успішно витягнуто та збережено SVG у файл: %s\n", outputFilename)
+	slog.Info("SVG витягнуто і збережено", "file", outputFilename)
 	return nil
 }
 
-// renderSVG рендерить SVG-вузол у правильному форматі XML з самозакриваючими тегами
-func renderSVG(w io.Writer, n *html.Node) error {
-	// Список SVG елементів, які повинні бути самозакриваючими
-	selfClosingTags := map[string]bool{
-		"circle": true, "ellipse": true, "line": true, "path": true,
-		"polygon": true, "polyline": true, "rect": true, "use": true,
-		"image": true, "stop": true, "animate": true, "animateMotion": true,
-		"animateTransform": true, "set": true,
-	}
-
-	var render func(*html.Node, int) error
-	render = func(n *html.Node, depth int) error {
-		indent := ""
-		for i := 0; i < depth; i++ {
-			indent += "    "
-		}
-
-		switch n.Type {
-		case html.ElementNode:
-			// Відкриваючий тег
-			fmt.Fprintf(w, "%s<%s", indent, n.Data)
-
-			// Атрибути
-			for _, attr := range n.Attr {
-				fmt.Fprintf(w, " %s=\"%s\"", attr.Key, attr.Val)
-			}
-
-			// Перевірка чи є дочірні елементи
-			hasChildren := n.FirstChild != nil
-
-			// Якщо це самозакриваючий тег і немає дітей
-			if selfClosingTags[n.Data] && !hasChildren {
-				fmt.Fprintf(w, " />\n")
-			} else if !hasChildren && n.Data != "svg" && n.Data != "g" && n.Data != "defs" && n.Data != "style" && n.Data != "text" {
-				// Інші порожні елементи (крім контейнерів)
-				fmt.Fprintf(w, " />\n")
-			} else {
-				fmt.Fprintf(w, ">")
-
-				// Якщо це текстовий контейнер, не додаємо новий рядок
-				if n.Data == "text" || n.Data == "style" {
-					// Рендеримо дітей без відступів
-					for c := n.FirstChild; c != nil; c = c.NextSibling {
-						if c.Type == html.TextNode {
-							fmt.Fprint(w, c.Data)
-						} else {
-							render(c, 0)
-						}
-					}
-					fmt.Fprintf(w, "</%s>\n", n.Data)
-				} else {
-					fmt.Fprint(w, "\n")
-					// Рендеримо дочірні елементи
-					for c := n.FirstChild; c != nil; c = c.NextSibling {
-						if err := render(c, depth+1); err != nil {
-							return err
-						}
-					}
-					fmt.Fprintf(w, "%s</%s>\n", indent, n.Data)
-				}
-			}
-
-		case html.TextNode:
-			// Пропускаємо порожні текстові вузли (пробіли між тегами)
-			trimmed := bytes.TrimSpace([]byte(n.Data))
-			if len(trimmed) > 0 {
-				fmt.Fprintf(w, "%s%s\n", indent, n.Data)
-			}
-
-		case html.CommentNode:
-			fmt.Fprintf(w, "%s<!-- %s -->\n", indent, n.Data)
-		}
-
-		return nil
-	}
-
-	return render(n, 0)
-}
-
 // findTag рекурсивно шукає вузол із заданим ім'ям тега і повертає його текстовий вміст.
 func findTag(n *html.Node, tagName string) string {
 	if n.Type == html.ElementNode && n.Data == tagName {
@@ -256,260 +159,128 @@ func traverse(n *html.Node, f func(*html.Node) bool) {
 }
 
 func main() {
-
-	// 2. Створюємо вхідний файл, якщо він не існує
-	if err := ensureFileExists(inputFilename); err != nil {
-		fmt.Printf("Не вдалося створити або перевірити вхідний файл: %v\n", err)
-		return
-	}
-
-	// 3. Відкриваємо файл для читання
-	file, err := os.Open(inputFilename)
-	if err != nil {
-		fmt.Printf("Помилка відкриття файлу %s: %v\n", inputFilename, err)
-		return
+	// Глобальні прапорці задаються перед підкомандою: simple-plan -quiet -lang en build
+	quiet := flag.Bool("quiet", false, "виводити лише попередження та помилки")
+	verbose := flag.Bool("verbose", false, "виводити налагоджувальні повідомлення")
+	logFormat := flag.String("log-format", "text", "формат журналу: text або json")
+	lang := flag.String("lang", defaultLanguage(), "мова повідомлень про помилки: "+strings.Join(errorLanguages, ", "))
+	flag.Usage = printUsage
+	flag.Parse()
+
+	err := setupLogging(os.Stderr, *quiet, *verbose, *logFormat)
+	if err == nil && !slices.Contains(errorLanguages, *lang) {
+		err = usageErrorf("невідома мова %q", *lang)
 	}
-	defer file.Close()
-
-	fmt.Printf("Файл '%s' успішно відкрито.\n", inputFilename)
-
-	// 4. Виконуємо парсинг, передаючи файл (io.Reader)
-	doc, err := parseContent(file)
 	if err != nil {
-		return
+		fmt.Fprintf(os.Stderr, "Помилка: %s\n", localizeError(err, *lang))
+		os.Exit(exitCode(err))
 	}
 
-	// 5. Витягуємо SVG і зберігаємо його
-	if err := extractAndSaveSVG(doc, targetSVGFilename); err != nil {
-		fmt.Printf("Помилка витягнення SVG: %v\n", err)
-		return
-	}
-
-	// 6. Конвертуємо SVG в PNG
-	if err := convertSVGToPNG(targetSVGFilename, targetPNGFilename, 2450, 830); err != nil {
-		fmt.Printf("Помилка конвертації SVG в PNG: %v\n", err)
-		return
+	if err := run(flag.Args()); err != nil && !errors.Is(err, flag.ErrHelp) {
+		code := exitCode(err)
+		slog.Error(localizeError(err, *lang), "code", code)
+		os.Exit(code)
 	}
 }
 
-// convertSVGToPNG конвертує SVG файл у PNG з заданими розмірами
-// Використовує rsvg-convert для кращої підтримки всіх SVG можливостей
-func convertSVGToPNG(svgFilename, pngFilename string, width, height int) error {
-	// Перевіряємо чи встановлений rsvg-convert
-	_, err := exec.LookPath("rsvg-convert")
-	if err != nil {
-		// Якщо rsvg-convert не встановлено, пробуємо використати oksvg
-		return convertSVGToPNGWithOksvg(svgFilename, pngFilename, width, height)
+// defaultLanguage повертає мову повідомлень зі змінної оточення SIMPLE_PLAN_LANG (за замовчуванням uk).
+func defaultLanguage() string {
+	if lang := os.Getenv("SIMPLE_PLAN_LANG"); lang != "" {
+		return lang
 	}
+	return "uk"
+}
 
-	// Для PNG створюємо SVG БЕЗ дзеркального відображення, читаючи з HTML
-	// Читаємо оригінальний HTML файл
-	htmlFile := inputFilename
-	htmlData, err := os.ReadFile(htmlFile)
-	if err != nil {
-		return fmt.Errorf("помилка читання HTML: %v", err)
+// run виконує підкоманду, збірку маніфесту або конвеєр за замовчуванням.
+func run(args []string) error {
+	// Підкоманди (legend, ...) обробляються окремо від основного конвеєра
+	if len(args) > 0 {
+		return runCommand(args[0], args[1:])
 	}
 
-	// Парсимо HTML і витягуємо SVG
-	doc, err := html.Parse(bytes.NewReader(htmlData))
-	if err != nil {
-		return fmt.Errorf("помилка парсингу HTML: %v", err)
-	}
-
-	// Знаходимо SVG вузол
-	var svgNode *html.Node
-	var findSVG func(*html.Node)
-	findSVG = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "svg" {
-			svgNode = n
-			return
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			if svgNode != nil {
-				return
-			}
-			findSVG(c)
-		}
+	// Якщо є маніфест, збираємо всі описані в ньому плани
+	if _, err := os.Stat(manifestFilename); err == nil {
+		return buildManifest(manifestFilename, buildOptions{})
 	}
-	findSVG(doc)
+	return runPipeline()
+}
 
-	if svgNode == nil {
-		return fmt.Errorf("не знайдено SVG у HTML")
+// runPipeline - конвеєр без маніфесту: mirror.html → mirror.svg → mirror.png.
+func runPipeline() error {
+	// 1. Створюємо вхідний файл, якщо він не існує
+	if err := ensureFileExists(inputFilename); err != nil {
+		return plan.Errorf("не вдалося створити або перевірити вхідний файл: %w", err)
 	}
 
-	// Серіалізуємо SVG без трансформацій
-	var buf bytes.Buffer
-	if err := renderSVG(&buf, svgNode); err != nil {
-		return fmt.Errorf("помилка рендерингу SVG: %v", err)
+	// 2. Відкриваємо файл для читання
+	file, err := os.Open(inputFilename)
+	if err != nil {
+		return plan.Errorf("помилка читання файлу %s: %w", inputFilename, err)
 	}
+	defer file.Close()
 
-	// Видаляємо дзеркальні трансформації для нормального PNG
-	cleanSVG := replaceTransform(buf.String())
+	slog.Debug("файл відкрито", "file", inputFilename)
 
-	// Зберігаємо у тимчасовий файл
-	tempSVG := "temp_for_png.svg"
-	if err := os.WriteFile(tempSVG, []byte(cleanSVG), 0644); err != nil {
-		return fmt.Errorf("помилка створення тимчасового SVG: %v", err)
+	// 3. Виконуємо парсинг, передаючи файл (io.Reader)
+	doc, err := parseContent(file)
+	if err != nil {
+		return plan.Errorf("%s: %w", inputFilename, err)
 	}
-	defer os.Remove(tempSVG)
 
-	// Використовуємо rsvg-convert з білим фоном
-	cmd := exec.Command("rsvg-convert",
-		"-w", fmt.Sprintf("%d", width),
-		"-b", "white",
-		"--keep-aspect-ratio",
-		"-o", pngFilename,
-		tempSVG)
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("помилка конвертації через rsvg-convert: %v\nВивід: %s", err, string(output))
+	// 4. Витягуємо SVG і зберігаємо його
+	if err := extractAndSaveSVG(doc, targetSVGFilename); err != nil {
+		return plan.Errorf("%s: %w", inputFilename, err)
 	}
 
-	fmt.Printf("\n--> Успішно створено PNG файл через rsvg-convert: %s (розмір: %dx%d)\n", pngFilename, width, height)
-	return nil
+	// 5. Конвертуємо SVG в PNG
+	return convertSVGToPNG(targetSVGFilename, targetPNGFilename, 2450, 830)
 }
 
-// flipPNGFile читає PNG, дзеркально відображає його та зберігає назад
-func flipPNGFile(filename string) error {
-	// Читаємо PNG
-	file, err := os.Open(filename)
-	if err != nil {
-		return err
-	}
-	img, err := png.Decode(file)
-	file.Close()
-	if err != nil {
-		return err
-	}
-
-	// Конвертуємо в RGBA якщо потрібно
-	bounds := img.Bounds()
-	rgba := image.NewRGBA(bounds)
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			rgba.Set(x, y, img.At(x, y))
-		}
+// convertSVGToPNG конвертує SVG файл у PNG з заданими розмірами
+// Використовує rsvg-convert для кращої підтримки всіх SVG можливостей
+func convertSVGToPNG(svgFilename, pngFilename string, width, height int) error {
+	// Якщо rsvg-convert не встановлено, пробуємо використати oksvg
+	if !plan.RsvgAvailable() {
+		return convertSVGToPNGWithOksvg(svgFilename, pngFilename, width, height)
 	}
 
-	// Дзеркально відображаємо
-	flipped := flipHorizontal(rgba)
-
-	// Зберігаємо назад
-	outFile, err := os.Create(filename)
-	if err != nil {
+	// Дзеркальні трансформації рендерер видаляє сам, тож PNG буде без них
+	if err := renderFile(plan.RsvgRenderer{}, svgFilename, pngFilename, width, height); err != nil {
 		return err
 	}
-	defer outFile.Close()
 
-	return png.Encode(outFile, flipped)
+	slog.Info("PNG створено", "file", pngFilename, "renderer", "rsvg-convert", "width", width, "height", height)
+	return nil
 }
 
 // convertSVGToPNGWithOksvg - запасний метод конвертації через oksvg (обмежена підтримка)
 func convertSVGToPNGWithOksvg(svgFilename, pngFilename string, width, height int) error {
-	fmt.Println("УВАГА: rsvg-convert не встановлено. Використовується oksvg (обмежена підтримка тексту)")
-	fmt.Println("Для кращої якості встановіть: sudo apt-get install librsvg2-bin")
+	slog.Warn("rsvg-convert не встановлено, використовується oksvg (обмежена підтримка тексту)",
+		"hint", "sudo apt-get install librsvg2-bin")
 
-	// Читаємо SVG файл
-	svgData, err := os.ReadFile(svgFilename)
-	if err != nil {
-		return fmt.Errorf("помилка читання SVG файлу: %v", err)
+	if err := renderFile(plan.OksvgRenderer{}, svgFilename, pngFilename, width, height); err != nil {
+		return err
 	}
 
-	// Видаляємо трансформації, які oksvg не підтримує
-	svgString := string(svgData)
-	svgString = replaceTransform(svgString)
-
-	// Перевіряємо, чи потрібно дзеркально відобразити
-	needsFlip := bytes.Contains(svgData, []byte(`transform="scale(-1, 1)"`))
+	slog.Info("PNG створено (можливо без тексту)", "file", pngFilename, "renderer", "oksvg", "width", width, "height", height)
+	return nil
+}
 
-	// Парсимо SVG
-	icon, err := oksvg.ReadIconStream(bytes.NewReader([]byte(svgString)))
+// renderFile рендерить SVG файл у PNG файл заданим рендерером.
+func renderFile(r plan.Renderer, svgFilename, pngFilename string, width, height int) error {
+	in, err := os.Open(svgFilename)
 	if err != nil {
-		return fmt.Errorf("помилка парсингу SVG: %v", err)
-	}
-
-	// Встановлюємо розміри
-	icon.SetTarget(0, 0, float64(width), float64(height))
-
-	// Створюємо зображення з білим фоном
-	img := image.NewRGBA(image.Rect(0, 0, width, height))
-
-	// Заповнюємо білим фоном
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			img.Set(x, y, image.White)
-		}
-	}
-
-	// Рендеримо SVG
-	scanner := rasterx.NewScannerGV(width, height, img, img.Bounds())
-	raster := rasterx.NewDasher(width, height, scanner)
-	icon.Draw(raster, 1.0)
-
-	// Дзеркально відображаємо якщо потрібно
-	var finalImg image.Image = img
-	if needsFlip {
-		finalImg = flipHorizontal(img)
+		return plan.Errorf("помилка читання файлу %s: %w", svgFilename, err)
 	}
+	defer in.Close()
 
-	// Зберігаємо PNG
-	outFile, err := os.Create(pngFilename)
-	if err != nil {
-		return fmt.Errorf("помилка створення PNG файлу: %v", err)
+	var buf bytes.Buffer
+	opts := plan.RenderOptions{Format: plan.FormatPNG, Width: width, Height: height}
+	if err := r.Render(context.Background(), &buf, in, opts); err != nil {
+		return err
 	}
-	defer outFile.Close()
-
-	if err := png.Encode(outFile, finalImg); err != nil {
-		return fmt.Errorf("помилка кодування PNG: %v", err)
+	if err := os.WriteFile(pngFilename, buf.Bytes(), 0644); err != nil {
+		return plan.Errorf("%w файлу %s: %w", plan.ErrWrite, pngFilename, err)
 	}
-
-	fmt.Printf("\n--> Створено PNG файл через oksvg: %s (можливо без тексту)\n", pngFilename)
 	return nil
 }
-
-// flipHorizontal дзеркально відображає зображення по горизонталі
-func flipHorizontal(src *image.RGBA) *image.RGBA {
-	bounds := src.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	flipped := image.NewRGBA(bounds)
-
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			flipped.Set(width-1-x, y, src.At(x, y))
-		}
-	}
-
-	return flipped
-}
-
-// replaceTransform видаляє атрибут transform зі SVG тега та CSS трансформації з текстових стилів
-func replaceTransform(svgString string) string {
-	result := svgString
-
-	// 1. Видаляємо transform="scale(-1, 1)" з головного SVG тегу
-	result = string(bytes.ReplaceAll([]byte(result), []byte(`transform="scale(-1, 1)"`), []byte("")))
-
-	// 2. Видаляємо style="transform-origin: center;" з головного SVG тегу
-	result = string(bytes.ReplaceAll([]byte(result), []byte(`style="transform-origin: center;"`), []byte("")))
-
-	// 3. Видаляємо CSS трансформації з текстових стилів (незалежно від відступів)
-	// Видаляємо цілі рядки з цими властивостями
-	lines := bytes.Split([]byte(result), []byte("\n"))
-	var cleanedLines [][]byte
-
-	for _, line := range lines {
-		lineStr := string(bytes.TrimSpace(line))
-		// Пропускаємо рядки з CSS трансформаціями
-		if lineStr == "transform: scale(-1, 1);" ||
-			lineStr == "transform-box: fill-box;" ||
-			lineStr == "transform-origin: center;" {
-			continue
-		}
-		cleanedLines = append(cleanedLines, line)
-	}
-
-	return string(bytes.Join(cleanedLines, []byte("\n")))
-}
@@ -0,0 +1,131 @@
+package plan
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultMetersPerUnit - масштаб за замовчуванням: одна одиниця viewBox = 1 см.
+const DefaultMetersPerUnit = 0.01
+
+// RoomMeasure - площа і периметр однієї кімнати.
+type RoomMeasure struct {
+	Label      string   `json:"label"`
+	Labels     []string `json:"labels"`
+	Path       string   `json:"path"`
+	Area       float64  `json:"area_units"`
+	Perimeter  float64  `json:"perimeter_units"`
+	AreaM2     float64  `json:"area_m2"`
+	PerimeterM float64  `json:"perimeter_m"`
+}
+
+// MeasureRooms обчислює площу і периметр кожної кімнати з підписом .room-name так само, як GeoJSON:
+// кімната - найменший polygon/rect з одним із класів classes, що містить підпис, а без такого -
+// замкнена область, обмежена стінами, контурами і дверима (площа й периметр - з отворами).
+// Підписи однієї кімнати об'єднуються; контури classes без підписів теж виводяться.
+// scale - кількість метрів в одній одиниці viewBox.
+func MeasureRooms(d *Document, classes []string, scale float64) []RoomMeasure {
+	svg := d.SVG
+	var nodes, labels []*html.Node
+	var shapes [][]point
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		if n.Data == "defs" || n.Data == "symbol" || getAttr(n, "id") == "legend" || getAttr(n, "id") == "title-block" {
+			return true
+		}
+		switch {
+		case n.Data == "polygon" && hasAnyClass(n, classes):
+			nodes, shapes = append(nodes, n), append(shapes, polygonPoints(n))
+		case n.Data == "rect" && hasAnyClass(n, classes):
+			nodes, shapes = append(nodes, n), append(shapes, rectPoints(n))
+		case n.Data == "text" && hasClass(n, "room-name"):
+			labels = append(labels, n)
+		}
+		return false
+	})
+
+	measure := func(label string, labels []string, path string, rings [][]point, area float64) RoomMeasure {
+		var perimeter float64
+		for _, ring := range rings {
+			perimeter += polygonPerimeter(ring)
+		}
+		return RoomMeasure{
+			Label:      label,
+			Labels:     labels,
+			Path:       path,
+			Area:       area,
+			Perimeter:  perimeter,
+			AreaM2:     area * scale * scale,
+			PerimeterM: perimeter * scale,
+		}
+	}
+
+	var rooms []RoomMeasure
+	labelled := make([]bool, len(shapes))
+	for _, f := range roomFeatures(labels, shapes, parseClassStyles(svg), newRoomIndex(svg)) {
+		if len(f.rings) == 0 {
+			continue
+		}
+		names := make([]string, len(f.labels))
+		for i, n := range f.labels {
+			names[i] = textContent(n)
+		}
+		path := elementPath(f.labels[0])
+		if f.shape >= 0 {
+			labelled[f.shape] = true
+			path = elementPath(nodes[f.shape])
+		}
+		rooms = append(rooms, measure(f.name, names, path, f.rings, f.area))
+	}
+	for i, pts := range shapes {
+		if labelled[i] || len(pts) < 3 {
+			continue
+		}
+		label := getAttr(nodes[i], "id")
+		if label == "" {
+			label = fmt.Sprintf("контур %d", i+1)
+		}
+		rooms = append(rooms, measure(label, nil, elementPath(nodes[i]), [][]point{pts}, polygonArea(pts)))
+	}
+	sort.SliceStable(rooms, func(i, j int) bool { return rooms[i].Label < rooms[j].Label })
+	return rooms
+}
+
+// hasAnyClass перевіряє, чи має елемент хоча б один із класів.
+func hasAnyClass(n *html.Node, classes []string) bool {
+	for _, c := range classes {
+		if hasClass(n, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteRoomsCSV виводить таблицю площ у CSV.
+func WriteRoomsCSV(w io.Writer, rooms []RoomMeasure) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"label", "area_m2", "perimeter_m", "area_units", "perimeter_units", "path"}); err != nil {
+		return err
+	}
+	for _, r := range rooms {
+		record := []string{
+			r.Label,
+			fmt.Sprintf("%.2f", r.AreaM2),
+			fmt.Sprintf("%.2f", r.PerimeterM),
+			formatNumber(r.Area),
+			formatNumber(r.Perimeter),
+			r.Path,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,89 @@
+package plan
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMeasureRoomsMatchesGeoJSON(t *testing.T) {
+	d := loadPlan(t, "full.html")
+	rooms := MeasureRooms(d, []string{"room"}, DefaultMetersPerUnit)
+
+	want := make(map[string]float64)
+	for _, f := range GeoJSON(d, GeoReference{}).Features {
+		if area, ok := f.Properties["area_m2"].(float64); ok {
+			want[f.Properties["name"].(string)] = area
+		}
+	}
+	if len(rooms) != len(want) {
+		t.Fatalf("виміряно %d кімнат, у GeoJSON %d", len(rooms), len(want))
+	}
+	for _, r := range rooms {
+		area, ok := want[r.Label]
+		if !ok {
+			t.Errorf("кімнати %q немає в GeoJSON", r.Label)
+			continue
+		}
+		if math.Abs(r.AreaM2-area) > 0.01 {
+			t.Errorf("%s: площа %.2f, у GeoJSON %.2f", r.Label, r.AreaM2, area)
+		}
+	}
+}
+
+func TestMeasureRoomsShapes(t *testing.T) {
+	d := parseSVG(t, `<svg viewBox="0 0 400 200">
+		<style>.room-name { font-size: 10px; }</style>
+		<rect class="room" x="0" y="0" width="100" height="50"/>
+		<polygon class="room" id="склад" points="200,0 300,0 300,100 200,100"/>
+		<text class="room-name" x="40" y="25">кухня</text>
+		<text class="room-name" x="40" y="40">їдальня</text>
+	</svg>`)
+	rooms := MeasureRooms(d, []string{"room"}, 0.1)
+	if len(rooms) != 2 {
+		t.Fatalf("очікувалось 2 контури, отримано %d: %+v", len(rooms), rooms)
+	}
+	kitchen, store := rooms[0], rooms[1]
+	if kitchen.Label != "кухня; їдальня" || len(kitchen.Labels) != 2 || kitchen.Area != 5000 || kitchen.Perimeter != 300 {
+		t.Errorf("кухня: %+v", kitchen)
+	}
+	if math.Abs(kitchen.AreaM2-50) > 1e-9 || math.Abs(kitchen.PerimeterM-30) > 1e-9 {
+		t.Errorf("кухня в метрах: %v м², %v м", kitchen.AreaM2, kitchen.PerimeterM)
+	}
+	if store.Label != "склад" || store.Labels != nil || store.Area != 10000 {
+		t.Errorf("контур без підпису: %+v", store)
+	}
+}
+
+func TestMeasureRoomsWallBounded(t *testing.T) {
+	// Кімнати без контурів .room: прямокутна зала і трикутна комора з похилою стіною.
+	// Площі рахуються за формулою шнурування по внутрішніх гранях стін товщиною 4, а не по комірках сітки.
+	d := parseSVG(t, `<svg viewBox="-10 -10 520 220">
+		<style>
+			.wall { stroke: #000; stroke-width: 4; }
+			.room-name { font-size: 10px; }
+		</style>
+		<polyline class="wall" points="0,0 200,0 200,100 0,100 0,0"/>
+		<polyline class="wall" points="300,0 500,0 300,200 300,0"/>
+		<text class="room-name" x="80" y="50">зала</text>
+		<text class="room-name" x="320" y="40">комора</text>
+	</svg>`)
+	rooms := MeasureRooms(d, []string{"room"}, 1)
+	if len(rooms) != 2 {
+		t.Fatalf("очікувалось 2 кімнати, отримано %d: %+v", len(rooms), rooms)
+	}
+
+	// Зала: 196 x 96, периметр 2*(196+96)
+	hall := rooms[0]
+	if hall.Label != "зала" || math.Abs(hall.Area-196*96) > 1e-6 || math.Abs(hall.Perimeter-584) > 1e-6 {
+		t.Errorf("зала: площа %v, периметр %v; очікувалось %v і 584", hall.Area, hall.Perimeter, 196*96)
+	}
+
+	// Комора: прямокутний рівнобедрений трикутник з катетами 200, грані зсунуті всередину на 2.
+	// Внутрішній трикутник подібний до зовнішнього з коефіцієнтом (r-2)/r, де r - радіус вписаного кола.
+	r := (200 + 200 - 200*math.Sqrt2) / 2
+	want := 200 * 200 / 2 * math.Pow((r-2)/r, 2)
+	store := rooms[1]
+	if store.Label != "комора" || math.Abs(store.Area-want) > 1e-6 {
+		t.Errorf("комора: площа %v, очікувалось %v", store.Area, want)
+	}
+}
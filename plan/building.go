@@ -0,0 +1,508 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Параметри зв'язування сходів і побудови маршрутів.
+const (
+	// stairLinkTol - максимальна відстань між центрами сходових кліток сусідніх поверхів
+	// без позначення, щоб вважати їх однією кліткою
+	stairLinkTol = 100
+	// routeCell, routeClearance - крок сітки маршрутів і відступ від стін
+	routeCell      = 5
+	routeClearance = 6
+	// overviewGap - місце для заголовка поверху на зведеному аркуші
+	overviewGap = 60
+)
+
+// defaultStairStyles - стилі позначень сходів і маршрутів, які додаються, якщо документ їх не визначає.
+var defaultStairStyles = map[string]string{
+	"stair-label":       ".stair-label { font-family: Arial; font-size: 14px; font-weight: bold; fill: #006400; text-anchor: middle; }",
+	"stair-route":       ".stair-route { fill: none; stroke: #00A000; stroke-width: 4; stroke-dasharray: 12 6; }",
+	"stair-route-arrow": ".stair-route-arrow { fill: #00A000; stroke: none; }",
+	"stair-link":        ".stair-link { fill: none; stroke: #00A000; stroke-width: 2; stroke-dasharray: 6 6; }",
+	"floor-title":       ".floor-title { font-family: Arial; font-size: 28px; font-weight: bold; fill: #000; }",
+}
+
+// Building - проєкт будівлі: список поверхів, кожен зі своїм файлом плану.
+type Building struct {
+	Name   string  `json:"name"`
+	Floors []Floor `json:"floors"`
+}
+
+// Floor - поверх будівлі.
+type Floor struct {
+	Level int    `json:"level"`
+	Name  string `json:"name,omitempty"`
+	// Source - HTML/SVG файл плану (відносно файлу проєкту)
+	Source string `json:"source"`
+	// Selector - селектор потрібного <svg>, якщо у файлі їх кілька
+	Selector string `json:"selector,omitempty"`
+}
+
+// Title повертає назву поверху для підписів.
+func (f Floor) Title() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return strconv.Itoa(f.Level) + " поверх"
+}
+
+// Ordinal повертає номер рівня IMDF: 1 поверх - 0, цокольний (0) - -1, підвал (-1) - -2.
+func (f Floor) Ordinal() int {
+	return f.Level - 1
+}
+
+// FloorPlan - завантажений план поверху зі знайденими сходовими клітками.
+type FloorPlan struct {
+	Floor  Floor
+	Doc    *Document
+	Stairs []Stairwell
+	// Routes - маршрути евакуації: на нижньому поверсі від сходів до виходів,
+	// на верхніх - від кімнат до сходів
+	Routes []StairRoute
+}
+
+// StairRoute - евакуаційний маршрут: від сходової клітки до виходу з будівлі (нижній поверх)
+// або від кімнати From до сходової клітки Stair (верхні поверхи).
+type StairRoute struct {
+	Stair  string  `json:"stair"`
+	From   string  `json:"from,omitempty"`
+	Length float64 `json:"length"`
+	points []point
+}
+
+// LoadBuilding читає проєкт будівлі у форматі JSON.
+func LoadBuilding(r io.Reader) (*Building, error) {
+	var b Building
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, Errorf("%w проєкту будівлі: %w", ErrParse, err)
+	}
+	if len(b.Floors) == 0 {
+		return nil, Errorf("проєкт будівлі не містить поверхів")
+	}
+	levels := make(map[int]bool)
+	for _, f := range b.Floors {
+		if f.Source == "" {
+			return nil, Errorf("поверх %d: не вказано файл плану (source)", f.Level)
+		}
+		if levels[f.Level] {
+			return nil, Errorf("поверх %d вказано двічі", f.Level)
+		}
+		levels[f.Level] = true
+	}
+	return &b, nil
+}
+
+// LoadFloors завантажує плани всіх поверхів (шляхи відносно dir), знаходить сходові клітки
+// і зв'язує їх між поверхами. Результат відсортовано від нижнього поверху до верхнього.
+func LoadFloors(ctx context.Context, b *Building, dir string) ([]*FloorPlan, error) {
+	var plans []*FloorPlan
+	for _, f := range b.Floors {
+		path := f.Source
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, Errorf("поверх %d: %w", f.Level, err)
+		}
+		d, err := Extractor{Selector: f.Selector, InjectSymbols: true}.Extract(ctx, file)
+		file.Close()
+		if err != nil {
+			return nil, Errorf("поверх %d (%s): %w", f.Level, f.Source, err)
+		}
+		plans = append(plans, &FloorPlan{Floor: f, Doc: d, Stairs: FindStairwells(d)})
+	}
+	sort.SliceStable(plans, func(i, j int) bool { return plans[i].Floor.Level < plans[j].Floor.Level })
+	linkStairs(plans)
+	return plans, nil
+}
+
+// linkStairs присвоює сходовим кліткам спільні для будівлі позначення: спочатку за позначенням
+// з плану (П2), потім за положенням відносно клітки на попередньому поверсі.
+func linkStairs(plans []*FloorPlan) {
+	type known struct {
+		id, label string
+		pos       point
+	}
+	var all []known
+	next := 1
+	for _, fp := range plans {
+		used := make(map[int]bool)
+		for i := range fp.Stairs {
+			st := &fp.Stairs[i]
+			match := -1
+			for k, s := range all {
+				if !used[k] && st.Label != "" && s.label == st.Label {
+					match = k
+					break
+				}
+			}
+			if match < 0 {
+				best := float64(stairLinkTol)
+				for k, s := range all {
+					if d := dist(s.pos, st.center()); !used[k] && (st.Label == "" || s.label == "") && d <= best {
+						best, match = d, k
+					}
+				}
+			}
+			if match < 0 {
+				id := st.Label
+				if id == "" {
+					id = "С" + strconv.Itoa(next)
+					next++
+				}
+				all = append(all, known{id: id, label: st.Label})
+				match = len(all) - 1
+			}
+			used[match] = true
+			all[match].pos = st.center()
+			if all[match].label == "" {
+				all[match].label = st.Label
+			}
+			st.ID = all[match].id
+		}
+	}
+}
+
+// AnnotateFloors підписує сходові клітки на всіх поверхах і будує маршрути евакуації:
+// на нижньому поверсі - від кожної клітки до найближчого виходу з будівлі, на верхніх -
+// від кожної кімнати (підпису .room-name) до найближчої клітки, що доходить до нижнього поверху.
+// Повертає попередження: клітки верхніх поверхів, що не доходять до нижнього, клітки без маршруту
+// до виходу і кімнати без маршруту до сходів.
+func AnnotateFloors(plans []*FloorPlan) []string {
+	if len(plans) == 0 {
+		return nil
+	}
+	ground := plans[0]
+	var warnings []string
+
+	onGround := make(map[string]bool)
+	for _, st := range ground.Stairs {
+		onGround[st.ID] = true
+	}
+	for _, fp := range plans[1:] {
+		for _, st := range fp.Stairs {
+			if !onGround[st.ID] {
+				warnings = append(warnings, fmt.Sprintf("%s: сходи %s не мають продовження на %s",
+					fp.Floor.Title(), st.ID, ground.Floor.Title()))
+			}
+		}
+	}
+
+	exits := exitDoors(ground.Doc.SVG)
+	var targets []point
+	for _, e := range exits {
+		targets = append(targets, point{(e.A.X + e.B.X) / 2, (e.A.Y + e.B.Y) / 2})
+	}
+	g := planGrid(ground.Doc.SVG, routeCell, routeClearance)
+	ground.Routes = nil
+	for _, st := range ground.Stairs {
+		pts, _ := g.shortestPath(st.Exit, targets)
+		if pts == nil {
+			warnings = append(warnings, fmt.Sprintf("%s: не знайдено шляху від сходів %s до виходу", ground.Floor.Title(), st.ID))
+			continue
+		}
+		pts[0] = st.Exit
+		ground.Routes = append(ground.Routes, StairRoute{Stair: st.ID, Length: polylineLength(pts), points: pts})
+	}
+
+	for _, fp := range plans[1:] {
+		warnings = append(warnings, routeToStairs(fp, onGround)...)
+	}
+
+	for _, fp := range plans {
+		annotateFloor(fp, ground)
+	}
+	return warnings
+}
+
+// routeToStairs будує на верхньому поверсі маршрути від кожної кімнати до найближчої
+// сходової клітки з продовженням на нижньому поверсі (onGround) і повертає попередження
+// про кімнати, від яких до таких сходів не дійти. Підписи виходів ("Вихід ...") пропускаються.
+func routeToStairs(fp *FloorPlan, onGround map[string]bool) []string {
+	fp.Routes = nil
+	var targets []point
+	var ids []string
+	for _, st := range fp.Stairs {
+		if onGround[st.ID] {
+			targets = append(targets, st.center())
+			ids = append(ids, st.ID)
+		}
+	}
+
+	svg := fp.Doc.SVG
+	styles := parseClassStyles(svg)
+	var rooms []*html.Node
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		if n.Data == "defs" || n.Data == "symbol" {
+			return true
+		}
+		if n.Data == "text" && hasClass(n, "room-name") && !strings.HasPrefix(textContent(n), "Вихід") {
+			rooms = append(rooms, n)
+		}
+		return false
+	})
+	if len(rooms) == 0 {
+		return nil
+	}
+	if len(targets) == 0 {
+		return []string{fmt.Sprintf("%s: немає сходів, що ведуть на нижній поверх", fp.Floor.Title())}
+	}
+
+	var warnings []string
+	g := planGrid(svg, routeCell, routeClearance)
+	for _, n := range rooms {
+		name := textContent(n)
+		from := textBox(n, styles).center()
+		pts, t := g.shortestPath(from, targets)
+		if pts == nil {
+			warnings = append(warnings, fmt.Sprintf("%s: не знайдено шляху від кімнати %q до сходів", fp.Floor.Title(), name))
+			continue
+		}
+		pts[0] = from
+		fp.Routes = append(fp.Routes, StairRoute{Stair: ids[t], From: name, Length: polylineLength(pts), points: pts})
+	}
+	return warnings
+}
+
+// polylineLength повертає довжину ламаної.
+func polylineLength(pts []point) float64 {
+	var length float64
+	for i := 1; i < len(pts); i++ {
+		length += dist(pts[i-1], pts[i])
+	}
+	return length
+}
+
+// annotateFloor додає групу <g id="stairs"> з підписами сходових кліток і маршрутами.
+func annotateFloor(fp *FloorPlan, ground *FloorPlan) {
+	svg := fp.Doc.SVG
+	if old := findElementByID(svg, "stairs"); old != nil && old.Parent != nil {
+		old.Parent.RemoveChild(old)
+	}
+	group := newElement("g", "id", "stairs")
+	classes := []string{"stair-label"}
+	if len(fp.Routes) > 0 {
+		classes = append(classes, "stair-route", "stair-route-arrow")
+	}
+
+	for _, r := range fp.Routes {
+		coords := make([]string, len(r.points))
+		for i, p := range r.points {
+			coords[i] = formatNumber(p.X) + "," + formatNumber(p.Y)
+		}
+		group.AppendChild(newElement("polyline", "class", "stair-route", "points", strings.Join(coords, " ")))
+		if n := len(r.points); n >= 2 {
+			group.AppendChild(arrowHead(r.points[n-2], r.points[n-1]))
+		}
+	}
+
+	for _, st := range fp.Stairs {
+		label := st.ID + " ↓ " + ground.Floor.Title()
+		if fp == ground {
+			label = st.ID + " → вихід"
+		}
+		group.AppendChild(newTextElement("text", label,
+			"x", formatNumber(st.X+st.W/2), "y", formatNumber(st.Y-8), "class", "stair-label"))
+	}
+
+	if style := missingStyles(svg, classes, defaultStairStyles); style != nil {
+		group.InsertBefore(style, group.FirstChild)
+	}
+	svg.AppendChild(group)
+}
+
+// arrowHead будує трикутник-вістря на кінці маршруту.
+func arrowHead(from, to point) *html.Node {
+	l := dist(from, to)
+	if l == 0 {
+		l = 1
+	}
+	ux, uy := (to.X-from.X)/l, (to.Y-from.Y)/l
+	const size = 14
+	base := point{to.X - ux*size, to.Y - uy*size}
+	a := point{base.X - uy*size/2, base.Y + ux*size/2}
+	b := point{base.X + uy*size/2, base.Y - ux*size/2}
+	return newElement("polygon", "class", "stair-route-arrow", "points",
+		fmt.Sprintf("%s,%s %s,%s %s,%s", formatNumber(to.X), formatNumber(to.Y),
+			formatNumber(a.X), formatNumber(a.Y), formatNumber(b.X), formatNumber(b.Y)))
+}
+
+// Overview будує зведений аркуш: усі поверхи один під одним (верхній угорі)
+// з назвами і пунктирними лініями, що з'єднують ту саму сходову клітку на сусідніх поверхах.
+func Overview(title string, plans []*FloorPlan) *Document {
+	var width float64
+	for _, fp := range plans {
+		_, _, vw, _, _ := parseViewBox(fp.Doc.SVG)
+		width = max(width, vw)
+	}
+
+	root := newElement("svg", "xmlns", "http://www.w3.org/2000/svg")
+	defs := newElement("defs")
+	root.AppendChild(defs)
+	seenDefs := make(map[string]bool)
+
+	y := 0.0
+	if title != "" {
+		root.AppendChild(newTextElement("text", title, "x", "20", "y", "40", "class", "floor-title"))
+		y = overviewGap
+	}
+
+	// Центри сходових кліток у координатах аркуша: ID → точка на попередньому поверсі
+	prevCenters := map[string]point{}
+	var links []*html.Node
+	for i := len(plans) - 1; i >= 0; i-- {
+		fp := plans[i]
+		vx, vy, _, vh, _ := parseViewBox(fp.Doc.SVG)
+		root.AppendChild(newTextElement("text", fp.Floor.Title(), "x", "20", "y", formatNumber(y+40), "class", "floor-title"))
+		y += overviewGap
+
+		group := newElement("g", "id", "floor-"+strconv.Itoa(fp.Floor.Level),
+			"transform", fmt.Sprintf("translate(%s, %s)", formatNumber(-vx), formatNumber(y-vy)))
+		// Поверхи мають однакові id (символи бібліотеки, групи stairs, градієнти), тож перед
+		// злиттям їх id отримують префікс поверху, а посилання переписуються на нові id
+		floor := cloneNode(fp.Doc.SVG)
+		prefixIDs(floor, "f"+strconv.Itoa(fp.Floor.Level)+"-")
+		for c := floor.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "defs" {
+				mergeDefs(defs, c, seenDefs)
+				continue
+			}
+			group.AppendChild(cloneNode(c))
+		}
+		root.AppendChild(group)
+
+		centers := map[string]point{}
+		for _, st := range fp.Stairs {
+			c := st.center()
+			c = point{c.X - vx, c.Y - vy + y}
+			centers[st.ID] = c
+			if p, ok := prevCenters[st.ID]; ok {
+				links = append(links, newElement("line", "class", "stair-link",
+					"x1", formatNumber(p.X), "y1", formatNumber(p.Y), "x2", formatNumber(c.X), "y2", formatNumber(c.Y)))
+			}
+		}
+		prevCenters = centers
+		y += vh
+	}
+	for _, l := range links {
+		root.AppendChild(l)
+	}
+
+	setAttr(root, "viewBox", "0 0 "+formatNumber(width)+" "+formatNumber(y))
+	setAttr(root, "width", formatNumber(width))
+	setAttr(root, "height", formatNumber(y))
+	d := &Document{SVG: root}
+	if style := missingStyles(root, []string{"floor-title", "stair-link"}, defaultStairStyles); style != nil {
+		defs.AppendChild(style)
+	}
+	hoistStyles(root, defs)
+	return d
+}
+
+// hoistStyles переносить усі блоки <style> аркуша в один на початку <defs>:
+// oksvg враховує лише класи з останнього прочитаного <style>, тож поверхи,
+// між якими трапляються інші стилі, інакше малюються без оформлення.
+func hoistStyles(root, defs *html.Node) {
+	var styles []*html.Node
+	traverse(root, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "style" {
+			styles = append(styles, n)
+			return true
+		}
+		return false
+	})
+	seen := make(map[string]bool)
+	var css []string
+	for _, n := range styles {
+		if text := strings.TrimSpace(textContent(n)); text != "" && !seen[text] {
+			seen[text] = true
+			css = append(css, text)
+		}
+		n.Parent.RemoveChild(n)
+	}
+	if len(css) > 0 {
+		defs.InsertBefore(newTextElement("style", strings.Join(css, "\n")), defs.FirstChild)
+	}
+}
+
+// prefixIDs додає prefix до всіх id у піддереві root і переписує посилання на них:
+// href і xlink:href виду #id та url(#id) в атрибутах і блоках <style>.
+func prefixIDs(root *html.Node, prefix string) {
+	ids := make(map[string]bool)
+	traverse(root, func(n *html.Node) bool {
+		if n.Type == html.ElementNode {
+			if id := getAttr(n, "id"); id != "" {
+				ids[id] = true
+			}
+		}
+		return false
+	})
+	if len(ids) == 0 {
+		return
+	}
+	rewriteURLs := func(s string) string {
+		return urlRe.ReplaceAllStringFunc(s, func(m string) string {
+			id := urlRe.FindStringSubmatch(m)[1]
+			if !ids[id] {
+				return m
+			}
+			return strings.TrimSuffix(m, id) + prefix + id
+		})
+	}
+	traverse(root, func(n *html.Node) bool {
+		switch n.Type {
+		case html.ElementNode:
+			for i := range n.Attr {
+				a := &n.Attr[i]
+				switch {
+				case a.Key == "id":
+					a.Val = prefix + a.Val
+				case (a.Key == "href" || a.Key == "xlink:href") && strings.HasPrefix(a.Val, "#") && ids[a.Val[1:]]:
+					a.Val = "#" + prefix + a.Val[1:]
+				default:
+					a.Val = rewriteURLs(a.Val)
+				}
+			}
+		case html.TextNode:
+			if n.Parent != nil && n.Parent.Data == "style" {
+				n.Data = rewriteURLs(n.Data)
+			}
+		}
+		return false
+	})
+}
+
+// mergeDefs копіює вміст <defs> поверху у спільні <defs>, пропускаючи вже додані id та стилі.
+func mergeDefs(dst, src *html.Node, seen map[string]bool) {
+	for c := src.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		key := getAttr(c, "id")
+		if c.Data == "style" {
+			key = "style:" + textContent(c)
+		}
+		if key != "" && seen[key] {
+			continue
+		}
+		seen[key] = true
+		dst.AppendChild(cloneNode(c))
+	}
+}
@@ -0,0 +1,150 @@
+package plan
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestAnnotateFloorsRoutesEveryFloor(t *testing.T) {
+	// Два поверхи з однаковим планом: сходи зв'язуються за положенням
+	b := &Building{Floors: []Floor{{Level: 2, Source: "full.html"}, {Level: 1, Source: "full.html"}}}
+	plans, err := LoadFloors(context.Background(), b, "..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := AnnotateFloors(plans)
+	for _, w := range warnings {
+		t.Logf("попередження: %s", w)
+	}
+
+	ground, upper := plans[0], plans[1]
+	if len(ground.Stairs) == 0 {
+		t.Fatal("на плані не знайдено сходів")
+	}
+	onGround := make(map[string]bool)
+	for _, st := range ground.Stairs {
+		onGround[st.ID] = true
+	}
+	if len(ground.Routes) == 0 {
+		t.Error("на нижньому поверсі немає маршрутів до виходу")
+	}
+	for _, r := range ground.Routes {
+		if r.From != "" {
+			t.Errorf("маршрут нижнього поверху веде від кімнати %q, а не від сходів", r.From)
+		}
+	}
+
+	if len(upper.Routes) == 0 {
+		t.Fatal("на верхньому поверсі немає маршрутів до сходів")
+	}
+	for _, r := range upper.Routes {
+		if r.From == "" || strings.HasPrefix(r.From, "Вихід") {
+			t.Errorf("маршрут верхнього поверху без кімнати чи від виходу: %+v", r)
+		}
+		if !onGround[r.Stair] {
+			t.Errorf("маршрут від %q веде до сходів %s, що не доходять до нижнього поверху", r.From, r.Stair)
+		}
+		if r.Length <= 0 || len(r.points) < 2 {
+			t.Errorf("маршрут від %q порожній: довжина %v, точок %d", r.From, r.Length, len(r.points))
+		}
+	}
+
+	// Маршрути намальовані на кожному поверсі
+	for _, fp := range plans {
+		var routes int
+		traverse(fp.Doc.SVG, func(n *html.Node) bool {
+			if n.Type == html.ElementNode && n.Data == "polyline" && hasClass(n, "stair-route") {
+				routes++
+			}
+			return false
+		})
+		if routes != len(fp.Routes) {
+			t.Errorf("%s: намальовано %d маршрутів, очікувалось %d", fp.Floor.Title(), routes, len(fp.Routes))
+		}
+	}
+}
+
+func TestAnnotateFloorsNoLinkedStairs(t *testing.T) {
+	ground := &FloorPlan{Floor: Floor{Level: 1}, Doc: parseSVG(t, `<svg viewBox="0 0 100 100"></svg>`)}
+	upper := &FloorPlan{Floor: Floor{Level: 2}, Doc: parseSVG(t, `<svg viewBox="0 0 100 100">
+		<text class="room-name" x="50" y="50">Кухня</text>
+	</svg>`)}
+	warnings := AnnotateFloors([]*FloorPlan{ground, upper})
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "немає сходів") {
+		t.Errorf("попередження %q, очікувалось про відсутні сходи", warnings)
+	}
+}
+
+// overviewFloor - поверх, id якого збігаються з id інших поверхів.
+func overviewFloor(t *testing.T, level int, color string) *FloorPlan {
+	d := parseSVG(t, `<svg viewBox="0 0 100 100">
+		<defs>
+			<linearGradient id="fill"><stop offset="0" stop-color="`+color+`"/></linearGradient>
+			<symbol id="mark" viewBox="0 0 10 10"><rect width="10" height="10" fill="url(#fill)"/></symbol>
+			<style>.room { fill: url('#fill'); }</style>
+		</defs>
+		<g id="rooms"><rect class="room" width="50" height="50"/></g>
+		<use href="#mark" x="60" y="60" width="10" height="10"/>
+		<use xlink:href="#mark" x="80" y="60" width="10" height="10"/>
+		<use href="#missing" x="80" y="80" width="10" height="10"/>
+	</svg>`)
+	return &FloorPlan{Floor: Floor{Level: level}, Doc: d}
+}
+
+func TestOverviewPrefixesIDs(t *testing.T) {
+	d := Overview("", []*FloorPlan{overviewFloor(t, 1, "#f00"), overviewFloor(t, 2, "#00f")})
+
+	ids := make(map[string]int)
+	traverse(d.SVG, func(n *html.Node) bool {
+		if n.Type == html.ElementNode {
+			if id := getAttr(n, "id"); id != "" {
+				ids[id]++
+			}
+		}
+		return false
+	})
+	for _, id := range []string{"f1-fill", "f2-fill", "f1-mark", "f2-mark", "f1-rooms", "f2-rooms", "floor-1", "floor-2"} {
+		if ids[id] != 1 {
+			t.Errorf("id %s зустрічається %d разів, очікувався 1", id, ids[id])
+		}
+	}
+	for _, id := range []string{"fill", "mark", "rooms"} {
+		if ids[id] != 0 {
+			t.Errorf("лишився id %s без префікса поверху", id)
+		}
+	}
+
+	// Кожен поверх посилається на власні символи і градієнти
+	for _, level := range []string{"1", "2"} {
+		floor := findElementByID(d.SVG, "floor-"+level)
+		var hrefs []string
+		traverse(floor, func(n *html.Node) bool {
+			if n.Type == html.ElementNode && n.Data == "use" {
+				hrefs = append(hrefs, useHref(n))
+			}
+			return false
+		})
+		want := "f" + level + "-mark|f" + level + "-mark|missing"
+		if got := strings.Join(hrefs, "|"); got != want {
+			t.Errorf("поверх %s: посилання %s, очікувалось %s", level, got, want)
+		}
+		rect := findElementByID(d.SVG, "f"+level+"-mark").FirstChild
+		for rect != nil && rect.Type != html.ElementNode {
+			rect = rect.NextSibling
+		}
+		if got := getAttr(rect, "fill"); got != "url(#f"+level+"-fill)" {
+			t.Errorf("поверх %s: fill символу %s", level, got)
+		}
+	}
+
+	var css string
+	for _, n := range elementsByTag(d, "style") {
+		css += textContent(n)
+	}
+	if !strings.Contains(css, "url('#f1-fill')") || !strings.Contains(css, "url('#f2-fill')") {
+		t.Errorf("посилання у стилях не переписано: %s", css)
+	}
+}
@@ -0,0 +1,251 @@
+package plan
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Параметри розведення підписів за замовчуванням.
+const (
+	defaultCollisionStep     = 5.0
+	defaultCollisionMaxShift = 60.0
+	defaultCollisionMargin   = 2.0
+)
+
+// DefaultCollisionClasses - класи підписів, що перевіряються за замовчуванням, у порядку пріоритету.
+var DefaultCollisionClasses = []string{"room-name", "door-number"}
+
+// CollisionOptions - параметри пошуку й розведення перетинів підписів.
+type CollisionOptions struct {
+	// Classes - класи текстів у порядку пріоритету: підпис наступного класу зсувається,
+	// щоб не перекривати попередні; порожній - DefaultCollisionClasses
+	Classes []string `json:"classes,omitempty"`
+	// Step - крок пошуку вільного місця; 0 - 5
+	Step float64 `json:"step,omitempty"`
+	// MaxShift - найбільше зміщення підпису; 0 - 60
+	MaxShift float64 `json:"max_shift,omitempty"`
+	// Margin - найменший проміжок між підписом і іншими об'єктами; 0 - 2
+	Margin float64 `json:"margin,omitempty"`
+}
+
+// LabelCollision - підпис, що перекриває інші підписи, стіни чи двері.
+type LabelCollision struct {
+	Label string `json:"label"`
+	Path  string `json:"path"`
+	// With - що перекриває підпис: "стіну", "двері" або "підпис ..."
+	With []string `json:"with"`
+	// DX, DY - зміщення підпису в координатах viewBox (0, якщо не зсувався)
+	DX float64 `json:"dx"`
+	DY float64 `json:"dy"`
+	// Resolved - підпис зсунуто на вільне місце
+	Resolved bool `json:"resolved"`
+
+	node *html.Node
+}
+
+// FindLabelCollisions шукає перетини підписів між собою і зі стінами та дверима, нічого не змінюючи.
+// Розміри текстів оцінюються за метриками шрифту (див. textBox). Перетин двох підписів
+// повідомляється один раз - для підпису з нижчим пріоритетом.
+func FindLabelCollisions(d *Document, opts CollisionOptions) []LabelCollision {
+	return checkCollisions(d, opts, false)
+}
+
+// ResolveLabelCollisions зсуває підписи, що перетинаються з іншими підписами, стінами чи дверима,
+// у найближче вільне місце в межах opts.MaxShift, не переносячи їх крізь стіни.
+// Повертає всі знайдені перетини; ті, що не вдалося розвести, мають Resolved = false.
+func ResolveLabelCollisions(d *Document, opts CollisionOptions) []LabelCollision {
+	return checkCollisions(d, opts, true)
+}
+
+// WriteCollisions виводить перетини у форматі path: підпис "..." перекриває ... (і зміщення, якщо розведено).
+func WriteCollisions(w io.Writer, collisions []LabelCollision) {
+	for _, c := range collisions {
+		fmt.Fprintf(w, "%s: підпис %q перекриває %s", c.Path, c.Label, strings.Join(c.With, ", "))
+		if c.Resolved {
+			fmt.Fprintf(w, " - зміщено на (%s, %s)", formatNumber(c.DX), formatNumber(c.DY))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// obstacle - лінія плану, яку не повинні перекривати підписи.
+type obstacle struct {
+	seg    segment
+	radius float64 // половина товщини лінії
+	kind   string
+	wall   bool // крізь стіни підписи не переносяться
+}
+
+// placedLabel - підпис і його поточна рамка.
+type placedLabel struct {
+	node *html.Node
+	box  box
+}
+
+// checkCollisions знаходить перетини і, якщо resolve, розводить їх.
+func checkCollisions(d *Document, opts CollisionOptions, resolve bool) []LabelCollision {
+	if len(opts.Classes) == 0 {
+		opts.Classes = DefaultCollisionClasses
+	}
+	if opts.Step <= 0 {
+		opts.Step = defaultCollisionStep
+	}
+	if opts.MaxShift <= 0 {
+		opts.MaxShift = defaultCollisionMaxShift
+	}
+	if opts.Margin <= 0 {
+		opts.Margin = defaultCollisionMargin
+	}
+	svg := d.SVG
+	styles := parseClassStyles(svg)
+	obstacles := planObstacles(svg, styles)
+	offsets := shiftOffsets(opts.Step, opts.MaxShift)
+
+	var collisions []LabelCollision
+	var placed []placedLabel
+	for _, n := range labelsByPriority(svg, opts.Classes) {
+		b := textBox(n, styles)
+		with := labelHits(b.inflate(opts.Margin), obstacles, placed)
+		if len(with) == 0 {
+			placed = append(placed, placedLabel{n, b})
+			continue
+		}
+
+		c := LabelCollision{Label: textContent(n), Path: elementPath(n), With: with, node: n}
+		if resolve {
+			if off, ok := freeOffset(b, opts.Margin, obstacles, placed, offsets); ok {
+				b = box{b.X + off.X, b.Y + off.Y, b.W, b.H}
+				moveText(n, b.center(), styles)
+				c.DX, c.DY, c.Resolved = off.X, off.Y, true
+			}
+		}
+		collisions = append(collisions, c)
+		placed = append(placed, placedLabel{n, b})
+	}
+	return collisions
+}
+
+// freeOffset шукає серед offsets перше зміщення рамки b, за якого вона не перекриває перешкод
+// і розміщених підписів, а шлях від початкового положення не перетинає стін.
+func freeOffset(b box, margin float64, obstacles []obstacle, placed []placedLabel, offsets []point) (point, bool) {
+	from := b.center()
+	for _, off := range offsets {
+		moved := box{b.X + off.X, b.Y + off.Y, b.W, b.H}
+		if len(labelHits(moved.inflate(margin), obstacles, placed)) > 0 ||
+			crossesWall(segment{from, moved.center()}, obstacles) {
+			continue
+		}
+		return off, true
+	}
+	return point{}, false
+}
+
+// labelsByPriority повертає тексти з класами classes: спершу всі підписи першого класу, потім другого...
+func labelsByPriority(svg *html.Node, classes []string) []*html.Node {
+	seen := make(map[*html.Node]bool)
+	var out []*html.Node
+	for _, class := range classes {
+		traverse(svg, func(n *html.Node) bool {
+			if n.Type != html.ElementNode {
+				return false
+			}
+			if n.Data == "defs" || n.Data == "symbol" {
+				return true
+			}
+			if n.Data == "text" && hasClass(n, class) && !seen[n] {
+				seen[n] = true
+				out = append(out, n)
+			}
+			return false
+		})
+	}
+	return out
+}
+
+// planObstacles збирає стіни, ребра контурів .outline і двері з товщиною їхніх ліній.
+func planObstacles(svg *html.Node, styles classStyles) []obstacle {
+	var out []obstacle
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		r := styles.strokeWidth(n) / 2
+		switch {
+		case n.Data == "defs" || n.Data == "symbol":
+			return true
+		case isWall(n):
+			for _, s := range wallEdges(n) {
+				out = append(out, obstacle{s, r, "стіну", true})
+			}
+		case n.Data == "line" && isDoor(n):
+			out = append(out, obstacle{lineSegment(n), r, "двері", false})
+		case n.Data == "polygon" && hasClass(n, "outline"):
+			for _, e := range edges(polygonPoints(n)) {
+				out = append(out, obstacle{e, r, "стіну", true})
+			}
+		}
+		return false
+	})
+	return out
+}
+
+// labelHits повертає перелік того, що перекриває рамка b (без повторів).
+func labelHits(b box, obstacles []obstacle, placed []placedLabel) []string {
+	var hits []string
+	seen := make(map[string]bool)
+	add := func(s string) {
+		if !seen[s] {
+			seen[s] = true
+			hits = append(hits, s)
+		}
+	}
+	for _, o := range obstacles {
+		if b.inflate(o.radius).crossedBy(o.seg) {
+			add(o.kind)
+		}
+	}
+	for _, p := range placed {
+		if b.overlaps(p.box) {
+			add(fmt.Sprintf("підпис %q", textContent(p.node)))
+		}
+	}
+	return hits
+}
+
+// crossesWall перевіряє, чи перетинає шлях підпису якусь стіну.
+func crossesWall(path segment, obstacles []obstacle) bool {
+	for _, o := range obstacles {
+		if o.wall && o.seg.intersects(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// shiftOffsets повертає зміщення на сітці з кроком step у межах кола maxShift,
+// від найменшого; за однакової довжини горизонтальні зсуви йдуть першими.
+func shiftOffsets(step, maxShift float64) []point {
+	var out []point
+	n := int(maxShift / step)
+	for j := -n; j <= n; j++ {
+		for i := -n; i <= n; i++ {
+			p := point{float64(i) * step, float64(j) * step}
+			if (i != 0 || j != 0) && math.Hypot(p.X, p.Y) <= maxShift {
+				out = append(out, p)
+			}
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool {
+		la, lb := math.Hypot(out[a].X, out[a].Y), math.Hypot(out[b].X, out[b].Y)
+		if la != lb {
+			return la < lb
+		}
+		return math.Abs(out[a].Y) < math.Abs(out[b].Y)
+	})
+	return out
+}
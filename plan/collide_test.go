@@ -0,0 +1,97 @@
+package plan
+
+import (
+	"slices"
+	"testing"
+)
+
+// collideStyle - стилі стін, дверей і підписів для тестів перетинів.
+const collideStyle = `<style>
+	.wall { stroke: #000; stroke-width: 4; }
+	.doors { stroke: #FFF; stroke-width: 8; }
+	.room-name { font-size: 10px; }
+	.door-number { font-size: 10px; }
+</style>`
+
+func TestFindLabelCollisions(t *testing.T) {
+	cases := []struct {
+		name, body string
+		label      string
+		with       []string
+	}{
+		{
+			"підпис на підписі",
+			`<text class="room-name" x="100" y="100">кухня</text><text class="room-name" x="104" y="103">склад</text>`,
+			"склад", []string{`підпис "кухня"`},
+		},
+		{
+			// Номер дверей має нижчий пріоритет, тож повідомляється він, хоч і йде в документі першим
+			"номер дверей на підписі кімнати",
+			`<text class="door-number" x="104" y="103">7</text><text class="room-name" x="100" y="100">кухня</text>`,
+			"7", []string{`підпис "кухня"`},
+		},
+		{
+			"підпис на стіні",
+			`<line class="wall" x1="110" y1="50" x2="110" y2="150"/><text class="room-name" x="100" y="100">кухня</text>`,
+			"кухня", []string{"стіну"},
+		},
+		{
+			"підпис на дверях",
+			`<line class="doors" x1="50" y1="97" x2="150" y2="97"/><text class="room-name" x="100" y="100">кухня</text>`,
+			"кухня", []string{"двері"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := parseSVG(t, `<svg viewBox="0 0 300 300">`+collideStyle+c.body+`</svg>`)
+			got := FindLabelCollisions(d, CollisionOptions{})
+			if len(got) != 1 || got[0].Label != c.label || !slices.Equal(got[0].With, c.with) || got[0].Resolved {
+				t.Fatalf("очікувався перетин %q з %v, отримано %+v", c.label, c.with, got)
+			}
+		})
+	}
+
+	clean := parseSVG(t, `<svg viewBox="0 0 300 300">`+collideStyle+`
+		<line class="wall" x1="0" y1="150" x2="300" y2="150"/>
+		<text class="room-name" x="20" y="100">кухня</text>
+		<text class="room-name" x="200" y="100">склад</text>
+	</svg>`)
+	if got := FindLabelCollisions(clean, CollisionOptions{}); len(got) != 0 {
+		t.Errorf("зайві перетини: %+v", got)
+	}
+}
+
+func TestResolveLabelCollisions(t *testing.T) {
+	d := parseSVG(t, `<svg viewBox="0 0 300 300">`+collideStyle+`
+		<text class="room-name" x="100" y="100">кухня</text>
+		<text class="room-name" x="104" y="103">склад</text>
+	</svg>`)
+	got := ResolveLabelCollisions(d, CollisionOptions{})
+	if len(got) != 1 || !got[0].Resolved || (got[0].DX == 0 && got[0].DY == 0) {
+		t.Fatalf("перетин мав бути розведений: %+v", got)
+	}
+	if again := FindLabelCollisions(d, CollisionOptions{}); len(again) != 0 {
+		t.Errorf("після розведення лишилися перетини: %+v", again)
+	}
+}
+
+func TestResolveLabelCollisionsNoFreeSpot(t *testing.T) {
+	// Підпис у комірці, меншій за нього: будь-яке зміщення перекриває стіну або переносить його крізь стіну
+	d := parseSVG(t, `<svg viewBox="0 0 300 300">`+collideStyle+`
+		<line class="wall" x1="80" y1="90" x2="140" y2="90"/>
+		<line class="wall" x1="80" y1="105" x2="140" y2="105"/>
+		<line class="wall" x1="80" y1="90" x2="80" y2="105"/>
+		<line class="wall" x1="140" y1="90" x2="140" y2="105"/>
+		<text class="room-name" x="85" y="101">електрощитова</text>
+	</svg>`)
+	text := elementsByTag(d, "text")[0]
+	x, y := getAttr(text, "x"), getAttr(text, "y")
+
+	got := ResolveLabelCollisions(d, CollisionOptions{})
+	if len(got) != 1 || got[0].Resolved || got[0].DX != 0 || got[0].DY != 0 {
+		t.Fatalf("вільного місця немає, очікувався нерозведений перетин: %+v", got)
+	}
+	if getAttr(text, "x") != x || getAttr(text, "y") != y {
+		t.Errorf("нерозведений підпис зсунуто: (%s, %s) → (%s, %s)", x, y, getAttr(text, "x"), getAttr(text, "y"))
+	}
+}
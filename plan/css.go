@@ -0,0 +1,187 @@
+package plan
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// classStyles - CSS-властивості, задані для класів у блоках <style> документа (клас → властивість → значення).
+type classStyles map[string]map[string]string
+
+// parseClassStyles збирає правила простих селекторів класів (.name, .a, .b) з усіх <style>.
+// Складні селектори ігноруються, бо в наших планах вони не використовуються.
+func parseClassStyles(svg *html.Node) classStyles {
+	styles := make(classStyles)
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "style" {
+			parseCSSInto(styles, textContent(n))
+		}
+		return false
+	})
+	return styles
+}
+
+// parseCSSInto розбирає CSS-текст і додає правила класів у styles.
+func parseCSSInto(styles classStyles, css string) {
+	// Видаляємо коментарі /* ... */
+	for {
+		start := strings.Index(css, "/*")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(css[start+2:], "*/")
+		if end < 0 {
+			css = css[:start]
+			break
+		}
+		css = css[:start] + css[start+2+end+2:]
+	}
+
+	for {
+		open := strings.Index(css, "{")
+		if open < 0 {
+			return
+		}
+		closeIdx := strings.Index(css[open:], "}")
+		if closeIdx < 0 {
+			return
+		}
+		selectors := css[:open]
+		body := css[open+1 : open+closeIdx]
+		css = css[open+closeIdx+1:]
+
+		props := make(map[string]string)
+		for _, decl := range strings.Split(body, ";") {
+			name, value, ok := strings.Cut(decl, ":")
+			if !ok {
+				continue
+			}
+			props[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+
+		for _, sel := range strings.Split(selectors, ",") {
+			sel = strings.TrimSpace(sel)
+			if !strings.HasPrefix(sel, ".") || strings.ContainsAny(sel[1:], " .>:#[") {
+				continue
+			}
+			class := sel[1:]
+			if styles[class] == nil {
+				styles[class] = make(map[string]string)
+			}
+			for k, v := range props {
+				styles[class][k] = v
+			}
+		}
+	}
+}
+
+// property повертає значення CSS-властивості елемента: з атрибута, з inline style або з класів.
+func (s classStyles) property(n *html.Node, name string) string {
+	if v := getAttr(n, name); v != "" {
+		return v
+	}
+	for _, decl := range strings.Split(getAttr(n, "style"), ";") {
+		if k, v, ok := strings.Cut(decl, ":"); ok && strings.TrimSpace(k) == name {
+			return strings.TrimSpace(v)
+		}
+	}
+	classes := strings.Fields(getAttr(n, "class"))
+	for i := len(classes) - 1; i >= 0; i-- {
+		if v, ok := s[classes[i]][name]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// fontSize повертає розмір шрифту текстового елемента (16, якщо не задано, як у браузерах).
+func (s classStyles) fontSize(n *html.Node) float64 {
+	for p := n; p != nil && p.Type == html.ElementNode; p = p.Parent {
+		if v := s.property(p, "font-size"); v != "" {
+			if size, err := strconv.ParseFloat(strings.TrimSuffix(v, "px"), 64); err == nil {
+				return size
+			}
+		}
+	}
+	return 16
+}
+
+// strokeWidth повертає товщину лінії елемента (1, якщо не задано, як у SVG).
+func (s classStyles) strokeWidth(n *html.Node) float64 {
+	if v, err := strconv.ParseFloat(strings.TrimSuffix(s.property(n, "stroke-width"), "px"), 64); err == nil {
+		return v
+	}
+	return 1
+}
+
+// isBold перевіряє, чи текст напівжирний.
+func (s classStyles) isBold(n *html.Node) bool {
+	w := s.property(n, "font-weight")
+	return w == "bold" || w == "bolder" || w == "600" || w == "700" || w == "800" || w == "900"
+}
+
+// inlineClassStyles переносить правила класів у атрибут style кожного елемента й видаляє
+// <style>: oksvg бере лише останній фрагмент тексту <style> у <defs> (коментар його обриває)
+// і не знає кількох класів на елементі. Як і в CSS, inline style важить більше за клас,
+// а клас - більше за атрибут представлення.
+func inlineClassStyles(svg *html.Node) {
+	styles := parseClassStyles(svg)
+	var styleNodes []*html.Node
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		if n.Data == "style" {
+			styleNodes = append(styleNodes, n)
+			return true
+		}
+		classes := strings.Fields(getAttr(n, "class"))
+		if len(classes) == 0 {
+			return false
+		}
+		inline := getAttr(n, "style")
+		props := make(map[string]string)
+		for _, class := range classes {
+			for k, v := range styles[class] {
+				props[k] = v
+			}
+		}
+		names := make([]string, 0, len(props))
+		for k := range props {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		var decls []string
+		for _, k := range names {
+			if hasDeclaration(inline, k) {
+				continue
+			}
+			removeAttr(n, k)
+			decls = append(decls, k+":"+props[k])
+		}
+		if inline != "" {
+			decls = append(decls, inline)
+		}
+		removeAttr(n, "class")
+		if len(decls) > 0 {
+			setAttr(n, "style", strings.Join(decls, ";"))
+		}
+		return false
+	})
+	for _, n := range styleNodes {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// hasDeclaration перевіряє, чи inline style задає властивість name.
+func hasDeclaration(style, name string) bool {
+	for _, decl := range strings.Split(style, ";") {
+		if k, _, ok := strings.Cut(decl, ":"); ok && strings.TrimSpace(k) == name {
+			return true
+		}
+	}
+	return false
+}
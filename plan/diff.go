@@ -0,0 +1,305 @@
+package plan
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Допуски зіставлення елементів двох версій плану (в одиницях viewBox).
+const (
+	// diffSameTol - зсув, менший за який вважається тим самим положенням
+	diffSameTol = 0.5
+	// diffMoveTol - максимальна відстань, на яку може бути переміщено той самий елемент
+	diffMoveTol = 300
+	// diffRenameTol - максимальна відстань між старим і новим текстом, щоб вважати його зміненим
+	diffRenameTol = 40
+)
+
+// Типи змін структурного порівняння.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeMoved   = "moved"
+	ChangeEdited  = "changed"
+)
+
+// Change - одна відмінність між двома версіями плану.
+type Change struct {
+	Type    string `json:"type"`
+	Kind    string `json:"kind"` // door, room, door-number, text, symbol або tag.class для інших фігур
+	Path    string `json:"path"` // шлях у новій версії (у старій - для видалених)
+	Message string `json:"message"`
+}
+
+// diffItem - елемент плану, що порівнюється: тип, ключ (текст чи id символу),
+// опорна точка та геометричний підпис в абсолютних координатах.
+type diffItem struct {
+	kind string
+	key  string
+	pos  point
+	sig  string
+	desc string
+	path string
+}
+
+// Diff порівнює дві версії плану і повертає список змін: додані й видалені елементи,
+// переміщені двері, символи та підписи, перейменовані кімнати та змінені тексти.
+func Diff(before, after *Document) []Change {
+	oldItems := collectDiffItems(before.SVG)
+	newItems := collectDiffItems(after.SVG)
+	oldUsed := make([]bool, len(oldItems))
+	newUsed := make([]bool, len(newItems))
+	var changes []Change
+
+	// 1. Незмінені елементи: той самий тип, ключ і геометрія
+	bySig := map[string][]int{}
+	for i, it := range oldItems {
+		k := it.kind + "\x00" + it.key + "\x00" + it.sig
+		bySig[k] = append(bySig[k], i)
+	}
+	for j, it := range newItems {
+		k := it.kind + "\x00" + it.key + "\x00" + it.sig
+		if idx := bySig[k]; len(idx) > 0 {
+			oldUsed[idx[0]], newUsed[j] = true, true
+			bySig[k] = idx[1:]
+		}
+	}
+
+	// 2. Переміщені: той самий тип і ключ, найближчий за відстанню
+	matchNearest(oldItems, newItems, oldUsed, newUsed, diffMoveTol, true, func(o, n diffItem) {
+		changes = append(changes, Change{
+			Type: ChangeMoved, Kind: n.kind, Path: n.path,
+			Message: fmt.Sprintf("%s переміщено з %s до %s", n.desc, formatPoint(o.pos), formatPoint(n.pos)),
+		})
+	})
+
+	// 3. Змінені тексти: той самий тип на тому ж місці, але інший вміст
+	matchNearest(oldItems, newItems, oldUsed, newUsed, diffRenameTol, false, func(o, n diffItem) {
+		verb := "текст змінено"
+		switch n.kind {
+		case "room":
+			verb = "кімнату перейменовано"
+		case "door-number":
+			verb = "номер дверей змінено"
+		}
+		changes = append(changes, Change{
+			Type: ChangeEdited, Kind: n.kind, Path: n.path,
+			Message: fmt.Sprintf("%s: %q → %q", verb, o.key, n.key),
+		})
+	})
+
+	for i, it := range oldItems {
+		if !oldUsed[i] {
+			changes = append(changes, Change{Type: ChangeRemoved, Kind: it.kind, Path: it.path, Message: it.desc + " видалено"})
+		}
+	}
+	for j, it := range newItems {
+		if !newUsed[j] {
+			changes = append(changes, Change{Type: ChangeAdded, Kind: it.kind, Path: it.path, Message: it.desc + " додано"})
+		}
+	}
+	return changes
+}
+
+// matchNearest жадібно зіставляє ще не зіставлені елементи одного типу за найменшою відстанню.
+// sameKey вимагає збігу ключа (переміщення дверей, символів і текстів);
+// інакше ключі мають відрізнятися (зміна вмісту текстів). Стіни та інші фігури
+// не зіставляються - їх зміни показуються як видалення і додавання.
+func matchNearest(oldItems, newItems []diffItem, oldUsed, newUsed []bool, tol float64, sameKey bool, found func(o, n diffItem)) {
+	type pair struct {
+		i, j int
+		d    float64
+	}
+	var pairs []pair
+	for i, o := range oldItems {
+		if oldUsed[i] {
+			continue
+		}
+		for j, n := range newItems {
+			if newUsed[j] || o.kind != n.kind || (o.key == n.key) != sameKey {
+				continue
+			}
+			if !textKinds[n.kind] && (!sameKey || n.kind != "door" && n.kind != "symbol") {
+				continue
+			}
+			if d := dist(o.pos, n.pos); d <= tol {
+				pairs = append(pairs, pair{i, j, d})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool { return pairs[a].d < pairs[b].d })
+	for _, p := range pairs {
+		if oldUsed[p.i] || newUsed[p.j] {
+			continue
+		}
+		o, n := oldItems[p.i], newItems[p.j]
+		if sameKey && p.d <= diffSameTol && o.sig != n.sig {
+			// Те саме положення, але інша форма (наприклад, змінена довжина дверей) -
+			// показуємо як видалення і додавання
+			continue
+		}
+		oldUsed[p.i], newUsed[p.j] = true, true
+		found(o, n)
+	}
+}
+
+// textKinds - типи текстових елементів, вміст яких може змінюватися.
+var textKinds = map[string]bool{"room": true, "door-number": true, "text": true}
+
+// collectDiffItems збирає видимі елементи плану (без <defs>, <symbol> і <style>).
+func collectDiffItems(svg *html.Node) []diffItem {
+	var items []diffItem
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		switch n.Data {
+		case "defs", "symbol", "style", "title":
+			return true
+		}
+		if it, ok := diffItemOf(n); ok {
+			items = append(items, it)
+		}
+		return n.Data == "text"
+	})
+	return items
+}
+
+// diffItemOf описує елемент для порівняння; false - елемент не порівнюється (групи тощо).
+func diffItemOf(n *html.Node) (diffItem, bool) {
+	it := diffItem{path: elementPath(n)}
+	m := nodeTransform(n)
+	switch n.Data {
+	case "text":
+		it.key = strings.TrimSpace(textContent(n))
+		it.pos = m.apply(point{attrFloat(n, "x"), attrFloat(n, "y")})
+		it.sig = formatPoint(it.pos)
+		switch {
+		case hasClass(n, "room-name"):
+			it.kind = "room"
+			it.desc = fmt.Sprintf("підпис кімнати %q", it.key)
+		case hasClass(n, "door-number"):
+			it.kind = "door-number"
+			it.desc = fmt.Sprintf("номер дверей %q", it.key)
+		default:
+			it.kind = "text"
+			it.desc = fmt.Sprintf("текст %q", it.key)
+		}
+	case "use":
+		it.kind = "symbol"
+		it.key = useHref(n)
+		it.pos = m.apply(point{attrFloat(n, "x"), attrFloat(n, "y")})
+		it.sig = formatPoint(it.pos) + " " + getAttr(n, "width") + "x" + getAttr(n, "height")
+		it.desc = fmt.Sprintf("символ #%s", it.key)
+	case "line":
+		s := lineSegment(n)
+		it.pos = point{(s.A.X + s.B.X) / 2, (s.A.Y + s.B.Y) / 2}
+		it.sig = formatPoint(s.A) + "-" + formatPoint(s.B)
+		if isDoor(n) {
+			it.kind = "door"
+			it.desc = "двері " + it.sig
+		} else {
+			it.kind = shapeKind(n)
+			it.desc = it.kind + " " + it.sig
+		}
+	case "polygon", "polyline", "rect":
+		var pts []point
+		if n.Data == "rect" {
+			pts = rectPoints(n)
+		} else {
+			pts = polygonPoints(n)
+		}
+		if len(pts) == 0 {
+			return it, false
+		}
+		coords := make([]string, len(pts))
+		for i, p := range pts {
+			coords[i] = formatPoint(p)
+			it.pos.X += p.X / float64(len(pts))
+			it.pos.Y += p.Y / float64(len(pts))
+		}
+		it.kind = shapeKind(n)
+		it.sig = strings.Join(coords, " ")
+		it.desc = fmt.Sprintf("%s з центром %s", it.kind, formatPoint(it.pos))
+	case "circle", "ellipse":
+		it.kind = shapeKind(n)
+		it.pos = m.apply(point{attrFloat(n, "cx"), attrFloat(n, "cy")})
+		it.sig = formatPoint(it.pos) + " " + getAttr(n, "r") + getAttr(n, "rx") + getAttr(n, "ry")
+		it.desc = fmt.Sprintf("%s з центром %s", it.kind, formatPoint(it.pos))
+	case "path":
+		it.kind = shapeKind(n)
+		it.pos = m.apply(point{})
+		it.sig = getAttr(n, "transform") + " " + strings.Join(strings.Fields(getAttr(n, "d")), " ")
+		it.desc = it.kind
+	default:
+		return it, false
+	}
+	return it, true
+}
+
+// isDoor перевіряє, чи є лінія дверима.
+func isDoor(n *html.Node) bool {
+	return hasClass(n, "doors") || hasClass(n, "door") || hasClass(n, "doors-blue")
+}
+
+// shapeKind повертає тип фігури у вигляді tag.class.
+func shapeKind(n *html.Node) string {
+	if class := strings.Fields(getAttr(n, "class")); len(class) > 0 {
+		return n.Data + "." + class[0]
+	}
+	return n.Data
+}
+
+// formatPoint форматує точку як (x,y).
+func formatPoint(p point) string {
+	return "(" + formatNumber(p.X) + "," + formatNumber(p.Y) + ")"
+}
+
+// WriteChanges виводить зміни у текстовому вигляді: "+" додано, "-" видалено, "~" змінено чи переміщено.
+func WriteChanges(w io.Writer, changes []Change) {
+	marks := map[string]string{ChangeAdded: "+", ChangeRemoved: "-", ChangeMoved: "~", ChangeEdited: "~"}
+	for _, c := range changes {
+		fmt.Fprintf(w, "%s %s (%s)\n", marks[c.Type], c.Message, c.Path)
+	}
+}
+
+// DiffOverlay накладає два растри однакового масштабу: пікселі, що з'явились у новій версії,
+// зелені, зниклі - червоні, незмінні - сірі на білому фоні.
+func DiffOverlay(before, after image.Image) *image.RGBA {
+	ob, nb := before.Bounds(), after.Bounds()
+	w, h := max(ob.Dx(), nb.Dx()), max(ob.Dy(), nb.Dy())
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	ink := func(img image.Image, b image.Rectangle, x, y int) float64 {
+		if x >= b.Dx() || y >= b.Dy() {
+			return 0
+		}
+		r, g, bl, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+		white := 0xFFFF - a
+		l := (0.299*float64(r+white) + 0.587*float64(g+white) + 0.114*float64(bl+white)) / 0xFFFF
+		return 1 - l
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			o, n := ink(before, ob, x, y), ink(after, nb, x, y)
+			switch {
+			case n-o > 0.25:
+				out.Set(x, y, color.RGBA{0x00, 0xA0, 0x00, 0xFF})
+			case o-n > 0.25:
+				out.Set(x, y, color.RGBA{0xE0, 0x00, 0x00, 0xFF})
+			default:
+				v := uint8(255 - math.Round(math.Max(o, n)*0.6*255))
+				out.Set(x, y, color.RGBA{v, v, v, 0xFF})
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package plan
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// diffBefore - стара версія плану для порівняння.
+const diffBefore = `<svg viewBox="0 0 400 300">
+	<defs><symbol id="sink" viewBox="0 0 10 10"><rect width="10" height="10"/></symbol></defs>
+	<line class="wall" x1="0" y1="0" x2="400" y2="0"/>
+	<line class="wall" x1="200" y1="0" x2="200" y2="300"/>
+	<line class="doors" x1="200" y1="50" x2="200" y2="90"/>
+	<text class="room-name" x="50" y="100">кухня</text>
+	<text class="room-name" x="250" y="100">склад</text>
+	<text class="door-number" x="210" y="70">1</text>
+	<use href="#sink" x="20" y="20" width="20" height="20"/>
+</svg>`
+
+// diffAfter - нова версія: двері й умивальник переміщено, склад перейменовано, номер дверей змінено,
+// одну стіну видалено, додано коло.
+const diffAfter = `<svg viewBox="0 0 400 300">
+	<defs><symbol id="sink" viewBox="0 0 10 10"><rect width="10" height="10"/></symbol></defs>
+	<line class="wall" x1="0" y1="0" x2="400" y2="0"/>
+	<g transform="translate(0, 100)"><line class="doors" x1="200" y1="50" x2="200" y2="90"/></g>
+	<text class="room-name" x="50" y="100">кухня</text>
+	<text class="room-name" x="252" y="100">комора</text>
+	<text class="door-number" x="210" y="70">2</text>
+	<use href="#sink" x="60" y="20" width="20" height="20"/>
+	<circle class="column" cx="300" cy="200" r="10"/>
+</svg>`
+
+func TestDiff(t *testing.T) {
+	changes := Diff(parseSVG(t, diffBefore), parseSVG(t, diffAfter))
+
+	want := []struct {
+		typ, kind, message string
+	}{
+		// Переміщення й зміни йдуть від найближчих пар до найдальших
+		{ChangeMoved, "symbol", "символ #sink переміщено з (20,20) до (60,20)"},
+		// Трансформація групи враховується: двері описуються в абсолютних координатах
+		{ChangeMoved, "door", "двері (200,150)-(200,190) переміщено з (200,70) до (200,170)"},
+		{ChangeEdited, "door-number", `номер дверей змінено: "1" → "2"`},
+		{ChangeEdited, "room", `кімнату перейменовано: "склад" → "комора"`},
+		{ChangeRemoved, "line.wall", "line.wall (200,0)-(200,300) видалено"},
+		{ChangeAdded, "circle.column", "circle.column з центром (300,200) додано"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("отримано %d змін, очікувалось %d: %+v", len(changes), len(want), changes)
+	}
+	for i, w := range want {
+		c := changes[i]
+		if c.Type != w.typ || c.Kind != w.kind || c.Message != w.message || c.Path == "" {
+			t.Errorf("зміна %d: %+v, очікувалось %s %s %q", i, c, w.typ, w.kind, w.message)
+		}
+	}
+
+	if changes := Diff(parseSVG(t, diffBefore), parseSVG(t, diffBefore)); len(changes) != 0 {
+		t.Errorf("однакові версії дали зміни: %+v", changes)
+	}
+
+	var buf bytes.Buffer
+	WriteChanges(&buf, changes)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(want) || !strings.HasPrefix(lines[0], "~ символ #sink") ||
+		!strings.HasPrefix(lines[4], "- line.wall") || !strings.HasPrefix(lines[5], "+ circle.column") {
+		t.Errorf("текстовий список змін:\n%s", buf.String())
+	}
+}
+
+func TestDiffOverlay(t *testing.T) {
+	before := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	after := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	black, white := color.RGBA{0, 0, 0, 0xFF}, color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	// Піксель 0 - незмінний фон, 1 - незмінна лінія, 2 - зникла, 3 - додана
+	for x, c := range [][2]color.RGBA{{white, white}, {black, black}, {black, white}, {white, black}} {
+		before.Set(x, 0, c[0])
+		after.Set(x, 0, c[1])
+	}
+	out := DiffOverlay(before, after)
+	want := []color.RGBA{
+		{0xFF, 0xFF, 0xFF, 0xFF},
+		{0x66, 0x66, 0x66, 0xFF},
+		{0xE0, 0x00, 0x00, 0xFF},
+		{0x00, 0xA0, 0x00, 0xFF},
+	}
+	for x, w := range want {
+		if got := out.RGBAAt(x, 0); got != w {
+			t.Errorf("піксель %d: %v, очікувалось %v", x, got, w)
+		}
+	}
+
+	// Растри різного розміру накладаються на полотно більшого з них
+	if b := DiffOverlay(image.NewRGBA(image.Rect(0, 0, 3, 2)), image.NewRGBA(image.Rect(0, 0, 2, 5))).Bounds(); b.Dx() != 3 || b.Dy() != 5 {
+		t.Errorf("розмір накладення %v, очікувалось 3x5", b.Size())
+	}
+}
@@ -0,0 +1,12 @@
+// Package plan витягує SVG-плани евакуації з HTML, перетворює їх
+// (дзеркальне відображення, легенда, рамка, символи) і рендерить у PNG чи PDF.
+//
+// Основні складові:
+//   - Extractor - HTML (io.Reader) → Document з кореневим <svg>;
+//   - Serializer - Document → XML-розмітка SVG (io.Writer), з Optimize - компактна для публікації;
+//   - Renderer - SVG (io.Reader) → PNG/PDF (io.Writer), реалізації RsvgRenderer та OksvgRenderer;
+//   - Mirror - дзеркальне відображення плану;
+//   - ImportDXF - креслення ASCII DXF → Document у позначеннях планів;
+//   - ExportDXF, ExportGeoJSON - Document → креслення DXF чи GeoJSON з географічною прив'язкою;
+//   - IMDF - плани поверхів → пакет Indoor Mapping Data Format.
+package plan
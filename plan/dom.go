@@ -0,0 +1,256 @@
+package plan
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// findSVG повертає перший SVG-елемент у дереві або nil, якщо його немає.
+func findSVG(doc *html.Node) *html.Node {
+	return findElement(doc, func(n *html.Node) bool { return n.Data == "svg" })
+}
+
+// findElement повертає перший елемент (обхід у глибину), для якого match повертає true.
+func findElement(n *html.Node, match func(*html.Node) bool) *html.Node {
+	var found *html.Node
+	traverse(n, func(c *html.Node) bool {
+		if found != nil {
+			return true
+		}
+		if c.Type == html.ElementNode && match(c) {
+			found = c
+			return true
+		}
+		return false
+	})
+	return found
+}
+
+// matchSelector перевіряє простий CSS-селектор: tag, #id, .class, tag#id, tag.class.a.b.
+func matchSelector(n *html.Node, sel string) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	sel = strings.TrimSpace(sel)
+	tag := sel
+	if i := strings.IndexAny(sel, "#."); i >= 0 {
+		tag, sel = sel[:i], sel[i:]
+	} else {
+		sel = ""
+	}
+	if tag != "" && tag != "*" && !strings.EqualFold(tag, n.Data) {
+		return false
+	}
+	for sel != "" {
+		kind := sel[0]
+		rest := sel[1:]
+		end := strings.IndexAny(rest, "#.")
+		if end < 0 {
+			end = len(rest)
+		}
+		name := rest[:end]
+		sel = rest[end:]
+		switch kind {
+		case '#':
+			if getAttr(n, "id") != name {
+				return false
+			}
+		case '.':
+			if !hasClass(n, name) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// findElementByID шукає елемент із заданим атрибутом id.
+func findElementByID(n *html.Node, id string) *html.Node {
+	return findElement(n, func(c *html.Node) bool { return getAttr(c, "id") == id })
+}
+
+// nextElementSibling повертає наступний сусідній елемент, пропускаючи текст і коментарі.
+func nextElementSibling(n *html.Node) *html.Node {
+	for s := n.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+// elementPath будує читабельний шлях до елемента, наприклад svg > g#room-numbers > text[3].
+// Індекс рахується серед сусідів з тим самим ім'ям тега, починаючи з 1.
+func elementPath(n *html.Node) string {
+	var parts []string
+	for p := n; p != nil && p.Type == html.ElementNode; p = p.Parent {
+		part := p.Data
+		if id := getAttr(p, "id"); id != "" {
+			part += "#" + id
+		} else if p.Parent != nil {
+			index, total := 0, 0
+			for s := p.Parent.FirstChild; s != nil; s = s.NextSibling {
+				if s.Type == html.ElementNode && s.Data == p.Data {
+					total++
+					if s == p {
+						index = total
+					}
+				}
+			}
+			if total > 1 {
+				part += "[" + strconv.Itoa(index) + "]"
+			}
+		}
+		parts = append(parts, part)
+		if p.Data == "svg" {
+			break
+		}
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, " > ")
+}
+
+// getAttr повертає значення атрибута або порожній рядок.
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// setAttr встановлює значення атрибута, додаючи його, якщо він відсутній.
+func setAttr(n *html.Node, key, val string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+// removeAttr видаляє атрибут елемента, якщо він є.
+func removeAttr(n *html.Node, key string) {
+	n.Attr = slices.DeleteFunc(n.Attr, func(a html.Attribute) bool { return a.Key == key })
+}
+
+// hasClass перевіряє, чи містить атрибут class вказаний клас.
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(getAttr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// newElement створює SVG-елемент з атрибутами, заданими парами ключ-значення.
+func newElement(tag string, kv ...string) *html.Node {
+	n := &html.Node{Type: html.ElementNode, Data: tag, Namespace: "svg"}
+	for i := 0; i+1 < len(kv); i += 2 {
+		n.Attr = append(n.Attr, html.Attribute{Key: kv[i], Val: kv[i+1]})
+	}
+	return n
+}
+
+// newTextElement створює елемент з єдиним текстовим вузлом усередині (наприклад, <text>).
+func newTextElement(tag, text string, kv ...string) *html.Node {
+	n := newElement(tag, kv...)
+	n.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+	return n
+}
+
+// setText замінює вміст елемента одним текстовим вузлом.
+func setText(n *html.Node, text string) {
+	for c := n.FirstChild; c != nil; c = n.FirstChild {
+		n.RemoveChild(c)
+	}
+	n.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+}
+
+// cloneNode повертає глибоку копію вузла без батька і сусідів.
+func cloneNode(n *html.Node) *html.Node {
+	c := &html.Node{Type: n.Type, DataAtom: n.DataAtom, Data: n.Data, Namespace: n.Namespace}
+	c.Attr = append([]html.Attribute(nil), n.Attr...)
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		c.AppendChild(cloneNode(ch))
+	}
+	return c
+}
+
+// textContent збирає весь текст усередині вузла.
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	traverse(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+		return false
+	})
+	return strings.TrimSpace(sb.String())
+}
+
+// useHref повертає id символу, на який посилається <use> (href або xlink:href).
+func useHref(n *html.Node) string {
+	href := getAttr(n, "href")
+	if href == "" {
+		href = getAttr(n, "xlink:href")
+	}
+	return strings.TrimPrefix(href, "#")
+}
+
+// parseViewBox розбирає атрибут viewBox SVG-елемента.
+// Якщо viewBox відсутній, використовує числові width/height.
+func parseViewBox(svg *html.Node) (x, y, w, h float64, ok bool) {
+	fields := strings.FieldsFunc(getAttr(svg, "viewBox"), func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	if len(fields) == 4 {
+		var vals [4]float64
+		for i, f := range fields {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return 0, 0, 0, 0, false
+			}
+			vals[i] = v
+		}
+		return vals[0], vals[1], vals[2], vals[3], true
+	}
+
+	w, errW := strconv.ParseFloat(getAttr(svg, "width"), 64)
+	h, errH := strconv.ParseFloat(getAttr(svg, "height"), 64)
+	if errW != nil || errH != nil {
+		return 0, 0, 0, 0, false
+	}
+	return 0, 0, w, h, true
+}
+
+// missingStyles повертає <style> з визначеннями тих класів із defaults,
+// яких немає в жодному <style> документа, або nil, якщо всі класи вже визначені.
+func missingStyles(svg *html.Node, classes []string, defaults map[string]string) *html.Node {
+	existing := parseClassStyles(svg)
+
+	var missing []string
+	for _, class := range classes {
+		if _, ok := existing[class]; !ok {
+			missing = append(missing, defaults[class])
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return newTextElement("style", strings.Join(missing, "\n"))
+}
+
+// formatNumber форматує координату з точністю до сотих без зайвих нулів.
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,477 @@
+package plan
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Правила нумерації дверей.
+const (
+	// DoorOrderLTR - секції зліва направо, у секції двері зліва направо (за однакового x - згори вниз)
+	DoorOrderLTR = "ltr"
+	// DoorOrderClockwise - у кожній секції за годинниковою стрілкою навколо її центру, починаючи з 12 години
+	DoorOrderClockwise = "clockwise"
+)
+
+// DoorOrders - усі правила нумерації.
+var DoorOrders = []string{DoorOrderLTR, DoorOrderClockwise}
+
+// Параметри нумерації за замовчуванням.
+const (
+	defaultDoorLabelRadius = 80.0
+	defaultMinDoorGap      = 30.0
+)
+
+// defaultDoorNumberStyles - стиль номерів дверей, якщо в документі його немає.
+var defaultDoorNumberStyles = map[string]string{
+	"door-number": ".door-number { font-family: Arial; font-size: 45px; font-weight: 900; fill: #00f; }",
+}
+
+// DoorNumbering - параметри нумерації дверей.
+type DoorNumbering struct {
+	// Order - правило впорядкування (DoorOrders); порожній - ltr
+	Order string `json:"order,omitempty"`
+	// Start - перший номер; 0 - 1
+	Start int `json:"start,omitempty"`
+	// Prefix - префікс поверху: з "2" номери мають вигляд 201, 202, ...
+	Prefix string `json:"prefix,omitempty"`
+	// Gaps - вважати дверима також прорізи: розриви між стінами на одній прямій без line.doors
+	Gaps bool `json:"gaps,omitempty"`
+	// MinDoorGap, DoorGap - найменша й найбільша ширина прорізу для Gaps; 0 - 30 і 100
+	MinDoorGap float64 `json:"min_door_gap,omitempty"`
+	DoorGap    float64 `json:"door_gap,omitempty"`
+	// Radius - найбільша відстань від дверей до наявного номера, щоб вважати його номером цих дверей; 0 - 80
+	Radius float64 `json:"radius,omitempty"`
+}
+
+// DoorNumber - номер дверей після нумерації.
+type DoorNumber struct {
+	Number string `json:"number"`
+	// Old - попередній номер ("" для нового підпису)
+	Old string `json:"old,omitempty"`
+	// X, Y - середина дверей (для Orphan - центр підпису)
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Section int     `json:"section"`
+	Path    string  `json:"path"`
+	// Created - підпис створено, бо біля дверей не було номера
+	Created bool `json:"created,omitempty"`
+	// Orphan - номер не стоїть біля жодних дверей; лишається без змін, якщо не збігся з новим
+	Orphan bool `json:"orphan,omitempty"`
+	// Invalid - підпис .door-number не є номером (додатним цілим числом) і лишився без змін
+	Invalid bool `json:"invalid,omitempty"`
+}
+
+// WriteDoorNumbers виводить відповідність старих і нових номерів: old → new (x, y) секція N.
+func WriteDoorNumbers(w io.Writer, numbers []DoorNumber) {
+	for _, n := range numbers {
+		switch {
+		case n.Invalid:
+			fmt.Fprintf(w, "%s: не номер дверей, без змін (%s)\n", n.Number, n.Path)
+		case n.Orphan && n.Old != n.Number:
+			fmt.Fprintf(w, "%s → %s: не біля жодних дверей, номер зайнятий (%s)\n", n.Old, n.Number, n.Path)
+		case n.Orphan:
+			fmt.Fprintf(w, "%s: не біля жодних дверей, без змін (%s)\n", n.Number, n.Path)
+		case n.Created:
+			fmt.Fprintf(w, "новий → %s (%s, %s) секція %d\n", n.Number, formatNumber(n.X), formatNumber(n.Y), n.Section+1)
+		default:
+			fmt.Fprintf(w, "%s → %s (%s, %s) секція %d\n", n.Old, n.Number, formatNumber(n.X), formatNumber(n.Y), n.Section+1)
+		}
+	}
+}
+
+// door - двері чи проріз плану.
+type door struct {
+	seg     segment
+	mid     point
+	section int
+}
+
+// NumberDoors нумерує двері (line.doors, а з opts.Gaps - і прорізи в стінах) за правилом opts.Order:
+// переписує наявні номери .door-number, що стоять біля дверей, і створює відсутні поруч з дверима
+// на вільному місці. Секції - контури .outline зліва направо. Підписи .door-number, що не є номерами
+// ("osb"), не прив'язуються до дверей і повертаються з Invalid. Номери, що не стоять біля жодних
+// дверей, повертаються з Orphan і не змінюються, а ті з них, що збігаються з новими, отримують
+// наступні вільні номери після останніх дверей, щоб на плані не було двох однакових номерів.
+func NumberDoors(d *Document, opts DoorNumbering) ([]DoorNumber, error) {
+	if opts.Order == "" {
+		opts.Order = DoorOrderLTR
+	}
+	if opts.Start <= 0 {
+		opts.Start = 1
+	}
+	if opts.DoorGap <= 0 {
+		opts.DoorGap = defaultDoorGap
+	}
+	if opts.MinDoorGap <= 0 {
+		opts.MinDoorGap = defaultMinDoorGap
+	}
+	if opts.MinDoorGap > opts.DoorGap {
+		return nil, Errorf("найменша ширина прорізу %s більша за найбільшу %s", formatNumber(opts.MinDoorGap), formatNumber(opts.DoorGap))
+	}
+	if opts.Radius <= 0 {
+		opts.Radius = defaultDoorLabelRadius
+	}
+	svg := d.SVG
+	doors := planDoors(svg, opts)
+	if err := orderDoors(doors, opts.Order); err != nil {
+		return nil, err
+	}
+
+	all := labelsByPriority(svg, []string{"door-number"})
+	labels, invalid := splitDoorNumbers(all)
+	styles := parseClassStyles(svg)
+	owner := matchDoorLabels(doors, labels, styles, opts.Radius)
+
+	// Нові підписи додаються до наявних номерів або до групи підписів кімнат
+	var group *html.Node
+	if len(all) > 0 {
+		group = all[0].Parent
+	} else if group = findElementByID(svg, "room-numbers"); group == nil {
+		group = newElement("g", "id", "door-numbers")
+		svg.AppendChild(group)
+	}
+	if style := missingStyles(svg, []string{"door-number"}, defaultDoorNumberStyles); style != nil {
+		group.InsertBefore(style, group.FirstChild)
+		styles = parseClassStyles(svg)
+	}
+
+	obstacles := planObstacles(svg, styles)
+	var placed []placedLabel
+	for _, n := range labelsByPriority(svg, DefaultCollisionClasses) {
+		placed = append(placed, placedLabel{n, textBox(n, styles)})
+	}
+	offsets := shiftOffsets(defaultCollisionStep, defaultCollisionMaxShift)
+
+	// З префіксом номери доповнюються нулями до довжини найбільшого (не менше двох цифр): 101, 1001
+	width := max(2, len(strconv.Itoa(opts.Start+len(doors)-1)))
+	format := func(i int) string {
+		if opts.Prefix != "" {
+			return fmt.Sprintf("%s%0*d", opts.Prefix, width, opts.Start+i)
+		}
+		return strconv.Itoa(opts.Start + i)
+	}
+	var out []DoorNumber
+	bound := make(map[*html.Node]bool)
+	used := make(map[string]bool)
+	for i, dr := range doors {
+		num := format(i)
+		used[num] = true
+		res := DoorNumber{Number: num, X: dr.mid.X, Y: dr.mid.Y, Section: dr.section}
+		n := owner[i]
+		if n != nil {
+			res.Old = textContent(n)
+			setText(n, num)
+			bound[n] = true
+			// Номер міг стати довшим: якщо тепер він щось перекриває, зсуваємо його
+			others := slices.DeleteFunc(slices.Clone(placed), func(p placedLabel) bool { return p.node == n })
+			b := textBox(n, styles)
+			if len(labelHits(b.inflate(defaultCollisionMargin), obstacles, others)) > 0 {
+				if off, ok := freeOffset(b, defaultCollisionMargin, obstacles, others, offsets); ok {
+					moveText(n, point{b.center().X + off.X, b.center().Y + off.Y}, styles)
+				}
+			}
+			placed = append(others, placedLabel{n, textBox(n, styles)})
+		} else {
+			n = newTextElement("text", num, "x", "0", "y", "0", "class", "door-number")
+			group.AppendChild(n)
+			b := textBox(n, styles)
+			moveText(n, doorLabelSpot(dr, b, obstacles, placed, offsets), styles)
+			placed = append(placed, placedLabel{n, textBox(n, styles)})
+			res.Created = true
+		}
+		res.Path = elementPath(n)
+		out = append(out, res)
+	}
+
+	// Номер без дверей, що збігся з новим, дав би на плані двоє однакових дверей,
+	// тож такі номери продовжують нумерацію після останніх дверей
+	next := len(doors)
+	for _, n := range labels {
+		if bound[n] {
+			continue
+		}
+		old := textContent(n)
+		num := old
+		for used[num] {
+			num = format(next)
+			next++
+		}
+		used[num] = true
+		if num != old {
+			setText(n, num)
+		}
+		c := textBox(n, styles).center()
+		out = append(out, DoorNumber{Number: num, Old: old, X: c.X, Y: c.Y, Section: -1, Path: elementPath(n), Orphan: true})
+	}
+	for _, n := range invalid {
+		c := textBox(n, styles).center()
+		out = append(out, DoorNumber{Number: textContent(n), Old: textContent(n), X: c.X, Y: c.Y, Section: -1, Path: elementPath(n), Invalid: true})
+	}
+	return out, nil
+}
+
+// splitDoorNumbers ділить підписи .door-number на номери (додатні цілі числа, як вимагає lint)
+// та інші написи, які не можна ні зіставляти з дверима, ні переписувати.
+func splitDoorNumbers(labels []*html.Node) (numbers, invalid []*html.Node) {
+	for _, n := range labels {
+		if v, err := strconv.Atoi(strings.TrimSpace(textContent(n))); err == nil && v > 0 {
+			numbers = append(numbers, n)
+		} else {
+			invalid = append(invalid, n)
+		}
+	}
+	return numbers, invalid
+}
+
+// numberedDoors повертає двері, біля яких стоять номери .door-number, і самі номери в порядку DoorOrderLTR.
+// Двері - як у NumberDoors з Gaps (line.doors і прорізи в стінах), щоб номер біля прорізу не прив'язався
+// до сусідніх дверей; написи, що не є номерами, не враховуються.
+func numberedDoors(svg *html.Node) ([]door, []*html.Node) {
+	doors := planDoors(svg, DoorNumbering{Gaps: true, MinDoorGap: defaultMinDoorGap, DoorGap: defaultDoorGap})
+	_ = orderDoors(doors, DoorOrderLTR)
+	labels, _ := splitDoorNumbers(labelsByPriority(svg, []string{"door-number"}))
+	owner := matchDoorLabels(doors, labels, parseClassStyles(svg), defaultDoorLabelRadius)
+	var outDoors []door
+	var outLabels []*html.Node
+	for i, n := range owner {
+		if n != nil {
+			outDoors = append(outDoors, doors[i])
+			outLabels = append(outLabels, n)
+		}
+	}
+	return outDoors, outLabels
+}
+
+// planDoors збирає двері плану і визначає їхні секції (контури .outline зліва направо):
+// двері належать секції, в групі якої лежать, а прорізи - контуру, всередині якого їхня середина.
+func planDoors(svg *html.Node, opts DoorNumbering) []door {
+	var outlines, lines []*html.Node
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		switch {
+		case n.Data == "defs" || n.Data == "symbol":
+			return true
+		case n.Data == "line" && isDoor(n):
+			lines = append(lines, n)
+		case n.Data == "polygon" && hasClass(n, "outline"):
+			outlines = append(outlines, n)
+		}
+		return false
+	})
+
+	polys := make([][]point, len(outlines))
+	for i, n := range outlines {
+		polys[i] = polygonPoints(n)
+	}
+	sort.Sort(byLeft{outlines, polys})
+	index := make(map[*html.Node]int)
+	for i, n := range outlines {
+		index[n] = i
+	}
+	sectionOf := func(mid point) int {
+		for k, poly := range polys {
+			if pointInPolygon(mid, poly) {
+				return k
+			}
+		}
+		for k, poly := range polys {
+			if polygonBox(poly).inflate(lintWallTolerance).contains(mid) {
+				return k
+			}
+		}
+		return len(polys)
+	}
+
+	var doors []door
+	for _, n := range lines {
+		s := lineSegment(n)
+		d := door{seg: s, mid: point{(s.A.X + s.B.X) / 2, (s.A.Y + s.B.Y) / 2}}
+		if o := groupOutline(n); o != nil {
+			d.section = index[o]
+		} else {
+			d.section = sectionOf(d.mid)
+		}
+		doors = append(doors, d)
+	}
+	if opts.Gaps {
+		lineDoors := len(doors)
+	gaps:
+		for _, g := range wallGaps(svg, opts.DoorGap) {
+			if l := g.length(); l < opts.MinDoorGap || l > opts.DoorGap {
+				continue
+			}
+			mid := point{(g.A.X + g.B.X) / 2, (g.A.Y + g.B.Y) / 2}
+			// Проріз, у якому вже стоять двері (хоч і вужчі за нього), не рахується вдруге
+			for _, d := range doors[:lineDoors] {
+				if d.seg.distToSegment(mid) <= lintWallTolerance || g.distToSegment(d.mid) <= lintWallTolerance {
+					continue gaps
+				}
+			}
+			doors = append(doors, door{seg: g, mid: mid, section: sectionOf(mid)})
+		}
+	}
+	return doors
+}
+
+// byLeft впорядковує контури за лівим краєм.
+type byLeft struct {
+	nodes []*html.Node
+	polys [][]point
+}
+
+func (b byLeft) Len() int { return len(b.nodes) }
+
+func (b byLeft) Less(i, j int) bool { return polygonBox(b.polys[i]).X < polygonBox(b.polys[j]).X }
+
+func (b byLeft) Swap(i, j int) {
+	b.nodes[i], b.nodes[j] = b.nodes[j], b.nodes[i]
+	b.polys[i], b.polys[j] = b.polys[j], b.polys[i]
+}
+
+// groupOutline повертає контур .outline з найближчої групи-предка елемента.
+func groupOutline(n *html.Node) *html.Node {
+	for p := n.Parent; p != nil && p.Type == html.ElementNode && p.Data != "svg"; p = p.Parent {
+		for c := p.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "polygon" && hasClass(c, "outline") {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// polygonBox повертає прямокутник, що обмежує многокутник.
+func polygonBox(poly []point) box {
+	if len(poly) == 0 {
+		return box{}
+	}
+	minX, minY, maxX, maxY := poly[0].X, poly[0].Y, poly[0].X, poly[0].Y
+	for _, p := range poly[1:] {
+		minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+		maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+	}
+	return box{minX, minY, maxX - minX, maxY - minY}
+}
+
+// orderDoors впорядковує двері за правилом order.
+func orderDoors(doors []door, order string) error {
+	switch order {
+	case DoorOrderLTR:
+		sort.SliceStable(doors, func(i, j int) bool {
+			a, b := doors[i], doors[j]
+			if a.section != b.section {
+				return a.section < b.section
+			}
+			if math.Abs(a.mid.X-b.mid.X) > lintWallTolerance {
+				return a.mid.X < b.mid.X
+			}
+			return a.mid.Y < b.mid.Y
+		})
+	case DoorOrderClockwise:
+		// Центр секції - центр її дверей, щоб обхід ішов уздовж них, а не довкола порожнього контуру
+		centers := make(map[int]point)
+		counts := make(map[int]float64)
+		for _, d := range doors {
+			c := centers[d.section]
+			centers[d.section] = point{c.X + d.mid.X, c.Y + d.mid.Y}
+			counts[d.section]++
+		}
+		angle := func(d door) float64 {
+			c := centers[d.section]
+			c = point{c.X / counts[d.section], c.Y / counts[d.section]}
+			// 0 - напрям угору (12 година), кут зростає за годинниковою стрілкою (вісь y спрямована вниз)
+			a := math.Atan2(d.mid.X-c.X, c.Y-d.mid.Y)
+			if a < 0 {
+				a += 2 * math.Pi
+			}
+			return a
+		}
+		sort.SliceStable(doors, func(i, j int) bool {
+			a, b := doors[i], doors[j]
+			if a.section != b.section {
+				return a.section < b.section
+			}
+			return angle(a) < angle(b)
+		})
+	default:
+		return Errorf("невідоме правило нумерації %q (%s)", order, strings.Join(DoorOrders, ", "))
+	}
+	return nil
+}
+
+// matchDoorLabels зіставляє наявні номери з дверима: найближчі пари (центр підпису - середина дверей)
+// у межах radius, кожен підпис і кожні двері - не більше одного разу.
+func matchDoorLabels(doors []door, labels []*html.Node, styles classStyles, radius float64) []*html.Node {
+	type pair struct {
+		door, label int
+		dist        float64
+	}
+	var pairs []pair
+	for li, n := range labels {
+		b := textBox(n, styles)
+		for di, d := range doors {
+			// Відстань рахується до рамки підпису: великі номери стоять поруч з дверима, а не центром на них
+			if dd := boxDistance(b, d.mid); dd <= radius {
+				pairs = append(pairs, pair{di, li, dd})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].dist < pairs[j].dist })
+
+	owner := make([]*html.Node, len(doors))
+	used := make(map[int]bool)
+	for _, p := range pairs {
+		if owner[p.door] != nil || used[p.label] {
+			continue
+		}
+		owner[p.door] = labels[p.label]
+		used[p.label] = true
+	}
+	return owner
+}
+
+// boxDistance повертає відстань від точки до прямокутника (0, якщо точка всередині).
+func boxDistance(b box, p point) float64 {
+	dx := math.Max(0, math.Max(b.X-p.X, p.X-(b.X+b.W)))
+	dy := math.Max(0, math.Max(b.Y-p.Y, p.Y-(b.Y+b.H)))
+	return math.Hypot(dx, dy)
+}
+
+// doorLabelSpot обирає центр нового номера: поруч з серединою дверей з того боку,
+// де рамка b нічого не перекриває, або найближче вільне місце від нього.
+func doorLabelSpot(d door, b box, obstacles []obstacle, placed []placedLabel, offsets []point) point {
+	l := d.seg.length()
+	normal := point{0, -1}
+	if l > 0 {
+		normal = point{-(d.seg.B.Y - d.seg.A.Y) / l, (d.seg.B.X - d.seg.A.X) / l}
+	}
+	// Відступ від середини дверей до центру рамки вздовж нормалі, щоб рамка не торкалася дверей
+	gap := math.Abs(normal.X)*b.W/2 + math.Abs(normal.Y)*b.H/2 + defaultCollisionMargin + 4
+
+	// Якщо вільного місця немає, номер ставиться з першого боку - його розведе collisions
+	best := point{d.mid.X + normal.X*gap, d.mid.Y + normal.Y*gap}
+	bestShift := math.Inf(1)
+	for _, side := range []float64{1, -1} {
+		c := point{d.mid.X + side*normal.X*gap, d.mid.Y + side*normal.Y*gap}
+		cb := box{c.X - b.W/2, c.Y - b.H/2, b.W, b.H}
+		if len(labelHits(cb.inflate(defaultCollisionMargin), obstacles, placed)) == 0 {
+			return c
+		}
+		if off, ok := freeOffset(cb, defaultCollisionMargin, obstacles, placed, offsets); ok {
+			if shift := math.Hypot(off.X, off.Y); shift < bestShift {
+				best, bestShift = point{c.X + off.X, c.Y + off.Y}, shift
+			}
+		}
+	}
+	return best
+}
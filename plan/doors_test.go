@@ -0,0 +1,251 @@
+package plan
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// doorsPlan - секція 400x300 з дверима посередині кожної сторони; body додається в кінець.
+func doorsPlan(t *testing.T, body string) *Document {
+	t.Helper()
+	return parseSVG(t, `<svg viewBox="-50 -50 900 400">
+		<style>
+			.outline { fill: #FFF; stroke: #000; stroke-width: 5; }
+			.wall { stroke: #000; stroke-width: 5; }
+			.doors { stroke: #FFF; stroke-width: 8; }
+			.door-number { font-size: 20px; }
+		</style>
+		<polygon class="outline" points="0,0 400,0 400,300 0,300"/>
+		<line class="doors" x1="180" y1="0" x2="220" y2="0"/>
+		<line class="doors" x1="400" y1="130" x2="400" y2="170"/>
+		<line class="doors" x1="180" y1="300" x2="220" y2="300"/>
+		<line class="doors" x1="0" y1="130" x2="0" y2="170"/>
+		`+body+`
+	</svg>`)
+}
+
+// doorMids повертає середини пронумерованих дверей у порядку номерів.
+func doorMids(numbers []DoorNumber) []point {
+	var out []point
+	for _, n := range numbers {
+		if !n.Orphan {
+			out = append(out, point{n.X, n.Y})
+		}
+	}
+	return out
+}
+
+func TestNumberDoorsOrder(t *testing.T) {
+	top, right, bottom, left := point{200, 0}, point{400, 150}, point{200, 300}, point{0, 150}
+	cases := []struct {
+		order string
+		want  []point
+	}{
+		{DoorOrderLTR, []point{left, top, bottom, right}},
+		{DoorOrderClockwise, []point{top, right, bottom, left}},
+	}
+	for _, c := range cases {
+		t.Run(c.order, func(t *testing.T) {
+			d := doorsPlan(t, "")
+			numbers, err := NumberDoors(d, DoorNumbering{Order: c.order})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := doorMids(numbers); !slices.Equal(got, c.want) {
+				t.Errorf("порядок дверей %v, очікувався %v", got, c.want)
+			}
+			for i, n := range numbers {
+				if want := string(rune('1' + i)); n.Number != want || !n.Created {
+					t.Errorf("двері %d: %+v, очікувався новий номер %s", i, n, want)
+				}
+			}
+			if labels := elementsByTag(d, "text"); len(labels) != 4 {
+				t.Errorf("створено %d підписів, очікувалось 4", len(labels))
+			}
+		})
+	}
+
+	if _, err := NumberDoors(doorsPlan(t, ""), DoorNumbering{Order: "spiral"}); err == nil {
+		t.Error("невідоме правило нумерації має повертати помилку")
+	}
+}
+
+func TestNumberDoorsSections(t *testing.T) {
+	// Друга секція праворуч нумерується після першої, хоч її двері в документі йдуть раніше
+	d := parseSVG(t, `<svg viewBox="0 0 900 300">
+		<g><polygon class="outline" points="500,0 800,0 800,300 500,300"/>
+			<line class="doors" x1="520" y1="0" x2="560" y2="0"/></g>
+		<g><polygon class="outline" points="0,0 400,0 400,300 0,300"/>
+			<line class="doors" x1="300" y1="0" x2="340" y2="0"/></g>
+	</svg>`)
+	numbers, err := NumberDoors(d, DoorNumbering{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(numbers) != 2 || numbers[0].X != 320 || numbers[0].Section != 0 || numbers[1].X != 540 || numbers[1].Section != 1 {
+		t.Errorf("секції: %+v", numbers)
+	}
+}
+
+func TestNumberDoorsPrefixStart(t *testing.T) {
+	cases := []struct {
+		prefix string
+		start  int
+		want   []string
+	}{
+		{"", 5, []string{"5", "6", "7", "8"}},
+		{"2", 0, []string{"201", "202", "203", "204"}},
+		{"3", 8, []string{"308", "309", "310", "311"}},
+		// Номери доповнюються до довжини найбільшого, щоб усі на поверсі мали однакову ширину
+		{"1", 98, []string{"1098", "1099", "1100", "1101"}},
+	}
+	for _, c := range cases {
+		numbers, err := NumberDoors(doorsPlan(t, ""), DoorNumbering{Prefix: c.prefix, Start: c.start})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, n := range numbers {
+			got = append(got, n.Number)
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("префікс %q, початок %d: %v, очікувалось %v", c.prefix, c.start, got, c.want)
+		}
+	}
+}
+
+func TestNumberDoorsExistingAndOrphans(t *testing.T) {
+	// 17 стоїть біля лівих дверей і переписується, 9 - далеко від усіх дверей
+	d := doorsPlan(t, `<g id="door-numbers">
+		<text class="door-number" x="10" y="155">17</text>
+		<text class="door-number" x="600" y="250">9</text>
+	</g>`)
+	numbers, err := NumberDoors(d, DoorNumbering{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(numbers) != 5 {
+		t.Fatalf("очікувалось 4 двері й 1 зайвий номер, отримано %+v", numbers)
+	}
+	if first := numbers[0]; first.Old != "17" || first.Number != "1" || first.Created {
+		t.Errorf("наявний номер лівих дверей: %+v", first)
+	}
+	if orphan := numbers[4]; !orphan.Orphan || orphan.Number != "9" || orphan.Section != -1 {
+		t.Errorf("номер без дверей: %+v", orphan)
+	}
+	var texts []string
+	for _, n := range elementsByTag(d, "text") {
+		texts = append(texts, textContent(n))
+	}
+	if want := []string{"1", "9", "2", "3", "4"}; !slices.Equal(texts, want) {
+		t.Errorf("підписи %v, очікувалось %v", texts, want)
+	}
+
+	// Зайвий номер, що збігається з новим, продовжує нумерацію після останніх дверей;
+	// напис, що не є номером, не займає дверей і не змінюється
+	d = doorsPlan(t, `<g id="door-numbers">
+		<text class="door-number" x="600" y="250">3</text>
+		<text class="door-number" x="10" y="155">osb</text>
+	</g>`)
+	numbers, err = NumberDoors(d, DoorNumbering{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(numbers) != 6 {
+		t.Fatalf("очікувалось 4 двері, 1 зайвий номер і 1 напис, отримано %+v", numbers)
+	}
+	if !numbers[0].Created {
+		t.Errorf("біля лівих дверей лише напис osb, номер мав бути створений: %+v", numbers[0])
+	}
+	if orphan := numbers[4]; !orphan.Orphan || orphan.Old != "3" || orphan.Number != "5" {
+		t.Errorf("зайвий номер, що збігся з новим: %+v", orphan)
+	}
+	if invalid := numbers[5]; !invalid.Invalid || invalid.Number != "osb" {
+		t.Errorf("напис, що не є номером: %+v", invalid)
+	}
+	texts = nil
+	for _, n := range elementsByTag(d, "text") {
+		texts = append(texts, textContent(n))
+	}
+	if want := []string{"5", "osb", "1", "2", "3", "4"}; !slices.Equal(texts, want) {
+		t.Errorf("підписи %v, очікувалось %v", texts, want)
+	}
+}
+
+func TestNumberDoorsFullPlan(t *testing.T) {
+	for _, file := range []string{"full.html", "mirror.html"} {
+		for _, order := range DoorOrders {
+			for _, gaps := range []bool{false, true} {
+				d := loadPlan(t, file)
+				numbers, err := NumberDoors(d, DoorNumbering{Order: order, Gaps: gaps})
+				if err != nil {
+					t.Fatalf("%s %s gaps=%v: %v", file, order, gaps, err)
+				}
+				doors, invalid := 0, 0
+				for _, n := range numbers {
+					switch {
+					case n.Invalid:
+						invalid++
+						if n.Number != "osb" {
+							t.Errorf("%s: %q не мав вважатися написом", file, n.Number)
+						}
+					case !n.Orphan:
+						doors++
+					}
+				}
+				if doors == 0 || invalid != 1 {
+					t.Errorf("%s %s gaps=%v: дверей %d, написів %d", file, order, gaps, doors, invalid)
+				}
+
+				// Після нумерації на плані немає двох однакових номерів
+				seen := make(map[string]bool)
+				for _, n := range elementsByTag(d, "text") {
+					if !hasClass(n, "door-number") {
+						continue
+					}
+					if num := textContent(n); seen[num] {
+						t.Errorf("%s %s gaps=%v: номер %s повторюється", file, order, gaps, num)
+					} else {
+						seen[num] = true
+					}
+				}
+				for i := 1; i <= doors; i++ {
+					if !seen[strconv.Itoa(i)] {
+						t.Errorf("%s %s gaps=%v: немає номера %d", file, order, gaps, i)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestNumberDoorsGaps(t *testing.T) {
+	// Проріз 50 - двері, 150 - ні, 10 - надто вузький, проріз з line.doors не рахується вдруге
+	d := doorsPlan(t, `
+		<line class="wall" x1="0" y1="100" x2="100" y2="100"/>
+		<line class="wall" x1="150" y1="100" x2="200" y2="100"/>
+		<line class="wall" x1="350" y1="100" x2="360" y2="100"/>
+		<line class="wall" x1="370" y1="100" x2="400" y2="100"/>
+		<line class="wall" x1="0" y1="200" x2="100" y2="200"/>
+		<line class="wall" x1="160" y1="200" x2="400" y2="200"/>
+		<line class="doors" x1="110" y1="200" x2="150" y2="200"/>
+	`)
+	numbers, err := NumberDoors(d, DoorNumbering{Gaps: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gap int
+	for _, n := range numbers {
+		if n.X == 125 && n.Y == 100 {
+			gap++
+		}
+	}
+	if len(numbers) != 6 || gap != 1 {
+		t.Errorf("очікувалось 5 дверей і 1 проріз (125, 100), отримано %+v", numbers)
+	}
+
+	if _, err := NumberDoors(doorsPlan(t, ""), DoorNumbering{Gaps: true, MinDoorGap: 80, DoorGap: 60}); err == nil {
+		t.Error("найменша ширина прорізу, більша за найбільшу, має повертати помилку")
+	}
+}
@@ -0,0 +1,834 @@
+package plan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Параметри імпорту DXF за замовчуванням.
+const (
+	defaultDXFMargin = 20.0
+	// dxfMaxDepth - найбільша вкладеність блоків (захист від циклічних INSERT)
+	dxfMaxDepth = 16
+	// dxfArcStep - крок апроксимації дуг відрізками, градусів
+	dxfArcStep = 10.0
+)
+
+// DefaultDXFLayers - відповідність шарів DXF класам плану за замовчуванням
+// (шаблони з * без урахування регістру: назви AIA, поширені назви шарів і класи, з якими експортує ExportDXF).
+var DefaultDXFLayers = map[string]string{
+	"A-WALL*":      "wall",
+	"WALL*":        "wall",
+	"СТІН*":        "wall",
+	"A-DOOR*":      "doors",
+	"DOOR*":        "doors",
+	"ДВЕР*":        "doors",
+	"A-FLOR-STRS*": "stair-step",
+	"STAIR*":       "stair-step",
+	"СХОД*":        "stair-step",
+	"DOOR-NUMBER*": "door-number",
+	"OUTLINE*":     "outline",
+	"ARROW*":       "arrow",
+	"A-AREA-IDEN*": "room-name",
+	"ROOM*":        "room-name",
+	"ПРИМІЩ*":      "room-name",
+}
+
+// dxfLineClasses - класи, які в наших планах бувають лише на <line>: полілінії з них розбиваються на відрізки.
+var dxfLineClasses = map[string]bool{"wall": true, "doors": true, "stair-step": true}
+
+// dxfTextClasses - класи підписів: з таких шарів імпортуються лише тексти, з інших - лише геометрія.
+var dxfTextClasses = map[string]bool{"room-name": true, "door-number": true}
+
+// defaultDXFStyles - стилі класів імпортованого плану (як у full.html).
+var defaultDXFStyles = map[string]string{
+	"outline":     ".outline { fill: #FFFEF8; stroke: #000; stroke-width: 5; }",
+	"wall":        ".wall { fill: none; stroke: #000; stroke-width: 5; }",
+	"doors":       ".doors { fill: #FFF; stroke: #FFF; stroke-width: 8; }",
+	"stair-step":  ".stair-step { stroke: #000; stroke-width: 2; fill: none; }",
+	"arrow":       ".arrow { fill: #000; stroke: #000; stroke-width: 2; }",
+	"room-name":   ".room-name { font-family: Arial; font-size: 21px; font-weight: 900; fill: #666; }",
+	"door-number": ".door-number { font-family: Arial; font-size: 45px; font-weight: 900; fill: #00f; }",
+}
+
+// dxfUnits - сантиметрів в одиниці DXF за значенням $INSUNITS.
+var dxfUnits = map[int]float64{1: 2.54, 2: 30.48, 4: 0.1, 5: 1, 6: 100}
+
+// DXFOptions - параметри імпорту DXF.
+type DXFOptions struct {
+	// Layers - відповідність шарів класам (шаблони з * без урахування регістру); доповнює DefaultDXFLayers
+	// і має вищий пріоритет. Порожній клас - пропустити шар
+	Layers map[string]string `json:"layers,omitempty"`
+	// Scale - одиниць viewBox в одиниці DXF; 0 - сантиметри за $INSUNITS (1, якщо одиниці не задано)
+	Scale float64 `json:"scale,omitempty"`
+	// Margin - поле навколо плану; 0 - 20
+	Margin float64 `json:"margin,omitempty"`
+}
+
+// DXFLayer - шар DXF і результат його імпорту.
+type DXFLayer struct {
+	Name string `json:"name"`
+	// Class - клас, з яким імпортовано шар ("" - шар пропущено)
+	Class string `json:"class,omitempty"`
+	// Entities - кількість імпортованих об'єктів
+	Entities int `json:"entities"`
+	// Skipped - кількість пропущених об'єктів (шар без класу, тексти на шарі геометрії чи навпаки, непідтримувані типи)
+	Skipped int `json:"skipped,omitempty"`
+}
+
+// ImportDXF читає ASCII DXF і будує план у наших позначеннях: LINE, LWPOLYLINE, POLYLINE, ARC
+// стають <line>/<polyline>/<polygon> (полілінії стін, дверей і сходинок - окремими <line>), CIRCLE - <circle>,
+// TEXT, MTEXT і атрибути блоків - <text> у групі room-numbers. Блоки (INSERT) розгортаються в абсолютні
+// координати; об'єкти шару 0 у блоці отримують шар вставки. Вісь y перевертається, план зсувається
+// так, щоб viewBox починався з 0 0. Повертає також звіт по шарах у порядку назв.
+func ImportDXF(ctx context.Context, r io.Reader, opts DXFOptions) (*Document, []DXFLayer, error) {
+	if opts.Margin <= 0 {
+		opts.Margin = defaultDXFMargin
+	}
+	pairs, err := readDXFPairs(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	f := parseDXF(pairs)
+	if opts.Scale <= 0 {
+		opts.Scale = 1
+		if s, ok := dxfUnits[f.units]; ok {
+			opts.Scale = s
+		}
+	}
+
+	c := &dxfConverter{file: f, patterns: dxfPatterns(opts.Layers), layers: make(map[string]*DXFLayer)}
+	c.walk(f.entities, identity, "", 0)
+
+	var report []DXFLayer
+	for _, l := range c.layers {
+		report = append(report, *l)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Name < report[j].Name })
+	if len(c.shapes) == 0 {
+		names := make([]string, len(report))
+		for i, l := range report {
+			names[i] = l.Name
+		}
+		return nil, report, Errorf("%w DXF: немає об'єктів на шарах, зіставлених з класами плану (шари: %s)", ErrParse, strings.Join(names, ", "))
+	}
+	c.doorOpenings()
+	return &Document{SVG: c.svg(opts.Scale, opts.Margin)}, report, nil
+}
+
+// dxfPair - пара "код групи - значення" DXF.
+type dxfPair struct {
+	code int
+	val  string
+}
+
+// readDXFPairs розбирає ASCII DXF на пари код-значення.
+func readDXFPairs(r io.Reader) ([]dxfPair, error) {
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(18); string(head) == "AutoCAD Binary DXF" {
+		return nil, Errorf("%w DXF: двійковий DXF не підтримується, збережіть файл як ASCII DXF", ErrParse)
+	}
+	sc := bufio.NewScanner(br)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var pairs []dxfPair
+	for line := 1; sc.Scan(); line += 2 {
+		raw := strings.TrimSpace(sc.Text())
+		if raw == "" && len(pairs) > 0 && pairs[len(pairs)-1].val == "EOF" {
+			break
+		}
+		code, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, Errorf("%w DXF: рядок %d: очікувався код групи, отримано %q", ErrParse, line, raw)
+		}
+		if !sc.Scan() {
+			return nil, Errorf("%w DXF: рядок %d: немає значення для коду %d", ErrParse, line+1, code)
+		}
+		val := strings.TrimRight(sc.Text(), "\r")
+		if code != 1 && code != 3 {
+			val = strings.TrimSpace(val)
+		}
+		pairs = append(pairs, dxfPair{code, val})
+		if code == 0 && val == "EOF" {
+			break
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, Errorf("%w DXF: %w", ErrParse, err)
+	}
+	return pairs, nil
+}
+
+// dxfEntity - об'єкт DXF: тип, його пари і підлеглі об'єкти (VERTEX полілінії, ATTRIB вставки).
+type dxfEntity struct {
+	typ      string
+	pairs    []dxfPair
+	children []dxfEntity
+}
+
+// dxfBlock - визначення блоку.
+type dxfBlock struct {
+	base     point
+	entities []dxfEntity
+}
+
+// dxfFile - розібраний DXF: одиниці, кодова сторінка, блоки й об'єкти.
+type dxfFile struct {
+	units    int
+	cp1251   bool
+	blocks   map[string]dxfBlock
+	entities []dxfEntity
+}
+
+// parseDXF збирає з пар секції HEADER, BLOCKS і ENTITIES; решта секцій пропускається.
+func parseDXF(pairs []dxfPair) *dxfFile {
+	f := &dxfFile{blocks: make(map[string]dxfBlock)}
+	var records []dxfEntity
+	for _, p := range pairs {
+		if p.code == 0 {
+			records = append(records, dxfEntity{typ: p.val})
+		} else if len(records) > 0 {
+			r := &records[len(records)-1]
+			r.pairs = append(r.pairs, p)
+		}
+	}
+
+	section := ""
+	var block *dxfBlock
+	var blockName string
+	for i := 0; i < len(records); i++ {
+		r := records[i]
+		switch {
+		case r.typ == "SECTION":
+			// Змінні заголовка не мають коду 0 і потрапляють у пари самого SECTION
+			section = r.str(2)
+			if section == "HEADER" {
+				f.readHeader(r.pairs)
+			}
+			continue
+		case r.typ == "ENDSEC":
+			section = ""
+			continue
+		}
+		switch section {
+		case "BLOCKS":
+			switch r.typ {
+			case "BLOCK":
+				block, blockName = &dxfBlock{base: point{r.float(10), r.float(20)}}, r.str(2)
+			case "ENDBLK":
+				if block != nil {
+					f.blocks[blockName] = *block
+				}
+				block = nil
+			default:
+				if block != nil {
+					var e dxfEntity
+					e, i = groupDXFEntity(records, i)
+					block.entities = append(block.entities, e)
+				}
+			}
+		case "ENTITIES":
+			var e dxfEntity
+			e, i = groupDXFEntity(records, i)
+			f.entities = append(f.entities, e)
+		}
+	}
+	return f
+}
+
+// readHeader читає змінні заголовка $INSUNITS і $DWGCODEPAGE.
+func (f *dxfFile) readHeader(pairs []dxfPair) {
+	for i := 0; i+1 < len(pairs); i++ {
+		if pairs[i].code != 9 {
+			continue
+		}
+		switch pairs[i].val {
+		case "$INSUNITS":
+			f.units, _ = strconv.Atoi(pairs[i+1].val)
+		case "$DWGCODEPAGE":
+			f.cp1251 = strings.EqualFold(pairs[i+1].val, "ANSI_1251")
+		}
+	}
+}
+
+// groupDXFEntity повертає об'єкт records[i] разом із підлеглими VERTEX чи ATTRIB до SEQEND
+// та індекс останнього спожитого запису.
+func groupDXFEntity(records []dxfEntity, i int) (dxfEntity, int) {
+	e := records[i]
+	if e.typ != "POLYLINE" && !(e.typ == "INSERT" && e.int(66) == 1) {
+		return e, i
+	}
+	for i+1 < len(records) {
+		next := records[i+1]
+		if next.typ != "VERTEX" && next.typ != "ATTRIB" && next.typ != "SEQEND" {
+			break
+		}
+		i++
+		if next.typ == "SEQEND" {
+			break
+		}
+		e.children = append(e.children, next)
+	}
+	return e, i
+}
+
+// str повертає перше значення коду (або "").
+func (e dxfEntity) str(code int) string {
+	for _, p := range e.pairs {
+		if p.code == code {
+			return p.val
+		}
+	}
+	return ""
+}
+
+// float повертає перше числове значення коду (або 0).
+func (e dxfEntity) float(code int) float64 {
+	v, _ := strconv.ParseFloat(e.str(code), 64)
+	return v
+}
+
+// floatOr повертає числове значення коду або def, якщо коду немає.
+func (e dxfEntity) floatOr(code int, def float64) float64 {
+	for _, p := range e.pairs {
+		if p.code == code {
+			if v, err := strconv.ParseFloat(p.val, 64); err == nil {
+				return v
+			}
+		}
+	}
+	return def
+}
+
+// int повертає перше ціле значення коду (або 0).
+func (e dxfEntity) int(code int) int {
+	v, _ := strconv.Atoi(e.str(code))
+	return v
+}
+
+// dxfShape - імпортований об'єкт у світових координатах DXF (вісь y - вгору).
+type dxfShape struct {
+	class  string
+	pts    []point
+	closed bool
+	// circle - коло з центром pts[0] і радіусом r
+	circle bool
+	r      float64
+	// text - підпис у точці базової лінії pts[0] з поворотом rot (градуси проти годинникової стрілки)
+	text   string
+	anchor string
+	rot    float64
+	// swing - центр, початок і кінець дуги ARC (для дуг відкривання дверей)
+	swing []point
+}
+
+// dxfPattern - шаблон назви шару і його клас.
+type dxfPattern struct {
+	pattern, class string
+}
+
+// dxfPatterns впорядковує шаблони: спершу користувацькі, потім DefaultDXFLayers;
+// у кожній групі точні назви перед шаблонами з *, довші перед коротшими.
+func dxfPatterns(layers map[string]string) []dxfPattern {
+	group := func(m map[string]string) []dxfPattern {
+		var out []dxfPattern
+		for p, c := range m {
+			out = append(out, dxfPattern{strings.ToUpper(p), c})
+		}
+		sort.Slice(out, func(i, j int) bool {
+			wi, wj := strings.Contains(out[i].pattern, "*"), strings.Contains(out[j].pattern, "*")
+			if wi != wj {
+				return !wi
+			}
+			if len(out[i].pattern) != len(out[j].pattern) {
+				return len(out[i].pattern) > len(out[j].pattern)
+			}
+			return out[i].pattern < out[j].pattern
+		})
+		return out
+	}
+	return append(group(layers), group(DefaultDXFLayers)...)
+}
+
+// dxfConverter перетворює об'єкти DXF на фігури плану і веде звіт по шарах.
+type dxfConverter struct {
+	file     *dxfFile
+	patterns []dxfPattern
+	layers   map[string]*DXFLayer
+	shapes   []dxfShape
+}
+
+// layer повертає запис звіту для шару, визначаючи його клас за першим відповідним шаблоном.
+func (c *dxfConverter) layer(name string) *DXFLayer {
+	if l, ok := c.layers[name]; ok {
+		return l
+	}
+	l := &DXFLayer{Name: name}
+	upper := strings.ToUpper(name)
+	for _, p := range c.patterns {
+		if ok, _ := path.Match(p.pattern, upper); ok {
+			l.Class = p.class
+			break
+		}
+	}
+	c.layers[name] = l
+	return l
+}
+
+// walk перетворює об'єкти з матрицею m (координати об'єкта → світові); inherited - шар вставки для шару 0.
+func (c *dxfConverter) walk(entities []dxfEntity, m matrix, inherited string, depth int) {
+	for _, e := range entities {
+		name := e.str(8)
+		if (name == "0" || name == "") && inherited != "" {
+			name = inherited
+		}
+		switch e.typ {
+		case "INSERT":
+			c.insert(e, m, name, depth)
+			continue
+		case "ATTDEF":
+			// Визначення атрибутів блоку: значення приходять в ATTRIB вставки
+			continue
+		}
+		l := c.layer(name)
+		s, ok := c.shape(e, m)
+		if !ok || l.Class == "" || dxfTextClasses[l.Class] != (s.text != "") {
+			l.Skipped++
+			continue
+		}
+		s.class = l.Class
+		c.shapes = append(c.shapes, s)
+		l.Entities++
+	}
+}
+
+// insert розгортає вставку блоку та її атрибути.
+func (c *dxfConverter) insert(e dxfEntity, m matrix, layer string, depth int) {
+	b, ok := c.file.blocks[e.str(2)]
+	if ok && depth < dxfMaxDepth {
+		sx, sy := e.floatOr(41, 1), e.floatOr(42, 1)
+		a := e.float(50) * math.Pi / 180
+		cos, sin := math.Cos(a), math.Sin(a)
+		t := matrix{1, 0, 0, 1, e.float(10), e.float(20)}.
+			mul(matrix{cos, sin, -sin, cos, 0, 0}).
+			mul(matrix{sx, 0, 0, sy, 0, 0}).
+			mul(matrix{1, 0, 0, 1, -b.base.X, -b.base.Y})
+		c.walk(b.entities, m.mul(t), layer, depth+1)
+	} else {
+		c.layer(layer).Skipped++
+	}
+	// Атрибути вставки вже записані у координатах простору, куди вставлено блок
+	c.walk(e.children, m, layer, depth)
+}
+
+// shape перетворює об'єкт на фігуру у світових координатах (false - тип не підтримується).
+func (c *dxfConverter) shape(e dxfEntity, m matrix) (dxfShape, bool) {
+	apply := func(pts []point) []point {
+		for i := range pts {
+			pts[i] = m.apply(pts[i])
+		}
+		return pts
+	}
+	switch e.typ {
+	case "LINE":
+		return dxfShape{pts: apply([]point{{e.float(10), e.float(20)}, {e.float(11), e.float(21)}})}, true
+	case "LWPOLYLINE":
+		var verts []point
+		var bulges []float64
+		for _, p := range e.pairs {
+			v, _ := strconv.ParseFloat(p.val, 64)
+			switch p.code {
+			case 10:
+				verts = append(verts, point{X: v})
+				bulges = append(bulges, 0)
+			case 20:
+				if len(verts) > 0 {
+					verts[len(verts)-1].Y = v
+				}
+			case 42:
+				if len(bulges) > 0 {
+					bulges[len(bulges)-1] = v
+				}
+			}
+		}
+		closed := e.int(70)&1 != 0
+		return dxfShape{pts: apply(flattenBulges(verts, bulges, closed)), closed: closed}, len(verts) >= 2
+	case "POLYLINE":
+		// Тривимірні сітки та багатогранники не є контурами плану
+		if e.int(70)&(16|64) != 0 {
+			return dxfShape{}, false
+		}
+		var verts []point
+		var bulges []float64
+		for _, v := range e.children {
+			verts = append(verts, point{v.float(10), v.float(20)})
+			bulges = append(bulges, v.float(42))
+		}
+		closed := e.int(70)&1 != 0
+		return dxfShape{pts: apply(flattenBulges(verts, bulges, closed)), closed: closed}, len(verts) >= 2
+	case "ARC":
+		a0, a1 := e.float(50), e.float(51)
+		if a1 <= a0 {
+			a1 += 360
+		}
+		pts := arcPoints(point{e.float(10), e.float(20)}, e.float(40), a0, a1)
+		swing := apply([]point{{e.float(10), e.float(20)}, pts[0], pts[len(pts)-1]})
+		return dxfShape{pts: apply(pts), swing: swing}, true
+	case "CIRCLE":
+		return dxfShape{pts: apply([]point{{e.float(10), e.float(20)}}), circle: true, r: e.float(40) * math.Sqrt(math.Abs(m[0]*m[3]-m[1]*m[2]))}, true
+	case "TEXT", "ATTRIB":
+		return c.text(e, m, c.file.decode(e.str(1)))
+	case "MTEXT":
+		var sb strings.Builder
+		for _, p := range e.pairs {
+			if p.code == 3 {
+				sb.WriteString(p.val)
+			}
+		}
+		sb.WriteString(e.str(1))
+		return c.text(e, m, stripMText(c.file.decode(sb.String())))
+	}
+	return dxfShape{}, false
+}
+
+// text перетворює TEXT, ATTRIB чи MTEXT на підпис: вирівнювання DXF переводиться
+// в text-anchor і зсув точки до базової лінії.
+func (c *dxfConverter) text(e dxfEntity, m matrix, s string) (dxfShape, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || e.int(70)&1 != 0 && e.typ == "ATTRIB" {
+		// Порожній текст або невидимий атрибут
+		return dxfShape{}, false
+	}
+	p := point{e.float(10), e.float(20)}
+	h := e.float(40)
+	rot := e.float(50)
+	var col, row int // 0 - ліво/база, 1 - центр/середина, 2 - право/верх
+	if e.typ == "MTEXT" {
+		if dx, dy := e.float(11), e.float(21); dx != 0 || dy != 0 {
+			// Напрям рядка MTEXT може бути задано вектором замість кута
+			rot = math.Atan2(dy, dx) * 180 / math.Pi
+		}
+		if a := e.int(71); a >= 1 && a <= 9 {
+			col, row = (a-1)%3, 2-(a-1)/3
+		} else {
+			row = 2
+		}
+	} else {
+		hj, vj := e.int(72), e.int(73)
+		if e.typ == "ATTRIB" {
+			// В атрибутах код 73 - довжина поля, вертикальне вирівнювання - 74
+			vj = e.int(74)
+		}
+		if hj != 0 || vj != 0 {
+			p = point{e.float(11), e.float(21)}
+		}
+		switch hj {
+		case 1, 4:
+			col = 1
+		case 2:
+			col = 2
+		case 3, 5:
+			// Вирівнювання за двома точками: середина між ними
+			p = point{(e.float(10) + e.float(11)) / 2, (e.float(20) + e.float(21)) / 2}
+			col = 1
+		}
+		switch {
+		case hj == 4 || vj == 2:
+			row = 1
+		case vj == 3:
+			row = 2
+		}
+	}
+	// Зсув точки до базової лінії вздовж напрямку "вгору" тексту: h - висота великих літер
+	up := [3]float64{0, h / 2, h}[row]
+	a := rot * math.Pi / 180
+	p = point{p.X + math.Sin(a)*up, p.Y - math.Cos(a)*up}
+
+	// Напрям рядка після перетворення блоку
+	o, x := m.apply(point{}), m.apply(point{math.Cos(a), math.Sin(a)})
+	return dxfShape{
+		pts:    []point{m.apply(p)},
+		text:   s,
+		anchor: [3]string{"", "middle", "end"}[col],
+		rot:    math.Atan2(x.Y-o.Y, x.X-o.X) * 180 / math.Pi,
+	}, true
+}
+
+// doorOpenings зводить двері до наших позначень - лінії прорізу на стіні: дуга відкривання
+// на шарі дверей замінюється тим її радіусом, що лежить уздовж стіни (закрита стулка),
+// а лінія відчиненої стулки, що збігається з іншим радіусом, відкидається.
+func (c *dxfConverter) doorOpenings() {
+	var walls []segment
+	for _, s := range c.shapes {
+		if s.class == "wall" && s.text == "" && !s.circle {
+			pts := s.pts
+			if s.closed {
+				pts = append(pts[:len(pts):len(pts)], pts[0])
+			}
+			for i := 0; i+1 < len(pts); i++ {
+				walls = append(walls, segment{pts[i], pts[i+1]})
+			}
+		}
+	}
+	// along оцінює, наскільки відрізок лежить на прямій якоїсь паралельної стіни (менше - краще)
+	along := func(r segment) float64 {
+		best := math.Inf(1)
+		for _, w := range walls {
+			if w.length() == 0 || r.length() == 0 {
+				continue
+			}
+			cross := ((r.B.X-r.A.X)*(w.B.Y-w.A.Y) - (r.B.Y-r.A.Y)*(w.B.X-w.A.X)) / (r.length() * w.length())
+			if math.Abs(cross) < 0.05 {
+				best = math.Min(best, w.distToLine(r.A)+w.distToLine(r.B))
+			}
+		}
+		return best
+	}
+
+	var leaves []segment
+	for i, s := range c.shapes {
+		if s.class != "doors" || s.swing == nil {
+			continue
+		}
+		closed, open := segment{s.swing[0], s.swing[1]}, segment{s.swing[0], s.swing[2]}
+		if along(open) < along(closed) {
+			closed, open = open, closed
+		}
+		c.shapes[i] = dxfShape{class: s.class, pts: []point{closed.A, closed.B}}
+		leaves = append(leaves, open)
+	}
+	same := func(a, b segment) bool {
+		tol := 1e-3 * math.Max(1, a.length())
+		return (dist(a.A, b.A) < tol && dist(a.B, b.B) < tol) || (dist(a.A, b.B) < tol && dist(a.B, b.A) < tol)
+	}
+	c.shapes = slices.DeleteFunc(c.shapes, func(s dxfShape) bool {
+		if s.class != "doors" || len(s.pts) != 2 || s.circle {
+			return false
+		}
+		return slices.ContainsFunc(leaves, func(l segment) bool { return same(l, segment{s.pts[0], s.pts[1]}) })
+	})
+}
+
+// flattenBulges перетворює вершини полілінії з опуклостями (bulge - тангенс чверті кута дуги) на ламану.
+func flattenBulges(verts []point, bulges []float64, closed bool) []point {
+	var out []point
+	for i, v := range verts {
+		out = append(out, v)
+		if bulges[i] == 0 || (i == len(verts)-1 && !closed) {
+			continue
+		}
+		next := verts[(i+1)%len(verts)]
+		angle := 4 * math.Atan(bulges[i])
+		chord := dist(v, next)
+		if chord == 0 {
+			continue
+		}
+		r := chord / (2 * math.Sin(math.Abs(angle)/2))
+		// Центр лежить на серединному перпендикулярі хорди
+		mid := point{(v.X + next.X) / 2, (v.Y + next.Y) / 2}
+		h := math.Sqrt(math.Max(0, r*r-chord*chord/4))
+		nx, ny := -(next.Y-v.Y)/chord, (next.X-v.X)/chord
+		if (bulges[i] > 0) != (math.Abs(angle) > math.Pi) {
+			nx, ny = -nx, -ny
+		}
+		center := point{mid.X - nx*h, mid.Y - ny*h}
+		a0 := math.Atan2(v.Y-center.Y, v.X-center.X) * 180 / math.Pi
+		arc := arcPoints(center, r, a0, a0+angle*180/math.Pi)
+		out = append(out, arc[1:len(arc)-1]...)
+	}
+	return out
+}
+
+// arcPoints апроксимує дугу від a0 до a1 градусів (проти годинникової стрілки при a1 > a0) відрізками.
+func arcPoints(c point, r, a0, a1 float64) []point {
+	n := max(2, int(math.Ceil(math.Abs(a1-a0)/dxfArcStep)))
+	pts := make([]point, n+1)
+	for i := range pts {
+		a := (a0 + (a1-a0)*float64(i)/float64(n)) * math.Pi / 180
+		pts[i] = point{c.X + r*math.Cos(a), c.Y + r*math.Sin(a)}
+	}
+	return pts
+}
+
+// decode переводить рядок DXF у UTF-8: кодова сторінка ANSI_1251 старих версій,
+// екранування \U+XXXX і коди %%c, %%d, %%p.
+func (f *dxfFile) decode(s string) string {
+	if f.cp1251 && !utf8.ValidString(s) {
+		s = decodeCP1251(s)
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.HasPrefix(s[i:], `\U+`) && i+7 <= len(s) {
+			if r, err := strconv.ParseUint(s[i+3:i+7], 16, 32); err == nil {
+				sb.WriteRune(rune(r))
+				i += 6
+				continue
+			}
+		}
+		if strings.HasPrefix(s[i:], "%%") && i+2 < len(s) {
+			if r, ok := map[byte]string{'c': "⌀", 'C': "⌀", 'd': "°", 'D': "°", 'p': "±", 'P': "±", '%': "%"}[s[i+2]]; ok {
+				sb.WriteString(r)
+				i += 2
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// decodeCP1251 перекодовує рядок з Windows-1251 (кирилиця, включно з українськими літерами).
+func decodeCP1251(s string) string {
+	special := map[byte]rune{
+		0xA8: 'Ё', 0xB8: 'ё', 0xAA: 'Є', 0xBA: 'є', 0xB2: 'І', 0xB3: 'і',
+		0xAF: 'Ї', 0xBF: 'ї', 0xA5: 'Ґ', 0xB4: 'ґ', 0xB0: '°', 0xB1: '±', 0xB9: '№', 0xA0: ' ',
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		switch {
+		case b < 0x80:
+			sb.WriteByte(b)
+		case b >= 0xC0:
+			sb.WriteRune(rune(0x410 + int(b) - 0xC0))
+		case special[b] != 0:
+			sb.WriteRune(special[b])
+		default:
+			sb.WriteRune(utf8.RuneError)
+		}
+	}
+	return sb.String()
+}
+
+// stripMText прибирає коди форматування MTEXT: шрифти, висоту, колір ({\fArial;...}, \H2.5;),
+// перемикачі підкреслення; \P (новий рядок) і \~ стають пробілами, дроби \S1/2; - "1/2".
+func stripMText(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '{' || ch == '}':
+		case ch == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'P', '~':
+				sb.WriteByte(' ')
+			case '\\', '{', '}':
+				sb.WriteByte(s[i])
+			case 'L', 'l', 'O', 'o', 'K', 'k':
+			case 'S':
+				end := strings.IndexByte(s[i:], ';')
+				if end < 0 {
+					end = len(s) - i
+				}
+				sb.WriteString(strings.NewReplacer("^", "/", "#", "/").Replace(s[i+1 : i+end]))
+				i += end
+			default:
+				// \A1; \C7; \fArial|b1; \H2.5x; \Q15; \T1.1; \W0.8; \pxi-3;
+				if end := strings.IndexByte(s[i:], ';'); end >= 0 {
+					i += end
+				}
+			}
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// svg будує документ: перевертає вісь y, масштабує, зсуває план у початок viewBox з полем margin.
+func (c *dxfConverter) svg(scale, margin float64) *html.Node {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, s := range c.shapes {
+		for _, p := range s.pts {
+			minX, maxX = math.Min(minX, p.X-s.r), math.Max(maxX, p.X+s.r)
+			minY, maxY = math.Min(minY, p.Y-s.r), math.Max(maxY, p.Y+s.r)
+		}
+	}
+	pt := func(p point) point {
+		return point{(p.X-minX)*scale + margin, (maxY-p.Y)*scale + margin}
+	}
+	w, h := (maxX-minX)*scale+2*margin, (maxY-minY)*scale+2*margin
+
+	svg := newElement("svg", "xmlns", "http://www.w3.org/2000/svg",
+		"width", formatNumber(w), "height", formatNumber(h),
+		"viewBox", "0 0 "+formatNumber(w)+" "+formatNumber(h))
+	defs := newElement("defs")
+	svg.AppendChild(defs)
+	labels := newElement("g", "id", "room-numbers")
+
+	used := make(map[string]bool)
+	var classes []string
+	for _, s := range c.shapes {
+		if !used[s.class] {
+			used[s.class] = true
+			classes = append(classes, s.class)
+		}
+		switch {
+		case s.text != "":
+			p := pt(s.pts[0])
+			n := newTextElement("text", s.text, "x", formatNumber(p.X), "y", formatNumber(p.Y), "class", s.class)
+			if s.anchor != "" {
+				setAttr(n, "text-anchor", s.anchor)
+			}
+			if math.Abs(s.rot) > 0.01 {
+				setAttr(n, "transform", fmt.Sprintf("rotate(%s, %s, %s)", formatNumber(-s.rot), formatNumber(p.X), formatNumber(p.Y)))
+			}
+			labels.AppendChild(n)
+		case s.circle:
+			p := pt(s.pts[0])
+			svg.AppendChild(newElement("circle", "cx", formatNumber(p.X), "cy", formatNumber(p.Y), "r", formatNumber(s.r*scale), "class", s.class))
+		case len(s.pts) == 2 || dxfLineClasses[s.class]:
+			pts := s.pts
+			if s.closed {
+				pts = append(pts, pts[0])
+			}
+			for i := 0; i+1 < len(pts); i++ {
+				a, b := pt(pts[i]), pt(pts[i+1])
+				svg.AppendChild(newElement("line", "x1", formatNumber(a.X), "y1", formatNumber(a.Y),
+					"x2", formatNumber(b.X), "y2", formatNumber(b.Y), "class", s.class))
+			}
+		default:
+			coords := make([]string, len(s.pts))
+			for i, p := range s.pts {
+				p = pt(p)
+				coords[i] = formatNumber(p.X) + "," + formatNumber(p.Y)
+			}
+			tag := "polyline"
+			if s.closed {
+				tag = "polygon"
+			}
+			svg.AppendChild(newElement(tag, "points", strings.Join(coords, " "), "class", s.class))
+		}
+	}
+	if labels.FirstChild != nil {
+		svg.AppendChild(labels)
+	}
+
+	sort.Strings(classes)
+	var css bytes.Buffer
+	for _, class := range classes {
+		if style, ok := defaultDXFStyles[class]; ok {
+			css.WriteString(style + "\n")
+		}
+	}
+	if css.Len() > 0 {
+		defs.AppendChild(newTextElement("style", strings.TrimSuffix(css.String(), "\n")))
+	}
+	return svg
+}
@@ -0,0 +1,192 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// dxfSource складає ASCII DXF з пар "код, значення" секції ENTITIES (і HEADER, якщо header не порожній).
+func dxfSource(header []string, entities ...string) string {
+	var lines []string
+	if len(header) > 0 {
+		lines = append(lines, "0", "SECTION", "2", "HEADER")
+		lines = append(lines, header...)
+		lines = append(lines, "0", "ENDSEC")
+	}
+	lines = append(lines, "0", "SECTION", "2", "ENTITIES")
+	lines = append(lines, entities...)
+	lines = append(lines, "0", "ENDSEC", "0", "EOF")
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// dxfLine - відрізок LINE на шарі layer.
+func dxfLine(layer string, x1, y1, x2, y2 float64) []string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	return []string{"0", "LINE", "8", layer, "10", f(x1), "20", f(y1), "11", f(x2), "21", f(y2)}
+}
+
+// importDXF імпортує DXF з тесту, перевіряючи відсутність помилки.
+func importDXF(t *testing.T, src string, opts DXFOptions) (*Document, map[string]DXFLayer) {
+	t.Helper()
+	d, report, err := ImportDXF(context.Background(), strings.NewReader(src), opts)
+	if err != nil {
+		t.Fatalf("імпорт DXF: %v", err)
+	}
+	layers := make(map[string]DXFLayer)
+	for _, l := range report {
+		layers[l.Name] = l
+	}
+	return d, layers
+}
+
+// classCount рахує елементи з тегом tag і класом class.
+func classCount(d *Document, tag, class string) int {
+	return len(slices.DeleteFunc(elementsByTag(d, tag), func(n *html.Node) bool { return !hasClass(n, class) }))
+}
+
+func TestImportDXFLayers(t *testing.T) {
+	var entities []string
+	entities = append(entities, dxfLine("A-WALL", 0, 0, 400, 0)...)
+	entities = append(entities, dxfLine("стіни", 0, 0, 0, 300)...)
+	entities = append(entities, dxfLine("A-DOOR", 100, 0, 150, 0)...)
+	entities = append(entities, dxfLine("MEBLI", 10, 10, 50, 50)...)
+	entities = append(entities, dxfLine("SHAFA", 60, 60, 90, 90)...)
+	// Текст на шарі стін пропускається: з шарів геометрії імпортуються лише лінії
+	entities = append(entities, "0", "TEXT", "8", "A-WALL", "10", "5", "20", "5", "40", "10", "1", "стіна")
+	src := dxfSource(nil, entities...)
+
+	d, layers := importDXF(t, src, DXFOptions{})
+	want := map[string]DXFLayer{
+		"A-WALL": {Name: "A-WALL", Class: "wall", Entities: 1, Skipped: 1},
+		"стіни":  {Name: "стіни", Class: "wall", Entities: 1},
+		"A-DOOR": {Name: "A-DOOR", Class: "doors", Entities: 1},
+		"MEBLI":  {Name: "MEBLI", Skipped: 1},
+		"SHAFA":  {Name: "SHAFA", Skipped: 1},
+	}
+	for name, w := range want {
+		if layers[name] != w {
+			t.Errorf("шар %s: %+v, очікувалось %+v", name, layers[name], w)
+		}
+	}
+	if n := classCount(d, "line", "wall"); n != 2 {
+		t.Errorf("імпортовано %d стін, очікувалось 2", n)
+	}
+	if n := classCount(d, "line", "doors"); n != 1 {
+		t.Errorf("імпортовано %d дверей, очікувалось 1", n)
+	}
+
+	// Власні шаблони доповнюють типові й мають вищий пріоритет; порожній клас пропускає шар
+	d, layers = importDXF(t, src, DXFOptions{Layers: map[string]string{"meb*": "furniture", "A-DOOR": ""}})
+	if l := layers["MEBLI"]; l.Class != "furniture" || l.Entities != 1 {
+		t.Errorf("шар MEBLI з власним шаблоном: %+v", l)
+	}
+	if l := layers["A-DOOR"]; l.Class != "" || l.Skipped != 1 {
+		t.Errorf("шар A-DOOR, вимкнений шаблоном: %+v", l)
+	}
+	if n := classCount(d, "line", "furniture"); n != 1 {
+		t.Errorf("імпортовано %d ліній furniture, очікувалась 1", n)
+	}
+	if n := classCount(d, "line", "doors"); n != 0 {
+		t.Errorf("вимкнений шар дверей імпортовано: %d ліній", n)
+	}
+}
+
+func TestImportDXFBulge(t *testing.T) {
+	// Опуклість 1 - півколо проти годинникової стрілки від (0,0) до (100,0), тобто вниз по осі y DXF
+	src := dxfSource(nil,
+		"0", "LWPOLYLINE", "8", "OUTLINE", "90", "2", "70", "0",
+		"10", "0", "20", "0", "42", "1",
+		"10", "100", "20", "0",
+	)
+	d, _ := importDXF(t, src, DXFOptions{})
+	polylines := elementsByTag(d, "polyline")
+	if len(polylines) != 1 {
+		t.Fatalf("очікувалась 1 полілінія, отримано %d", len(polylines))
+	}
+	pts := polygonPoints(polylines[0])
+	if len(pts) < 10 {
+		t.Fatalf("дугу не апроксимовано: %v", pts)
+	}
+	// Після перевертання осі y і поля 20 центр (50,0) переходить у (70,20), дуга - нижче нього
+	for _, p := range pts {
+		if r := dist(p, point{70, 20}); r < 49.9 || r > 50.1 || p.Y < 19.9 {
+			t.Errorf("точка %v не лежить на нижньому півколі радіуса 50 з центром (70,20)", p)
+		}
+	}
+	if h := getAttr(d.SVG, "height"); h != "90" {
+		t.Errorf("висота плану %s, очікувалось 90 (радіус дуги і два поля)", h)
+	}
+
+	// Замкнена полілінія стін розбивається на окремі відрізки
+	src = dxfSource(nil,
+		"0", "LWPOLYLINE", "8", "A-WALL", "90", "3", "70", "1",
+		"10", "0", "20", "0", "10", "100", "20", "0", "10", "100", "20", "50",
+	)
+	d, _ = importDXF(t, src, DXFOptions{})
+	if n := classCount(d, "line", "wall"); n != 3 {
+		t.Errorf("замкнена полілінія стін дала %d відрізків, очікувалось 3", n)
+	}
+}
+
+func TestImportDXFText(t *testing.T) {
+	src := dxfSource([]string{"9", "$DWGCODEPAGE", "3", "ANSI_1251"},
+		"0", "TEXT", "8", "ROOMS", "10", "0", "20", "0", "11", "50", "21", "0", "72", "1", "40", "10",
+		"1", `\U+041A\U+0443\U+0445\U+043D\U+044F 20%%dC`,
+		"0", "MTEXT", "8", "ROOMS", "10", "100", "20", "100", "40", "10", "71", "7",
+		"3", `{\fArial|b1;\H2.5x;Склад}\P`, "1", `\LNo\l 1`,
+		"0", "TEXT", "8", "ROOMS", "10", "0", "20", "100", "40", "10",
+		"1", "\xcf\xb3\xe2\xed\xb3\xf7\xed\xe0",
+	)
+	d, layers := importDXF(t, src, DXFOptions{})
+	if l := layers["ROOMS"]; l.Class != "room-name" || l.Entities != 3 {
+		t.Errorf("шар підписів: %+v", l)
+	}
+	texts := elementsByTag(d, "text")
+	if len(texts) != 3 {
+		t.Fatalf("очікувалось 3 підписи, отримано %d", len(texts))
+	}
+	want := []string{"Кухня 20°C", "Склад No 1", "Північна"}
+	for i, n := range texts {
+		if got := textContent(n); got != want[i] {
+			t.Errorf("підпис %d: %q, очікувалось %q", i, got, want[i])
+		}
+	}
+	// Вирівнювання по центру: точка - друга (11/21), text-anchor - middle
+	if getAttr(texts[0], "text-anchor") != "middle" || getAttr(texts[0], "x") != "70" {
+		t.Errorf("центрований TEXT: x=%s text-anchor=%q", getAttr(texts[0], "x"), getAttr(texts[0], "text-anchor"))
+	}
+	// MTEXT з точкою вставки внизу зліва (71=7) стоїть на базовій лінії без зсуву
+	if getAttr(texts[1], "text-anchor") != "" || getAttr(texts[1], "y") != "20" {
+		t.Errorf("MTEXT: y=%s text-anchor=%q", getAttr(texts[1], "y"), getAttr(texts[1], "text-anchor"))
+	}
+}
+
+func TestImportDXFMalformed(t *testing.T) {
+	cases := []struct {
+		name, src string
+	}{
+		{"код групи не число", "0\nSECTION\nabc\nENTITIES\n"},
+		{"немає значення для коду", "0\nSECTION\n2\nENTITIES\n0\nLINE\n8"},
+		{"двійковий DXF", "AutoCAD Binary DXF\r\n\x1a\x00"},
+		{"порожній файл", ""},
+		{"немає зіставлених шарів", dxfSource(nil, dxfLine("MEBLI", 0, 0, 10, 10)...)},
+		// Нечислові координати читаються як 0, а не ламають розбір
+		{"нечислові значення", dxfSource(nil, "0", "LINE", "8", "XREF", "10", "x", "20", "y", "11", "1e999")},
+		{"полілінія без вершин", dxfSource(nil, "0", "POLYLINE", "8", "A-WALL", "70", "1", "0", "SEQEND")},
+		{"вставка невідомого блоку", dxfSource(nil, "0", "INSERT", "8", "A-WALL", "2", "NOPE", "66", "1", "0", "ATTRIB")},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _, err := ImportDXF(context.Background(), strings.NewReader(c.src), DXFOptions{})
+			if !errors.Is(err, ErrParse) || d != nil {
+				t.Errorf("очікувалась ErrParse без документа, отримано %v", err)
+			}
+		})
+	}
+}
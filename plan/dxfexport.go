@@ -0,0 +1,487 @@
+package plan
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// FormatDXF - вихідний файл креслення ASCII DXF (див. ExportDXF).
+const FormatDXF = "dxf"
+
+// dxfExportUnits - $INSUNITS і кількість таких одиниць у сантиметрі.
+var dxfExportUnits = map[string]struct {
+	code  int
+	perCM float64
+}{
+	"mm": {4, 10},
+	"cm": {5, 1},
+	"m":  {6, 0.01},
+}
+
+// DXFUnits - одиниці креслення для експорту.
+var DXFUnits = []string{"mm", "cm", "m"}
+
+// dxfCircleSteps - кількість відрізків для кола чи еліпса, які не можна записати як CIRCLE.
+const dxfCircleSteps = 36
+
+// dxfColors - кольори AutoCAD (ACI), до яких зводяться кольори CSS.
+var dxfColors = []struct {
+	aci     int
+	r, g, b float64
+}{
+	{1, 255, 0, 0}, {2, 255, 255, 0}, {3, 0, 255, 0}, {4, 0, 255, 255},
+	{5, 0, 0, 255}, {6, 255, 0, 255}, {7, 0, 0, 0}, {7, 255, 255, 255},
+	{8, 128, 128, 128}, {9, 192, 192, 192},
+}
+
+// DXFExportOptions - параметри експорту в DXF.
+type DXFExportOptions struct {
+	// Units - одиниці креслення: mm, cm або m; порожній - cm
+	Units string `json:"units,omitempty"`
+	// UnitSize - сантиметрів в одиниці viewBox; 0 - 1 (як у наших планах)
+	UnitSize float64 `json:"unit_size,omitempty"`
+}
+
+// ExportDXF записує геометрію плану в ASCII DXF (R12, AC1009): трансформації розкриваються,
+// кожен CSS-клас стає шаром (колір шару - з stroke чи fill класу), <line> - LINE, полігони, прямокутники,
+// контури <path> - POLYLINE, кола - CIRCLE, тексти - TEXT (висота - висота великих літер),
+// символи <symbol> - блоки, а <use> - їхні вставки INSERT. Вісь y перевертається, початок креслення -
+// лівий нижній кут viewBox. Заливки не переносяться.
+func ExportDXF(ctx context.Context, w io.Writer, d *Document, opts DXFExportOptions) error {
+	if opts.Units == "" {
+		opts.Units = "cm"
+	}
+	units, ok := dxfExportUnits[opts.Units]
+	if !ok {
+		return Errorf("невідомі одиниці DXF %q (%s)", opts.Units, strings.Join(DXFUnits, ", "))
+	}
+	if opts.UnitSize <= 0 {
+		opts.UnitSize = 1
+	}
+	vx, vy, vw, vh, ok := parseViewBox(d.SVG)
+	if !ok {
+		return Errorf("SVG не має коректного viewBox або width/height")
+	}
+	k := opts.UnitSize * units.perCM
+
+	e := &dxfExporter{styles: parseClassStyles(d.SVG), symbols: make(map[string]*html.Node), layerColors: make(map[string]int)}
+	traverse(d.SVG, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "symbol" && getAttr(n, "id") != "" {
+			e.symbols[getAttr(n, "id")] = n
+			return true
+		}
+		return false
+	})
+	e.layer("0", nil)
+	// Координати viewBox → креслення: масштаб k, вісь y вгору від нижнього краю viewBox
+	e.element(d.SVG, matrix{k, 0, 0, -k, -k * vx, k * (vy + vh)}, "0", &e.entities)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	out := &dxfOut{w: bw}
+	out.pair(0, "SECTION")
+	out.pair(2, "HEADER")
+	out.pair(9, "$ACADVER")
+	out.pair(1, "AC1009")
+	out.pair(9, "$INSUNITS")
+	out.pair(70, strconv.Itoa(units.code))
+	out.pair(9, "$EXTMIN")
+	out.point(0, point{0, 0})
+	out.pair(9, "$EXTMAX")
+	out.point(0, point{vw * k, vh * k})
+	out.pair(0, "ENDSEC")
+
+	out.pair(0, "SECTION")
+	out.pair(2, "TABLES")
+	out.table("LTYPE", 1, func() {
+		out.pair(0, "LTYPE")
+		out.pair(2, "CONTINUOUS")
+		out.pair(70, "0")
+		out.pair(3, "Solid line")
+		out.pair(72, "65")
+		out.pair(73, "0")
+		out.pair(40, "0.0")
+	})
+	out.table("LAYER", len(e.layers), func() {
+		for _, name := range e.layers {
+			out.pair(0, "LAYER")
+			out.pair(2, name)
+			out.pair(70, "0")
+			out.pair(62, strconv.Itoa(e.layerColors[name]))
+			out.pair(6, "CONTINUOUS")
+		}
+	})
+	out.table("STYLE", 1, func() {
+		out.pair(0, "STYLE")
+		out.pair(2, "STANDARD")
+		out.pair(70, "0")
+		out.pair(40, "0.0")
+		out.pair(41, "1.0")
+		out.pair(50, "0.0")
+		out.pair(71, "0")
+		out.pair(42, "2.5")
+		out.pair(3, "txt")
+		out.pair(4, "")
+	})
+	out.pair(0, "ENDSEC")
+
+	out.pair(0, "SECTION")
+	out.pair(2, "BLOCKS")
+	for _, b := range e.blocks {
+		out.pair(0, "BLOCK")
+		out.pair(8, "0")
+		out.pair(2, b.name)
+		out.pair(70, "0")
+		out.point(0, point{})
+		out.pair(3, b.name)
+		out.raw(b.entities)
+		out.pair(0, "ENDBLK")
+		out.pair(8, "0")
+	}
+	out.pair(0, "ENDSEC")
+
+	out.pair(0, "SECTION")
+	out.pair(2, "ENTITIES")
+	out.raw(e.entities)
+	out.pair(0, "ENDSEC")
+	out.pair(0, "EOF")
+	if err := bw.Flush(); err != nil {
+		return Errorf("%w DXF: %w", ErrWrite, err)
+	}
+	return nil
+}
+
+// dxfOut записує пари код-значення DXF.
+type dxfOut struct {
+	w io.Writer
+}
+
+// pair записує одну пару.
+func (o *dxfOut) pair(code int, val string) {
+	fmt.Fprintf(o.w, "%3d\n%s\n", code, val)
+}
+
+// point записує точку з кодами 10+i, 20+i, 30+i.
+func (o *dxfOut) point(i int, p point) {
+	o.pair(10+i, dxfNumber(p.X))
+	o.pair(20+i, dxfNumber(p.Y))
+	o.pair(30+i, "0.0")
+}
+
+// table записує таблицю з count записами.
+func (o *dxfOut) table(name string, count int, entries func()) {
+	o.pair(0, "TABLE")
+	o.pair(2, name)
+	o.pair(70, strconv.Itoa(count))
+	entries()
+	o.pair(0, "ENDTAB")
+}
+
+// raw записує вже сформовані пари.
+func (o *dxfOut) raw(pairs []dxfPair) {
+	for _, p := range pairs {
+		o.pair(p.code, p.val)
+	}
+}
+
+// dxfBlockOut - блок для символу.
+type dxfBlockOut struct {
+	name     string
+	entities []dxfPair
+}
+
+// dxfExporter збирає шари, блоки й об'єкти креслення.
+type dxfExporter struct {
+	styles      classStyles
+	symbols     map[string]*html.Node
+	layers      []string
+	layerColors map[string]int
+	blocks      []dxfBlockOut
+	blockIDs    map[string]bool
+	entities    []dxfPair
+}
+
+// layer реєструє шар і повертає його назву; колір шару береться з першого елемента з цим класом.
+func (e *dxfExporter) layer(name string, n *html.Node) string {
+	name = dxfName(name)
+	if _, ok := e.layerColors[name]; !ok {
+		e.layers = append(e.layers, name)
+		color := 7
+		if n != nil {
+			if c := e.color(n); c != 0 {
+				color = c
+			}
+		}
+		e.layerColors[name] = color
+	}
+	return name
+}
+
+// color зводить stroke (або fill, якщо stroke немає) елемента до кольору ACI (0 - колір не задано).
+func (e *dxfExporter) color(n *html.Node) int {
+	c := e.styles.property(n, "stroke")
+	if c == "" || c == "none" {
+		c = e.styles.property(n, "fill")
+	}
+	return aciColor(c)
+}
+
+// element записує елемент і його нащадків; m - перетворення батька в координати креслення (чи блоку),
+// layer - шар найближчого предка з класом.
+func (e *dxfExporter) element(n *html.Node, m matrix, layer string, out *[]dxfPair) {
+	if n.Type != html.ElementNode {
+		return
+	}
+	switch n.Data {
+	case "defs", "symbol", "style", "title", "desc", "metadata", "image":
+		return
+	}
+	if e.styles.property(n, "display") == "none" {
+		return
+	}
+	if t := getAttr(n, "transform"); t != "" && n.Data != "svg" {
+		m = m.mul(parseTransform(t))
+	}
+	if class := strings.Fields(getAttr(n, "class")); len(class) > 0 {
+		layer = e.layer(class[0], n)
+	}
+
+	add := func(pairs ...dxfPair) {
+		*out = append(*out, pairs...)
+	}
+	head := func(typ string) {
+		add(dxfPair{0, typ}, dxfPair{8, layer})
+		if c := e.color(n); c != 0 && c != e.layerColors[layer] {
+			add(dxfPair{62, strconv.Itoa(c)})
+		}
+	}
+	at := func(i int, p point) {
+		add(dxfPair{10 + i, dxfNumber(p.X)}, dxfPair{20 + i, dxfNumber(p.Y)}, dxfPair{30 + i, "0.0"})
+	}
+	poly := func(pts []point, closed bool) {
+		if len(pts) < 2 {
+			return
+		}
+		head("POLYLINE")
+		flags := "0"
+		if closed {
+			flags = "1"
+		}
+		add(dxfPair{66, "1"}, dxfPair{70, flags})
+		at(0, point{})
+		for _, p := range pts {
+			add(dxfPair{0, "VERTEX"}, dxfPair{8, layer})
+			at(0, m.apply(p))
+		}
+		add(dxfPair{0, "SEQEND"}, dxfPair{8, layer})
+	}
+	ellipse := func(c point, rx, ry float64) {
+		sx, sy := math.Hypot(m[0], m[1]), math.Hypot(m[2], m[3])
+		if rx == ry && math.Abs(sx-sy) < 1e-9 && math.Abs(m[0]*m[2]+m[1]*m[3]) < 1e-9 {
+			head("CIRCLE")
+			at(0, m.apply(c))
+			add(dxfPair{40, dxfNumber(rx * sx)})
+			return
+		}
+		pts := make([]point, dxfCircleSteps)
+		for i := range pts {
+			a := 2 * math.Pi * float64(i) / dxfCircleSteps
+			pts[i] = point{c.X + rx*math.Cos(a), c.Y + ry*math.Sin(a)}
+		}
+		poly(pts, true)
+	}
+
+	switch n.Data {
+	case "line":
+		head("LINE")
+		at(0, m.apply(point{attrFloat(n, "x1"), attrFloat(n, "y1")}))
+		at(1, m.apply(point{attrFloat(n, "x2"), attrFloat(n, "y2")}))
+	case "polyline", "polygon":
+		pts, _ := parsePoints(getAttr(n, "points"))
+		poly(pts, n.Data == "polygon")
+	case "rect":
+		x, y, w, h := attrFloat(n, "x"), attrFloat(n, "y"), attrFloat(n, "width"), attrFloat(n, "height")
+		poly([]point{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}}, true)
+	case "circle":
+		r := attrFloat(n, "r")
+		ellipse(point{attrFloat(n, "cx"), attrFloat(n, "cy")}, r, r)
+	case "ellipse":
+		ellipse(point{attrFloat(n, "cx"), attrFloat(n, "cy")}, attrFloat(n, "rx"), attrFloat(n, "ry"))
+	case "path":
+		for _, part := range flattenPath(getAttr(n, "d")) {
+			poly(part.pts, part.closed)
+		}
+	case "text":
+		e.text(n, m, head, at, add)
+		return
+	case "use":
+		e.insert(n, m, head, at, add)
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		e.element(c, m, layer, out)
+	}
+}
+
+// text записує TEXT: висота - висота великих літер (0.7 розміру шрифту), вирівнювання - з text-anchor.
+func (e *dxfExporter) text(n *html.Node, m matrix, head func(string), at func(int, point), add func(...dxfPair)) {
+	s := textContent(n)
+	if s == "" {
+		return
+	}
+	p := m.apply(point{attrFloat(n, "x"), attrFloat(n, "y")})
+	scale := math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+	head("TEXT")
+	at(0, p)
+	add(dxfPair{40, dxfNumber(e.styles.fontSize(n) * 0.7 * scale)}, dxfPair{1, dxfText(s)})
+	if rot := math.Atan2(m[1], m[0]) * 180 / math.Pi; math.Abs(rot) > 0.01 {
+		add(dxfPair{50, dxfNumber(rot)})
+	}
+	add(dxfPair{7, "STANDARD"})
+	align := map[string]string{"middle": "1", "end": "2"}[e.styles.property(n, "text-anchor")]
+	if align != "" {
+		add(dxfPair{72, align})
+		at(1, p)
+	}
+}
+
+// insert записує вставку блоку символу, на який посилається <use>, створюючи блок при першому використанні.
+// Символ вписується в x, y, width, height зі збереженням пропорцій (preserveAspectRatio за замовчуванням).
+func (e *dxfExporter) insert(n *html.Node, m matrix, head func(string), at func(int, point), add func(...dxfPair)) {
+	symbol := e.symbols[useHref(n)]
+	if symbol == nil {
+		return
+	}
+	name := e.block(symbol)
+	bx, by, bw, bh, ok := parseViewBox(symbol)
+	if !ok || bw <= 0 || bh <= 0 {
+		bx, by, bw, bh = 0, 0, attrFloat(n, "width"), attrFloat(n, "height")
+	}
+	w, h := attrFloat(n, "width"), attrFloat(n, "height")
+	if w <= 0 || h <= 0 {
+		w, h = bw, bh
+	}
+	s := 1.0
+	if bw > 0 && bh > 0 {
+		s = math.Min(w/bw, h/bh)
+	}
+	dx, dy := (w-bw*s)/2, (h-bh*s)/2
+	// Координати блоку (див. block) → символ → <use> → креслення
+	toSymbol, _ := dxfBlockMatrix(bx, by).invert()
+	l := m.mul(matrix{1, 0, 0, 1, attrFloat(n, "x") + dx, attrFloat(n, "y") + dy}).
+		mul(matrix{s, 0, 0, s, 0, 0}).
+		mul(matrix{1, 0, 0, 1, -bx, -by}).
+		mul(toSymbol)
+
+	sx := math.Hypot(l[0], l[1])
+	if sx == 0 {
+		return
+	}
+	head("INSERT")
+	add(dxfPair{2, name})
+	at(0, point{l[4], l[5]})
+	add(dxfPair{41, dxfNumber(sx)}, dxfPair{42, dxfNumber((l[0]*l[3] - l[1]*l[2]) / sx)})
+	if rot := math.Atan2(l[1], l[0]) * 180 / math.Pi; math.Abs(rot) > 0.01 {
+		add(dxfPair{50, dxfNumber(rot)})
+	}
+}
+
+// dxfBlockMatrix переводить координати символу в координати блоку: початок - лівий верхній кут
+// viewBox символу, вісь y - вгору.
+func dxfBlockMatrix(bx, by float64) matrix {
+	return matrix{1, 0, 0, -1, -bx, by}
+}
+
+// block повертає назву блоку символу, записуючи його визначення при першому зверненні.
+// Об'єкти блоку лежать на шарі 0, тож у CAD успадковують шар вставки.
+func (e *dxfExporter) block(symbol *html.Node) string {
+	name := dxfName(getAttr(symbol, "id"))
+	if e.blockIDs == nil {
+		e.blockIDs = make(map[string]bool)
+	}
+	if e.blockIDs[name] {
+		return name
+	}
+	e.blockIDs[name] = true
+	bx, by, _, _, _ := parseViewBox(symbol)
+	var entities []dxfPair
+	for c := symbol.FirstChild; c != nil; c = c.NextSibling {
+		e.element(c, dxfBlockMatrix(bx, by), "0", &entities)
+	}
+	e.blocks = append(e.blocks, dxfBlockOut{name, entities})
+	return name
+}
+
+// dxfNumber форматує число для DXF (до мільйонних).
+func dxfNumber(v float64) string {
+	s := strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// dxfText екранує текст для DXF R12: символи поза ASCII записуються як \U+XXXX.
+func dxfText(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r < 0x80:
+			sb.WriteRune(r)
+		case r <= 0xFFFF:
+			fmt.Fprintf(&sb, `\U+%04X`, r)
+		}
+	}
+	return sb.String()
+}
+
+// dxfName замінює символи, недопустимі в назвах шарів і блоків DXF.
+func dxfName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>/\":;?*|=,`+"`", r) || r <= ' ' {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// aciColor повертає найближчий колір ACI для кольору CSS (#rgb, #rrggbb або назви); 0 - колір не задано.
+func aciColor(css string) int {
+	css = strings.ToLower(strings.TrimSpace(css))
+	named := map[string]string{
+		"black": "#000000", "white": "#ffffff", "red": "#ff0000", "green": "#008000", "lime": "#00ff00",
+		"blue": "#0000ff", "yellow": "#ffff00", "cyan": "#00ffff", "aqua": "#00ffff", "magenta": "#ff00ff",
+		"fuchsia": "#ff00ff", "gray": "#808080", "grey": "#808080", "silver": "#c0c0c0", "orange": "#ffa500",
+	}
+	if hex, ok := named[css]; ok {
+		css = hex
+	}
+	if !strings.HasPrefix(css, "#") {
+		return 0
+	}
+	hex := css[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return 0
+	}
+	r, g, b := float64(v>>16&0xFF), float64(v>>8&0xFF), float64(v&0xFF)
+	best, bestDist := 7, math.Inf(1)
+	for _, c := range dxfColors {
+		if d := (r-c.r)*(r-c.r) + (g-c.g)*(g-c.g) + (b-c.b)*(b-c.b); d < bestDist {
+			best, bestDist = c.aci, d
+		}
+	}
+	return best
+}
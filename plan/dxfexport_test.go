@@ -0,0 +1,131 @@
+package plan
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// dxfExportPlan - план, контур якого відступає від краю viewBox на типове поле імпорту (20),
+// тож після експорту й імпорту координати мають збігтися.
+const dxfExportPlan = `<svg viewBox="0 0 400 300">
+	<style>
+		.outline { fill: #FFF; stroke: #000; stroke-width: 5; }
+		.wall { stroke: #000; stroke-width: 5; }
+		.doors { stroke: #FFF; stroke-width: 8; }
+		.arrow { fill: #000; }
+		.room-name { font-size: 20px; }
+		.door-number { font-size: 20px; fill: #00f; }
+	</style>
+	<defs><symbol id="exit-arrow" viewBox="0 0 10 10"><polygon points="0,0 10,5 0,10"/></symbol></defs>
+	<polygon class="outline" points="20,20 380,20 380,280 20,280"/>
+	<line class="wall" x1="200" y1="20" x2="200" y2="280"/>
+	<g transform="translate(0, 100)"><line class="wall" x1="20" y1="50" x2="200" y2="50"/></g>
+	<line class="doors" x1="200" y1="60" x2="200" y2="100"/>
+	<use class="arrow" href="#exit-arrow" x="300" y="250" width="20" height="20"/>
+	<g id="room-numbers">
+		<text class="room-name" x="50" y="100">Кухня</text>
+		<text class="room-name" x="290" y="100" text-anchor="middle">Зала «Ї»</text>
+		<text class="door-number" x="210" y="85">12</text>
+	</g>
+</svg>`
+
+// exportDXF експортує план з тесту в DXF.
+func exportDXF(t *testing.T, d *Document, opts DXFExportOptions) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := ExportDXF(context.Background(), &buf, d, opts); err != nil {
+		t.Fatalf("експорт DXF: %v", err)
+	}
+	return buf.String()
+}
+
+func TestExportDXFRoundTrip(t *testing.T) {
+	src := exportDXF(t, parseSVG(t, dxfExportPlan), DXFExportOptions{})
+	d, layers := importDXF(t, src, DXFOptions{})
+
+	want := map[string]DXFLayer{
+		"outline":     {Name: "outline", Class: "outline", Entities: 1},
+		"wall":        {Name: "wall", Class: "wall", Entities: 2},
+		"doors":       {Name: "doors", Class: "doors", Entities: 1},
+		"arrow":       {Name: "arrow", Class: "arrow", Entities: 1},
+		"room-name":   {Name: "room-name", Class: "room-name", Entities: 2},
+		"door-number": {Name: "door-number", Class: "door-number", Entities: 1},
+	}
+	if len(layers) != len(want) {
+		t.Errorf("шари після імпорту: %+v", layers)
+	}
+	for name, w := range want {
+		if layers[name] != w {
+			t.Errorf("шар %s: %+v, очікувалось %+v", name, layers[name], w)
+		}
+	}
+	counts := []struct {
+		tag, class string
+		n          int
+	}{
+		{"polygon", "outline", 1},
+		// Символ стрілки розгортається з блоку на шар вставки
+		{"polygon", "arrow", 1},
+		{"line", "wall", 2},
+		{"line", "doors", 1},
+		{"text", "room-name", 2},
+		{"text", "door-number", 1},
+	}
+	for _, c := range counts {
+		if n := classCount(d, c.tag, c.class); n != c.n {
+			t.Errorf("%s.%s: %d, очікувалось %d", c.tag, c.class, n, c.n)
+		}
+	}
+
+	// Перевернута двічі вісь y і поле імпорту повертають координати на місце, трансформація групи розкрита
+	var got []string
+	for _, n := range elementsByTag(d, "line") {
+		if hasClass(n, "wall") {
+			got = append(got, getAttr(n, "x1")+","+getAttr(n, "y1")+" "+getAttr(n, "x2")+","+getAttr(n, "y2"))
+		}
+	}
+	if strings.Join(got, "; ") != "200,20 200,280; 20,150 200,150" {
+		t.Errorf("стіни після імпорту: %v", got)
+	}
+	texts := elementsByTag(d, "text")
+	if getAttr(texts[1], "x") != "290" || getAttr(texts[1], "y") != "100" || getAttr(texts[1], "text-anchor") != "middle" {
+		t.Errorf("центрований підпис: x=%s y=%s text-anchor=%q", getAttr(texts[1], "x"), getAttr(texts[1], "y"), getAttr(texts[1], "text-anchor"))
+	}
+}
+
+func TestExportDXFText(t *testing.T) {
+	src := exportDXF(t, parseSVG(t, dxfExportPlan), DXFExportOptions{})
+	for i := 0; i < len(src); i++ {
+		if src[i] >= 0x80 {
+			t.Fatalf("DXF R12 має бути в ASCII, байт %#x у позиції %d", src[i], i)
+		}
+	}
+	for _, want := range []string{`\U+041A\U+0443\U+0445\U+043D\U+044F`, `\U+0417\U+0430\U+043B\U+0430 \U+00AB\U+0407\U+00BB`} {
+		if !strings.Contains(src, "\n"+want+"\n") {
+			t.Errorf("немає екранованого тексту %s", want)
+		}
+	}
+
+	d, _ := importDXF(t, src, DXFOptions{})
+	var got []string
+	for _, n := range elementsByTag(d, "text") {
+		got = append(got, textContent(n))
+	}
+	if strings.Join(got, "|") != "Кухня|Зала «Ї»|12" {
+		t.Errorf("тексти після імпорту: %q", got)
+	}
+}
+
+func TestExportDXFUnits(t *testing.T) {
+	d := parseSVG(t, dxfExportPlan)
+	src := exportDXF(t, d, DXFExportOptions{Units: "mm", UnitSize: 2})
+	// 400 одиниць viewBox по 2 см - 8000 мм
+	if !strings.Contains(src, "$INSUNITS\n 70\n4\n") || !strings.Contains(src, "$EXTMAX\n 10\n8000.0\n 20\n6000.0\n") {
+		t.Errorf("заголовок DXF у міліметрах:\n%s", src[:min(len(src), 300)])
+	}
+	if err := ExportDXF(context.Background(), &bytes.Buffer{}, d, DXFExportOptions{Units: "ft"}); err == nil {
+		t.Error("невідомі одиниці мають повертати помилку")
+	}
+}
@@ -0,0 +1,46 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Категорії помилок обробки плану. Функції пакета загортають їх разом з причиною
+// (Errorf з %w), тож категорію можна перевірити через errors.Is, а причину - через errors.As.
+var (
+	// ErrNoSVG - у документі немає елемента <svg> (чи його не знайдено за селектором).
+	ErrNoSVG = errors.New("не вдалося знайти тег <svg> у HTML-документі")
+	// ErrParse - вхідні дані (HTML, SVG, JSON, шаблон) не вдалося розібрати.
+	ErrParse = errors.New("помилка парсингу")
+	// ErrWrite - не вдалося записати результат.
+	ErrWrite = errors.New("помилка запису")
+	// ErrRendererMissing - потрібний рендерер (rsvg-convert) не встановлено.
+	ErrRendererMissing = errors.New("рендерер недоступний")
+)
+
+// Error - помилка, що зберігає шаблон повідомлення українською та аргументи окремо.
+// Шаблон слугує ключем перекладу: інша мова підставляє ті самі аргументи у свій шаблон.
+type Error struct {
+	Format string
+	Args   []any
+}
+
+// Errorf створює Error; як і fmt.Errorf, підтримує %w.
+func Errorf(format string, args ...any) error {
+	return &Error{Format: format, Args: args}
+}
+
+func (e *Error) Error() string {
+	return fmt.Errorf(e.Format, e.Args...).Error()
+}
+
+// Unwrap повертає помилки, підставлені через %w.
+func (e *Error) Unwrap() []error {
+	switch err := fmt.Errorf(e.Format, e.Args...).(type) {
+	case interface{ Unwrap() error }:
+		return []error{err.Unwrap()}
+	case interface{ Unwrap() []error }:
+		return err.Unwrap()
+	}
+	return nil
+}
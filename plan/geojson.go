@@ -0,0 +1,601 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"golang.org/x/net/html"
+)
+
+// FormatGeoJSON - формат експорту GeoJSON (RFC 7946).
+const FormatGeoJSON = "geojson"
+
+// earthRadius - радіус Землі (WGS 84) для переведення метрів у градуси.
+const earthRadius = 6378137.0
+
+// geoCell - крок сітки, на якій обводяться кімнати без контуру .room.
+const geoCell = 1.0
+
+// GeoReference - прив'язка плану до географічних координат: точка плану OriginX, OriginY
+// має координати Lat, Lon, план повернуто на Rotation, а одиниця viewBox дорівнює MetersPerUnit.
+type GeoReference struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+	// OriginX, OriginY - точка плану в координатах viewBox, що відповідає Lat, Lon
+	OriginX float64 `json:"origin_x,omitempty"`
+	OriginY float64 `json:"origin_y,omitempty"`
+	// Rotation - азимут осі "вгору" плану в градусах за годинниковою стрілкою від півночі
+	Rotation float64 `json:"rotation,omitempty"`
+	// MetersPerUnit - метрів в одиниці viewBox; 0 - DefaultMetersPerUnit
+	MetersPerUnit float64 `json:"meters_per_unit,omitempty"`
+}
+
+// FeatureCollection - колекція об'єктів GeoJSON.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature - об'єкт GeoJSON; FeatureType - тип об'єкта IMDF (див. IMDF).
+type Feature struct {
+	Type        string         `json:"type"`
+	ID          string         `json:"id,omitempty"`
+	FeatureType string         `json:"feature_type,omitempty"`
+	Geometry    *Geometry      `json:"geometry"`
+	Properties  map[string]any `json:"properties"`
+}
+
+// Geometry - геометрія GeoJSON: Point, LineString, Polygon або MultiPolygon.
+type Geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// Типи об'єктів плану у властивості "type".
+const (
+	featureRoom      = "room"
+	featureDoor      = "door"
+	featureExit      = "exit"
+	featureEquipment = "equipment"
+)
+
+// planFeature - об'єкт плану в координатах viewBox до географічної прив'язки.
+type planFeature struct {
+	kind    string
+	name    string
+	number  string
+	section int
+	symbol  string
+	// rings - контур кімнати (перше кільце) і отвори в ній
+	rings [][]point
+	// line - відрізок дверей
+	line []point
+	// at - точка обладнання або підпису кімнати без замкненого контуру
+	at point
+	// area - площа кімнати в одиницях viewBox
+	area float64
+	// shape - номер явного контуру кімнати (-1 - замкнена область чи без контуру), labels - підписи кімнати
+	shape  int
+	labels []*html.Node
+}
+
+// GeoJSON перетворює план на колекцію об'єктів GeoJSON: кімнати (Polygon з назвою і площею,
+// або Point, якщо підпис не лежить у замкненій кімнаті), двері й виходи (LineString з номером)
+// та обладнання (<use>, Point з назвою символу). Координати - довгота і широта WGS 84.
+func GeoJSON(d *Document, ref GeoReference) *FeatureCollection {
+	ref = ref.withDefaults()
+	fc := &FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+	counts := make(map[string]int)
+	for _, f := range planFeatures(d) {
+		counts[f.kind]++
+		props := map[string]any{"type": f.kind}
+		if f.name != "" {
+			props["name"] = f.name
+		}
+		geom := ref.geometry(f)
+		switch {
+		case len(f.rings) > 0:
+			area := f.area * ref.MetersPerUnit * ref.MetersPerUnit
+			props["area_m2"] = math.Round(area*100) / 100
+		case len(f.line) > 0:
+			if f.number != "" {
+				props["door_number"] = f.number
+			}
+			props["section"] = f.section + 1
+		case f.symbol != "":
+			props["symbol"] = f.symbol
+		}
+		fc.Features = append(fc.Features, Feature{
+			Type:       "Feature",
+			ID:         fmt.Sprintf("%s-%d", f.kind, counts[f.kind]),
+			Geometry:   geom,
+			Properties: props,
+		})
+	}
+	return fc
+}
+
+// ExportGeoJSON записує план у форматі GeoJSON (див. GeoJSON).
+func ExportGeoJSON(ctx context.Context, w io.Writer, d *Document, ref GeoReference) error {
+	fc := GeoJSON(d, ref)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(fc); err != nil {
+		return Errorf("%w GeoJSON: %w", ErrWrite, err)
+	}
+	return nil
+}
+
+// withDefaults заповнює незадані параметри прив'язки значеннями за замовчуванням.
+func (r GeoReference) withDefaults() GeoReference {
+	if r.MetersPerUnit <= 0 {
+		r.MetersPerUnit = DefaultMetersPerUnit
+	}
+	return r
+}
+
+// geometry повертає геометрію об'єкта плану: Polygon кімнати, LineString дверей або Point.
+func (r GeoReference) geometry(f planFeature) *Geometry {
+	switch {
+	case len(f.rings) > 0:
+		return &Geometry{"Polygon", r.polygon(f.rings)}
+	case len(f.line) > 0:
+		line := make([][2]float64, len(f.line))
+		for i, p := range f.line {
+			line[i] = r.lonLat(p)
+		}
+		return &Geometry{"LineString", line}
+	}
+	return r.point(f.at)
+}
+
+// point повертає Point для точки плану.
+func (r GeoReference) point(p point) *Geometry {
+	return &Geometry{"Point", r.lonLat(p)}
+}
+
+// polygon переводить кільця полігону (перше - зовнішнє) в координати GeoJSON.
+func (r GeoReference) polygon(rings [][]point) [][][2]float64 {
+	out := make([][][2]float64, len(rings))
+	for i, ring := range rings {
+		out[i] = r.ring(ring, i == 0)
+	}
+	return out
+}
+
+// lonLat переводить точку плану в довготу і широту.
+func (r GeoReference) lonLat(p point) [2]float64 {
+	// Метри на схід і на північ до повороту: вісь y плану направлена вниз
+	e := (p.X - r.OriginX) * r.MetersPerUnit
+	n := -(p.Y - r.OriginY) * r.MetersPerUnit
+	sin, cos := math.Sincos(r.Rotation * math.Pi / 180)
+	east, north := e*cos+n*sin, n*cos-e*sin
+	lat := r.Lat + north/earthRadius*180/math.Pi
+	lon := r.Lon + east/(earthRadius*math.Cos(r.Lat*math.Pi/180))*180/math.Pi
+	return [2]float64{roundCoord(lon), roundCoord(lat)}
+}
+
+// ring переводить кільце полігону в довготу і широту, замикає його й орієнтує за RFC 7946:
+// зовнішнє кільце - проти годинникової стрілки, отвори - за нею.
+func (r GeoReference) ring(pts []point, exterior bool) [][2]float64 {
+	out := make([][2]float64, 0, len(pts)+1)
+	for _, p := range pts {
+		out = append(out, r.lonLat(p))
+	}
+	out = append(out, out[0])
+	var sum float64
+	for i := 0; i+1 < len(out); i++ {
+		sum += out[i][0]*out[i+1][1] - out[i+1][0]*out[i][1]
+	}
+	if (sum > 0) != exterior {
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+	return out
+}
+
+// roundCoord округлює градуси до 1e-8 (близько міліметра).
+func roundCoord(v float64) float64 {
+	return math.Round(v*1e8) / 1e8
+}
+
+// planFeatures збирає кімнати, двері, виходи й обладнання плану (без легенди й титульного блоку).
+// Кімната підпису .room-name - найменший контур polygon/rect.room, що його містить, а без такого -
+// замкнена область плану (див. roomIndex). Кілька підписів в одній кімнаті об'єднуються в назву через "; ".
+func planFeatures(d *Document) []planFeature {
+	svg := d.SVG
+	styles := parseClassStyles(svg)
+
+	var shapes [][]point
+	var labels, uses []*html.Node
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		if n.Data == "defs" || n.Data == "symbol" || getAttr(n, "id") == "legend" || getAttr(n, "id") == "title-block" {
+			return true
+		}
+		switch {
+		case n.Data == "polygon" && hasClass(n, "room"):
+			shapes = append(shapes, polygonPoints(n))
+		case n.Data == "rect" && hasClass(n, "room"):
+			shapes = append(shapes, rectPoints(n))
+		case n.Data == "text" && hasClass(n, "room-name"):
+			labels = append(labels, n)
+		case n.Data == "use":
+			uses = append(uses, n)
+		}
+		return false
+	})
+
+	out := roomFeatures(labels, shapes, styles, newRoomIndex(svg))
+
+	doors := planDoors(svg, DoorNumbering{DoorGap: defaultDoorGap})
+	_ = orderDoors(doors, DoorOrderLTR)
+	owner := matchDoorLabels(doors, labelsByPriority(svg, []string{"door-number"}), styles, defaultDoorLabelRadius)
+	exits := exitDoors(svg)
+	for i, dr := range doors {
+		f := planFeature{kind: featureDoor, section: dr.section, line: []point{dr.seg.A, dr.seg.B}}
+		for _, e := range exits {
+			if e == dr.seg {
+				f.kind = featureExit
+				break
+			}
+		}
+		if owner[i] != nil {
+			f.number = textContent(owner[i])
+		}
+		out = append(out, f)
+	}
+
+	symbols := make(map[string]*html.Node)
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "symbol" {
+			symbols[getAttr(n, "id")] = n
+		}
+		return false
+	})
+	for _, n := range uses {
+		id := useHref(n)
+		x, y := attrFloat(n, "x"), attrFloat(n, "y")
+		w, h := attrFloat(n, "width"), attrFloat(n, "height")
+		f := planFeature{kind: featureEquipment, symbol: id, at: nodeTransform(n).apply(point{x + w/2, y + h/2})}
+		if symbol := symbols[id]; symbol != nil {
+			f.name = symbolLabel(symbol, id, nil)
+		} else {
+			f.name = DefaultSymbolNames[id]
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// roomFeatures зіставляє підписи кімнат з кімнатами: найменшим контуром shapes, що містить центр
+// підпису, а без такого - замкненою областю index. Підписи однієї кімнати об'єднуються в назву через "; ",
+// підпис поза замкненими областями дає кімнату без контуру.
+func roomFeatures(labels []*html.Node, shapes [][]point, styles classStyles, index *roomIndex) []planFeature {
+	var out []planFeature
+	rooms := make(map[string]int)
+	for _, n := range labels {
+		name := textContent(n)
+		c := textBox(n, styles).center()
+		best := -1
+		for i, s := range shapes {
+			if len(s) >= 3 && pointInPolygon(c, s) && (best < 0 || polygonArea(s) < polygonArea(shapes[best])) {
+				best = i
+			}
+		}
+		var key string
+		f := planFeature{kind: featureRoom, name: name, at: c, shape: best, labels: []*html.Node{n}}
+		if best >= 0 {
+			key = fmt.Sprintf("shape %d", best)
+			f.rings, f.area = [][]point{shapes[best]}, polygonArea(shapes[best])
+		} else if id, ok := index.at(c); ok {
+			key = fmt.Sprintf("grid %d", id)
+			f.rings, f.area = index.outline(id), index.area(id)
+		}
+		if key == "" {
+			out = append(out, f)
+			continue
+		}
+		if i, ok := rooms[key]; ok {
+			out[i].name += "; " + name
+			out[i].labels = append(out[i].labels, n)
+			continue
+		}
+		rooms[key] = len(out)
+		out = append(out, f)
+	}
+	return out
+}
+
+// roomIndex - замкнені області плану, обмежені стінами, контурами .outline і зачиненими дверима
+// (як у PlaceRoomLabels), на сітці з кроком geoCell. Межа області проходить по внутрішніх гранях стін.
+// Області визначаються лише для точок, про які питають.
+type roomIndex struct {
+	g       *grid
+	region  []int
+	regions [][]int
+	closed  []bool
+
+	// Відрізки, якими заблоковано сітку, і половина товщини стіни: по них межа вирівнюється на грані
+	walls     []segment
+	clearance float64
+}
+
+// newRoomIndex будує сітку замкнених областей плану.
+func newRoomIndex(svg *html.Node) *roomIndex {
+	styles := parseClassStyles(svg)
+	wallWidth := 0.0
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && isWall(n) {
+			wallWidth = max(wallWidth, styles.strokeWidth(n))
+		}
+		return false
+	})
+	// Заблоковано півтовщини стіни, тож вільні комірки починаються від її грані
+	clearance := max(wallWidth/2, geoCell/2)
+	g, walls, doors := wallGrid(svg, geoCell, clearance)
+	closedDoors := append(doors, wallGaps(svg, defaultDoorGap)...)
+	for _, s := range closedDoors {
+		g.markSegment(s, clearance, true)
+	}
+	x := &roomIndex{g: g, region: make([]int, g.w*g.h), walls: append(walls, closedDoors...), clearance: clearance}
+	for k := range x.region {
+		x.region[k] = -1
+	}
+	return x
+}
+
+// at повертає номер замкненої області, в якій лежить точка.
+func (x *roomIndex) at(p point) (int, bool) {
+	i, j, ok := x.g.nearestFree(p, 3)
+	if !ok {
+		return 0, false
+	}
+	k := j*x.g.w + i
+	if x.region[k] < 0 {
+		cells, open := x.g.flood(k, x.region, len(x.regions))
+		x.regions = append(x.regions, cells)
+		x.closed = append(x.closed, !open)
+	}
+	id := x.region[k]
+	return id, x.closed[id]
+}
+
+// outline повертає контур області з отворами. Ребра контуру з сітки переносяться на грані стін,
+// уздовж яких вони проходять, а вершини - на перетини сусідніх ребер, тож межа не залежить від кроку сітки.
+func (x *roomIndex) outline(id int) [][]point {
+	rings := x.g.outline(x.regions[id], x.region, id)
+	for i, ring := range rings {
+		rings[i] = x.snapRing(ring)
+	}
+	return rings
+}
+
+// snapRing переносить кожне ребро кільця на грань стіни, паралельної йому і віддаленої
+// від нього не більше ніж на комірку. Ребра без такої стіни лишаються на місці.
+func (x *roomIndex) snapRing(ring []point) []point {
+	type line struct{ p, dir point }
+	lines := make([]line, len(ring))
+	for i, a := range ring {
+		b := ring[(i+1)%len(ring)]
+		l := dist(a, b)
+		lines[i] = line{a, point{(b.X - a.X) / l, (b.Y - a.Y) / l}}
+		if l < 2*x.g.cell {
+			continue
+		}
+		mid := point{(a.X + b.X) / 2, (a.Y + b.Y) / 2}
+		best := x.g.cell
+		for _, w := range x.walls {
+			wl := w.length()
+			if wl == 0 {
+				continue
+			}
+			dir := point{(w.B.X - w.A.X) / wl, (w.B.Y - w.A.Y) / wl}
+			if math.Abs(dir.X*lines[i].dir.Y-dir.Y*lines[i].dir.X) > 0.05 {
+				continue
+			}
+			if t := w.project(mid); t < -x.clearance-x.g.cell || t > wl+x.clearance+x.g.cell {
+				continue
+			}
+			d := w.distToLine(mid)
+			if off := math.Abs(d - x.clearance); off <= best {
+				best = off
+				// Нормаль стіни в бік ребра
+				n := point{-dir.Y, dir.X}
+				if (mid.X-w.A.X)*n.X+(mid.Y-w.A.Y)*n.Y < 0 {
+					n = point{dir.Y, -dir.X}
+				}
+				lines[i].p = point{w.A.X + n.X*x.clearance, w.A.Y + n.Y*x.clearance}
+				if dir.X*lines[i].dir.X+dir.Y*lines[i].dir.Y < 0 {
+					dir = point{-dir.X, -dir.Y}
+				}
+				lines[i].dir = dir
+			}
+		}
+	}
+
+	out := make([]point, len(ring))
+	for i, v := range ring {
+		prev, cur := lines[(i+len(ring)-1)%len(ring)], lines[i]
+		cross := prev.dir.X*cur.dir.Y - prev.dir.Y*cur.dir.X
+		if math.Abs(cross) < 1e-9 {
+			out[i] = v
+			continue
+		}
+		t := ((cur.p.X-prev.p.X)*cur.dir.Y - (cur.p.Y-prev.p.Y)*cur.dir.X) / cross
+		p := point{prev.p.X + t*prev.dir.X, prev.p.Y + t*prev.dir.Y}
+		// Майже паралельні ребра дають далекий перетин - лишаємо вершину сітки
+		if dist(p, v) > 3*x.g.cell {
+			p = v
+		}
+		out[i] = p
+	}
+	return out
+}
+
+// area повертає площу області в одиницях viewBox: площу зовнішнього кільця за формулою
+// шнурування без площ отворів.
+func (x *roomIndex) area(id int) float64 {
+	rings := x.outline(id)
+	area := polygonArea(rings[0])
+	for _, hole := range rings[1:] {
+		area -= polygonArea(hole)
+	}
+	return area
+}
+
+// outline обводить межу області id (комірки cells, позначені в room) по краях комірок:
+// перше кільце - зовнішній контур, решта - отвори (колони, шахти). Вершини на одній прямій
+// відкидаються, а сходинки комірок уздовж похилих стін спрямляються.
+func (g *grid) outline(cells []int, room []int, id int) [][]point {
+	in := func(i, j int) bool {
+		return i >= 0 && j >= 0 && i < g.w && j < g.h && room[j*g.w+i] == id
+	}
+	// Ребра межі обходять кімнату за годинниковою стрілкою на екрані (всередині - праворуч)
+	type corner struct{ i, j int }
+	type edge struct{ from, to corner }
+	next := make(map[corner][]edge)
+	var all []edge
+	add := func(a, b corner) {
+		e := edge{a, b}
+		next[a] = append(next[a], e)
+		all = append(all, e)
+	}
+	for _, k := range cells {
+		i, j := k%g.w, k/g.w
+		if !in(i, j-1) {
+			add(corner{i, j}, corner{i + 1, j})
+		}
+		if !in(i+1, j) {
+			add(corner{i + 1, j}, corner{i + 1, j + 1})
+		}
+		if !in(i, j+1) {
+			add(corner{i + 1, j + 1}, corner{i, j + 1})
+		}
+		if !in(i-1, j) {
+			add(corner{i, j + 1}, corner{i, j})
+		}
+	}
+
+	used := make(map[edge]bool)
+	var rings [][]point
+	var areas []float64
+	for _, start := range all {
+		if used[start] {
+			continue
+		}
+		var ring []corner
+		for e := start; !used[e]; {
+			used[e] = true
+			ring = append(ring, e.from)
+			dx, dy := e.to.i-e.from.i, e.to.j-e.from.j
+			// У точці дотику двох кутів повертаємо всередину кімнати, щоб кільця не перетиналися
+			cands := next[e.to]
+			e = cands[0]
+			for _, c := range cands {
+				if c.to.i-c.from.i == -dy && c.to.j-c.from.j == dx {
+					e = c
+				}
+			}
+		}
+		var pts []point
+		for i, c := range ring {
+			prev, nxt := ring[(i+len(ring)-1)%len(ring)], ring[(i+1)%len(ring)]
+			if (c.i-prev.i)*(nxt.j-c.j) == (c.j-prev.j)*(nxt.i-c.i) {
+				continue
+			}
+			pts = append(pts, point{g.x0 + float64(c.i)*g.cell, g.y0 + float64(c.j)*g.cell})
+		}
+		pts = simplifyRing(pts, g.cell)
+		var sum float64
+		for i, p := range pts {
+			q := pts[(i+1)%len(pts)]
+			sum += p.X*q.Y - q.X*p.Y
+		}
+		rings = append(rings, pts)
+		areas = append(areas, sum)
+	}
+
+	// Зовнішній контур - кільце з найбільшою площею, обійдене за годинниковою стрілкою
+	outer := 0
+	for i := range rings {
+		if areas[i] > areas[outer] {
+			outer = i
+		}
+	}
+	out := [][]point{rings[outer]}
+	for i, r := range rings {
+		if areas[i] < 0 {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// simplifyRing спрямляє замкнене кільце алгоритмом Дугласа-Пекера: вершини, що відхиляються
+// від хорди не більше ніж на tol, відкидаються. Опорні вершини - дві найвіддаленіші одна від одної.
+func simplifyRing(ring []point, tol float64) []point {
+	if len(ring) <= 4 {
+		return ring
+	}
+	far := func(from int) int {
+		best := from
+		for i, p := range ring {
+			if dist(p, ring[from]) > dist(ring[best], ring[from]) {
+				best = i
+			}
+		}
+		return best
+	}
+	a := far(0)
+	b := far(a)
+	if a > b {
+		a, b = b, a
+	}
+	keep := make([]bool, len(ring))
+	keep[a], keep[b] = true, true
+
+	// Вершини між i та j за ходом кільця (індекси беруться за модулем довжини)
+	var split func(i, j int)
+	split = func(i, j int) {
+		chord := segment{ring[i%len(ring)], ring[j%len(ring)]}
+		best, bestDist := -1, tol
+		for k := i + 1; k < j; k++ {
+			var d float64
+			if chord.length() == 0 {
+				d = dist(ring[k%len(ring)], chord.A)
+			} else {
+				d = chord.distToLine(ring[k%len(ring)])
+			}
+			if d > bestDist {
+				best, bestDist = k, d
+			}
+		}
+		if best < 0 {
+			return
+		}
+		keep[best%len(ring)] = true
+		split(i, best)
+		split(best, j)
+	}
+	split(a, b)
+	split(b, a+len(ring))
+
+	var out []point
+	for i, p := range ring {
+		if keep[i] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,145 @@
+package plan
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeoJSONDoorsAndExits(t *testing.T) {
+	fc := GeoJSON(loadPlan(t, "full.html"), GeoReference{})
+	kinds := make(map[string]int)
+	for _, f := range fc.Features {
+		kinds[f.Properties["type"].(string)]++
+	}
+	// На full.html виходи - лише двері без номера на межі будівлі: нижні в кожній секції і бічний
+	if kinds[featureExit] != 4 || kinds[featureDoor] != 11 {
+		t.Errorf("виходів %d, дверей %d; очікувалось 4 і 11", kinds[featureExit], kinds[featureDoor])
+	}
+}
+
+func TestGeoReferenceLonLat(t *testing.T) {
+	// 1 одиниця - 1 м; точка (100, 200) плану прив'язана до 50° пн. ш., 30° сх. д.
+	const lat, lon = 50.0, 30.0
+	dLat := 100 / earthRadius * 180 / math.Pi
+	dLon := dLat / math.Cos(lat*math.Pi/180)
+	cases := []struct {
+		name     string
+		rotation float64
+		p        point
+		want     [2]float64
+	}{
+		{"початок", 0, point{100, 200}, [2]float64{lon, lat}},
+		{"вгору без повороту - на північ", 0, point{100, 100}, [2]float64{lon, lat + dLat}},
+		{"праворуч без повороту - на схід", 0, point{200, 200}, [2]float64{lon + dLon, lat}},
+		{"вгору при повороті 90° - на схід", 90, point{100, 100}, [2]float64{lon + dLon, lat}},
+		{"праворуч при повороті 90° - на південь", 90, point{200, 200}, [2]float64{lon, lat - dLat}},
+		{"вгору при повороті 180° - на південь", 180, point{100, 100}, [2]float64{lon, lat - dLat}},
+		{"праворуч при повороті -90° - на північ", -90, point{200, 200}, [2]float64{lon, lat + dLat}},
+	}
+	for _, c := range cases {
+		ref := GeoReference{Lat: lat, Lon: lon, OriginX: 100, OriginY: 200, Rotation: c.rotation, MetersPerUnit: 1}
+		got := ref.lonLat(c.p)
+		if math.Abs(got[0]-c.want[0]) > 2e-8 || math.Abs(got[1]-c.want[1]) > 2e-8 {
+			t.Errorf("%s: %v, очікувалось %v", c.name, got, c.want)
+		}
+	}
+
+	// Без MetersPerUnit одиниця - сантиметр
+	got := GeoReference{Lat: lat, Lon: lon}.withDefaults().lonLat(point{0, -10000})
+	if math.Abs(got[1]-(lat+dLat)) > 2e-8 {
+		t.Errorf("100 м на північ за DefaultMetersPerUnit: %v", got)
+	}
+}
+
+// signedArea - орієнтована площа кільця GeoJSON (додатна - проти годинникової стрілки).
+func signedArea(ring [][2]float64) float64 {
+	var sum float64
+	for i := 0; i+1 < len(ring); i++ {
+		sum += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return sum / 2
+}
+
+// roomPolygons повертає кільця кімнат колекції у порядку об'єктів.
+func roomPolygons(t *testing.T, fc *FeatureCollection) [][][][2]float64 {
+	t.Helper()
+	var out [][][][2]float64
+	for _, f := range fc.Features {
+		if f.Properties["type"] != featureRoom {
+			continue
+		}
+		if f.Geometry.Type != "Polygon" {
+			t.Fatalf("кімната %v без контуру: %s", f.Properties["name"], f.Geometry.Type)
+		}
+		out = append(out, f.Geometry.Coordinates.([][][2]float64))
+	}
+	return out
+}
+
+func TestGeoJSONRingOrientation(t *testing.T) {
+	// Контури кімнат задано в обох напрямках обходу; план повернуто, що не має міняти орієнтацію
+	d := parseSVG(t, `<svg viewBox="0 0 400 200">
+		<style>.room-name { font-size: 10px; }</style>
+		<polygon class="room" points="0,0 100,0 100,100 0,100"/>
+		<polygon class="room" points="200,0 200,100 300,100 300,0"/>
+		<text class="room-name" x="30" y="50">кухня</text>
+		<text class="room-name" x="230" y="50">склад</text>
+	</svg>`)
+	for _, rotation := range []float64{0, 135} {
+		rooms := roomPolygons(t, GeoJSON(d, GeoReference{Lat: 50, Lon: 30, Rotation: rotation}))
+		if len(rooms) != 2 {
+			t.Fatalf("очікувалось 2 кімнати, отримано %d", len(rooms))
+		}
+		for i, rings := range rooms {
+			ring := rings[0]
+			if len(ring) != 5 || ring[0] != ring[len(ring)-1] {
+				t.Errorf("кімната %d: кільце не замкнене: %v", i, ring)
+			}
+			if signedArea(ring) <= 0 {
+				t.Errorf("поворот %v, кімната %d: зовнішнє кільце має йти проти годинникової стрілки", rotation, i)
+			}
+		}
+	}
+}
+
+func TestGeoJSONRoomWithHole(t *testing.T) {
+	// Кімната без контуру .room, обмежена стінами, з колоною посередині
+	d := parseSVG(t, `<svg viewBox="-10 -10 220 120">
+		<style>
+			.wall { stroke: #000; stroke-width: 4; }
+			.room-name { font-size: 10px; }
+		</style>
+		<polyline class="wall" points="0,0 200,0 200,100 0,100 0,0"/>
+		<polyline class="wall" points="90,40 110,40 110,60 90,60 90,40"/>
+		<text class="room-name" x="20" y="30">зал</text>
+	</svg>`)
+
+	index := newRoomIndex(d.SVG)
+	id, closed := index.at(point{30, 25})
+	if !closed {
+		t.Fatal("кімната між стінами має бути замкненою")
+	}
+	rings := index.outline(id)
+	if len(rings) != 2 {
+		t.Fatalf("очікувалось зовнішнє кільце й отвір колони, отримано %d кілець", len(rings))
+	}
+	// Межа проходить по внутрішніх гранях стін товщиною 4 (з точністю до комірки)
+	outer, hole := polygonBox(rings[0]), polygonBox(rings[1])
+	if math.Abs(outer.X-2) > 1 || math.Abs(outer.X+outer.W-198) > 1 || math.Abs(outer.Y-2) > 1 || math.Abs(outer.Y+outer.H-98) > 1 {
+		t.Errorf("зовнішній контур %+v", outer)
+	}
+	if math.Abs(hole.X-88) > 1 || math.Abs(hole.X+hole.W-112) > 1 || math.Abs(hole.Y-38) > 1 || math.Abs(hole.Y+hole.H-62) > 1 {
+		t.Errorf("отвір колони %+v", hole)
+	}
+	if want := outer.W*outer.H - hole.W*hole.H; math.Abs(index.area(id)-want) > 0.02*want {
+		t.Errorf("площа %v, очікувалось близько %v", index.area(id), want)
+	}
+
+	rooms := roomPolygons(t, GeoJSON(d, GeoReference{Lat: 50, Lon: 30}))
+	if len(rooms) != 1 || len(rooms[0]) != 2 {
+		t.Fatalf("очікувалась 1 кімната з отвором, отримано %v", rooms)
+	}
+	if signedArea(rooms[0][0]) <= 0 || signedArea(rooms[0][1]) >= 0 {
+		t.Errorf("за RFC 7946 зовнішнє кільце - проти годинникової стрілки, отвір - за нею")
+	}
+}
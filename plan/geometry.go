@@ -0,0 +1,464 @@
+package plan
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// point - точка в координатах viewBox.
+type point struct {
+	X, Y float64
+}
+
+// segment - відрізок між двома точками.
+type segment struct {
+	A, B point
+}
+
+// box - прямокутник у координатах viewBox.
+type box struct {
+	X, Y, W, H float64
+}
+
+// matrix - афінне перетворення SVG у формі (a b c d e f):
+// x' = a*x + c*y + e, y' = b*x + d*y + f.
+type matrix [6]float64
+
+// identity - одинична матриця.
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul повертає добуток m*n (спочатку застосовується n, потім m).
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+// apply перетворює точку.
+func (m matrix) apply(p point) point {
+	return point{m[0]*p.X + m[2]*p.Y + m[4], m[1]*p.X + m[3]*p.Y + m[5]}
+}
+
+// invert повертає обернене перетворення (false, якщо матриця вироджена).
+func (m matrix) invert() (matrix, bool) {
+	det := m[0]*m[3] - m[1]*m[2]
+	if math.Abs(det) < 1e-12 {
+		return identity, false
+	}
+	return matrix{
+		m[3] / det,
+		-m[1] / det,
+		-m[2] / det,
+		m[0] / det,
+		(m[2]*m[5] - m[3]*m[4]) / det,
+		(m[1]*m[4] - m[0]*m[5]) / det,
+	}, true
+}
+
+// parseTransform розбирає атрибут transform (translate, scale, rotate, matrix, skewX, skewY).
+// Нерозпізнані частини ігноруються.
+func parseTransform(s string) matrix {
+	result := identity
+	for {
+		open := strings.Index(s, "(")
+		if open < 0 {
+			break
+		}
+		end := strings.Index(s[open:], ")")
+		if end < 0 {
+			break
+		}
+		name := strings.TrimSpace(strings.Trim(s[:open], " ,\t\n"))
+		args := parseNumbers(s[open+1 : open+end])
+		s = s[open+end+1:]
+
+		var m matrix
+		switch name {
+		case "translate":
+			tx, ty := argOr(args, 0, 0), argOr(args, 1, 0)
+			m = matrix{1, 0, 0, 1, tx, ty}
+		case "scale":
+			sx := argOr(args, 0, 1)
+			sy := argOr(args, 1, sx)
+			m = matrix{sx, 0, 0, sy, 0, 0}
+		case "rotate":
+			a := argOr(args, 0, 0) * math.Pi / 180
+			cos, sin := math.Cos(a), math.Sin(a)
+			m = matrix{cos, sin, -sin, cos, 0, 0}
+			if len(args) >= 3 {
+				cx, cy := args[1], args[2]
+				m = matrix{1, 0, 0, 1, cx, cy}.mul(m).mul(matrix{1, 0, 0, 1, -cx, -cy})
+			}
+		case "skewX":
+			m = matrix{1, 0, math.Tan(argOr(args, 0, 0) * math.Pi / 180), 1, 0, 0}
+		case "skewY":
+			m = matrix{1, math.Tan(argOr(args, 0, 0) * math.Pi / 180), 0, 1, 0, 0}
+		case "matrix":
+			if len(args) != 6 {
+				continue
+			}
+			copy(m[:], args)
+		default:
+			continue
+		}
+		result = result.mul(m)
+	}
+	return result
+}
+
+// argOr повертає i-й аргумент або значення за замовчуванням.
+func argOr(args []float64, i int, def float64) float64 {
+	if i < len(args) {
+		return args[i]
+	}
+	return def
+}
+
+// parseNumbers витягує всі числа з рядка (розділювачі - пробіли та коми).
+func parseNumbers(s string) []float64 {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\n' || r == '\t' || r == '\r'
+	})
+	var nums []float64
+	for _, f := range fields {
+		if v, err := strconv.ParseFloat(f, 64); err == nil {
+			nums = append(nums, v)
+		}
+	}
+	return nums
+}
+
+// parsePoints розбирає атрибут points у список точок.
+// Другий результат - false, якщо кількість координат непарна.
+func parsePoints(s string) ([]point, bool) {
+	nums := parseNumbers(s)
+	var pts []point
+	for i := 0; i+1 < len(nums); i += 2 {
+		pts = append(pts, point{nums[i], nums[i+1]})
+	}
+	return pts, len(nums)%2 == 0
+}
+
+// attrFloat повертає числове значення атрибута (0, якщо відсутній чи некоректний).
+func attrFloat(n *html.Node, key string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(getAttr(n, key)), "px"), 64)
+	return v
+}
+
+// nodeTransform обчислює повне перетворення елемента з урахуванням усіх батьківських transform.
+func nodeTransform(n *html.Node) matrix {
+	var chain []*html.Node
+	for p := n; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && getAttr(p, "transform") != "" {
+			chain = append(chain, p)
+		}
+		if p.Type == html.ElementNode && p.Data == "svg" {
+			break
+		}
+	}
+	m := identity
+	for i := len(chain) - 1; i >= 0; i-- {
+		m = m.mul(parseTransform(getAttr(chain[i], "transform")))
+	}
+	return m
+}
+
+// lineSegment повертає відрізок елемента <line> в абсолютних координатах.
+func lineSegment(n *html.Node) segment {
+	m := nodeTransform(n)
+	return segment{
+		m.apply(point{attrFloat(n, "x1"), attrFloat(n, "y1")}),
+		m.apply(point{attrFloat(n, "x2"), attrFloat(n, "y2")}),
+	}
+}
+
+// polygonPoints повертає вершини <polygon>/<polyline> в абсолютних координатах.
+func polygonPoints(n *html.Node) []point {
+	pts, _ := parsePoints(getAttr(n, "points"))
+	m := nodeTransform(n)
+	for i := range pts {
+		pts[i] = m.apply(pts[i])
+	}
+	return pts
+}
+
+// rectPoints повертає кути <rect> в абсолютних координатах.
+func rectPoints(n *html.Node) []point {
+	x, y := attrFloat(n, "x"), attrFloat(n, "y")
+	w, h := attrFloat(n, "width"), attrFloat(n, "height")
+	m := nodeTransform(n)
+	return []point{
+		m.apply(point{x, y}),
+		m.apply(point{x + w, y}),
+		m.apply(point{x + w, y + h}),
+		m.apply(point{x, y + h}),
+	}
+}
+
+// edges повертає ребра замкненого многокутника.
+func edges(poly []point) []segment {
+	var out []segment
+	for i := range poly {
+		out = append(out, segment{poly[i], poly[(i+1)%len(poly)]})
+	}
+	return out
+}
+
+// dist повертає відстань між точками.
+func dist(a, b point) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
+
+// length повертає довжину відрізка.
+func (s segment) length() float64 {
+	return dist(s.A, s.B)
+}
+
+// distToLine повертає відстань від точки до прямої, що містить відрізок.
+func (s segment) distToLine(p point) float64 {
+	l := s.length()
+	if l == 0 {
+		return dist(s.A, p)
+	}
+	return math.Abs((s.B.X-s.A.X)*(s.A.Y-p.Y)-(s.A.X-p.X)*(s.B.Y-s.A.Y)) / l
+}
+
+// project повертає параметр проєкції точки на пряму відрізка (0 - A, length - B).
+func (s segment) project(p point) float64 {
+	l := s.length()
+	if l == 0 {
+		return 0
+	}
+	return ((p.X-s.A.X)*(s.B.X-s.A.X) + (p.Y-s.A.Y)*(s.B.Y-s.A.Y)) / l
+}
+
+// distToSegment повертає відстань від точки до відрізка.
+func (s segment) distToSegment(p point) float64 {
+	l := s.length()
+	if l == 0 {
+		return dist(s.A, p)
+	}
+	t := math.Max(0, math.Min(l, s.project(p))) / l
+	return dist(p, point{s.A.X + t*(s.B.X-s.A.X), s.A.Y + t*(s.B.Y-s.A.Y)})
+}
+
+// pointInPolygon перевіряє, чи лежить точка всередині многокутника (правило парності).
+func pointInPolygon(p point, poly []point) bool {
+	inside := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// polygonArea повертає площу многокутника за формулою шнурування (завжди невід'ємну).
+func polygonArea(poly []point) float64 {
+	var sum float64
+	for i := range poly {
+		j := (i + 1) % len(poly)
+		sum += poly[i].X*poly[j].Y - poly[j].X*poly[i].Y
+	}
+	return math.Abs(sum) / 2
+}
+
+// polygonPerimeter повертає периметр замкненого многокутника.
+func polygonPerimeter(poly []point) float64 {
+	var sum float64
+	for _, e := range edges(poly) {
+		sum += e.length()
+	}
+	return sum
+}
+
+// intersects перевіряє, чи перетинаються два відрізки (дотик теж вважається перетином).
+func (s segment) intersects(o segment) bool {
+	cross := func(a, b, c point) float64 {
+		return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+	}
+	d1, d2 := cross(o.A, o.B, s.A), cross(o.A, o.B, s.B)
+	d3, d4 := cross(s.A, s.B, o.A), cross(s.A, s.B, o.B)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	// Колінеарні або дотичні відрізки
+	const eps = 1e-9
+	return (math.Abs(d1) < eps && o.distToSegment(s.A) < eps) ||
+		(math.Abs(d2) < eps && o.distToSegment(s.B) < eps) ||
+		(math.Abs(d3) < eps && s.distToSegment(o.A) < eps) ||
+		(math.Abs(d4) < eps && s.distToSegment(o.B) < eps)
+}
+
+// isAxisAligned перевіряє, чи відрізок горизонтальний або вертикальний.
+func (s segment) isAxisAligned(tol float64) bool {
+	return math.Abs(s.A.X-s.B.X) <= tol || math.Abs(s.A.Y-s.B.Y) <= tol
+}
+
+// pathTokenRe розбиває атрибут d елемента <path> на команди й числа.
+var pathTokenRe = regexp.MustCompile(`[MmLlHhVvCcSsQqTtAaZz]|[-+]?(?:\d*\.\d+|\d+\.?)(?:[eE][-+]?\d+)?`)
+
+// pathPart - підконтур <path>, наближений ламаною.
+type pathPart struct {
+	pts    []point
+	closed bool
+}
+
+// pathCurveSteps - кількість відрізків, якими наближається крива Безьє.
+const pathCurveSteps = 8
+
+// flattenPath перетворює атрибут d на ламані: прямі команди переносяться точно, криві Безьє
+// (C, S, Q, T) наближаються відрізками, дуги A - відрізком до кінцевої точки.
+func flattenPath(d string) []pathPart {
+	tokens := pathTokenRe.FindAllString(d, -1)
+	var parts []pathPart
+	var cur, start, ctrl point
+	var cmd, prev byte
+	i := 0
+	nums := func(n int) ([]float64, bool) {
+		if i+n > len(tokens) {
+			return nil, false
+		}
+		out := make([]float64, n)
+		for k := range out {
+			v, err := strconv.ParseFloat(tokens[i+k], 64)
+			if err != nil {
+				return nil, false
+			}
+			out[k] = v
+		}
+		i += n
+		return out, true
+	}
+	lineTo := func(p point) {
+		if len(parts) == 0 {
+			parts = append(parts, pathPart{pts: []point{cur}})
+		}
+		last := &parts[len(parts)-1]
+		last.pts = append(last.pts, p)
+		cur = p
+	}
+	curveTo := func(pts ...point) {
+		from := cur
+		for s := 1; s <= pathCurveSteps; s++ {
+			t := float64(s) / pathCurveSteps
+			lineTo(bezier(append([]point{from}, pts...), t))
+		}
+	}
+	for i < len(tokens) {
+		if c := tokens[i][0]; strings.IndexByte("MmLlHhVvCcSsQqTtAaZz", c) >= 0 {
+			cmd = c
+			i++
+			if cmd == 'Z' || cmd == 'z' {
+				if len(parts) > 0 {
+					parts[len(parts)-1].closed = true
+				}
+				cur, prev = start, cmd
+				continue
+			}
+		} else if cmd == 0 {
+			break
+		}
+		rel := cmd >= 'a'
+		off := func(x, y float64) point {
+			if rel {
+				return point{cur.X + x, cur.Y + y}
+			}
+			return point{x, y}
+		}
+		var ok bool
+		var v []float64
+		switch cmd | 0x20 {
+		case 'm':
+			if v, ok = nums(2); ok {
+				cur = off(v[0], v[1])
+				start = cur
+				parts = append(parts, pathPart{pts: []point{cur}})
+				// Наступні пари координат після M - це L
+				cmd -= 'M' - 'L'
+			}
+		case 'l':
+			if v, ok = nums(2); ok {
+				lineTo(off(v[0], v[1]))
+			}
+		case 'h':
+			if v, ok = nums(1); ok {
+				x := v[0]
+				if rel {
+					x += cur.X
+				}
+				lineTo(point{x, cur.Y})
+			}
+		case 'v':
+			if v, ok = nums(1); ok {
+				y := v[0]
+				if rel {
+					y += cur.Y
+				}
+				lineTo(point{cur.X, y})
+			}
+		case 'c':
+			if v, ok = nums(6); ok {
+				c1, c2, p := off(v[0], v[1]), off(v[2], v[3]), off(v[4], v[5])
+				curveTo(c1, c2, p)
+				ctrl = c2
+			}
+		case 's':
+			if v, ok = nums(4); ok {
+				c1 := cur
+				if p := prev | 0x20; p == 'c' || p == 's' {
+					c1 = point{2*cur.X - ctrl.X, 2*cur.Y - ctrl.Y}
+				}
+				c2, p := off(v[0], v[1]), off(v[2], v[3])
+				curveTo(c1, c2, p)
+				ctrl = c2
+			}
+		case 'q':
+			if v, ok = nums(4); ok {
+				c, p := off(v[0], v[1]), off(v[2], v[3])
+				curveTo(c, p)
+				ctrl = c
+			}
+		case 't':
+			if v, ok = nums(2); ok {
+				c := cur
+				if p := prev | 0x20; p == 'q' || p == 't' {
+					c = point{2*cur.X - ctrl.X, 2*cur.Y - ctrl.Y}
+				}
+				p := off(v[0], v[1])
+				curveTo(c, p)
+				ctrl = c
+			}
+		case 'a':
+			if v, ok = nums(7); ok {
+				lineTo(off(v[5], v[6]))
+			}
+		}
+		if !ok {
+			break
+		}
+		prev = cmd
+	}
+	return parts
+}
+
+// bezier обчислює точку кривої Безьє з контрольними точками pts для параметра t (алгоритм де Кастельжо).
+func bezier(pts []point, t float64) point {
+	p := append([]point(nil), pts...)
+	for n := len(p) - 1; n > 0; n-- {
+		for k := 0; k < n; k++ {
+			p[k] = point{p[k].X + (p[k+1].X-p[k].X)*t, p[k].Y + (p[k+1].Y-p[k].Y)*t}
+		}
+	}
+	return p[0]
+}
@@ -0,0 +1,319 @@
+package plan
+
+import (
+	"math"
+
+	"golang.org/x/net/html"
+)
+
+// grid - растр плану для пошуку шляхів: комірки, зайняті стінами, заблоковані.
+type grid struct {
+	x0, y0  float64
+	cell    float64
+	w, h    int
+	blocked []bool
+}
+
+// newGrid створює порожню сітку, що покриває прямокутник (x, y, w, h).
+func newGrid(x, y, w, h, cell float64) *grid {
+	g := &grid{x0: x, y0: y, cell: cell}
+	g.w = int(math.Ceil(w/cell)) + 1
+	g.h = int(math.Ceil(h/cell)) + 1
+	g.blocked = make([]bool, g.w*g.h)
+	return g
+}
+
+// cellOf повертає комірку, в яку потрапляє точка.
+func (g *grid) cellOf(p point) (int, int, bool) {
+	i := int(math.Floor((p.X - g.x0) / g.cell))
+	j := int(math.Floor((p.Y - g.y0) / g.cell))
+	return i, j, i >= 0 && j >= 0 && i < g.w && j < g.h
+}
+
+// center повертає центр комірки.
+func (g *grid) center(i, j int) point {
+	return point{g.x0 + (float64(i)+0.5)*g.cell, g.y0 + (float64(j)+0.5)*g.cell}
+}
+
+// isBlocked перевіряє комірку (за межами сітки - заблоковано).
+func (g *grid) isBlocked(i, j int) bool {
+	if i < 0 || j < 0 || i >= g.w || j >= g.h {
+		return true
+	}
+	return g.blocked[j*g.w+i]
+}
+
+// markSegment позначає (blocked=true) або звільняє комірки, центр яких
+// ближче за radius до відрізка.
+func (g *grid) markSegment(s segment, radius float64, blocked bool) {
+	i0, j0, _ := g.cellOf(point{math.Min(s.A.X, s.B.X) - radius, math.Min(s.A.Y, s.B.Y) - radius})
+	i1, j1, _ := g.cellOf(point{math.Max(s.A.X, s.B.X) + radius, math.Max(s.A.Y, s.B.Y) + radius})
+	for j := max(j0, 0); j <= min(j1, g.h-1); j++ {
+		for i := max(i0, 0); i <= min(i1, g.w-1); i++ {
+			if s.distToSegment(g.center(i, j)) <= radius {
+				g.blocked[j*g.w+i] = blocked
+			}
+		}
+	}
+}
+
+// flood знаходить зв'язну (4 напрямки) область вільних комірок з комірки start і позначає
+// її комірки в room номером id; open - область доходить до краю сітки.
+func (g *grid) flood(start int, room []int, id int) (cells []int, open bool) {
+	room[start] = id
+	for queue := []int{start}; len(queue) > 0; queue = queue[1:] {
+		k := queue[0]
+		cells = append(cells, k)
+		i, j := k%g.w, k/g.w
+		if i == 0 || j == 0 || i == g.w-1 || j == g.h-1 {
+			open = true
+		}
+		for _, nb := range [][2]int{{i + 1, j}, {i - 1, j}, {i, j + 1}, {i, j - 1}} {
+			if g.isBlocked(nb[0], nb[1]) {
+				continue
+			}
+			if nk := nb[1]*g.w + nb[0]; room[nk] < 0 {
+				room[nk] = id
+				queue = append(queue, nk)
+			}
+		}
+	}
+	return cells, open
+}
+
+// nearestFree повертає найближчу до точки вільну комірку в межах maxSteps кілець.
+func (g *grid) nearestFree(p point, maxSteps int) (int, int, bool) {
+	ci, cj, _ := g.cellOf(p)
+	for r := 0; r <= maxSteps; r++ {
+		best, bi, bj := math.Inf(1), 0, 0
+		for j := cj - r; j <= cj+r; j++ {
+			for i := ci - r; i <= ci+r; i++ {
+				if max(abs(i-ci), abs(j-cj)) != r || g.isBlocked(i, j) {
+					continue
+				}
+				if d := dist(g.center(i, j), p); d < best {
+					best, bi, bj = d, i, j
+				}
+			}
+		}
+		if !math.IsInf(best, 1) {
+			return bi, bj, true
+		}
+	}
+	return 0, 0, false
+}
+
+// abs повертає модуль цілого числа.
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// shortestPath шукає найкоротший шлях (8 напрямків, без зрізання кутів стін)
+// від точки from до найближчої з targets і повертає спрощену ламану.
+// Другий результат - індекс досягнутої цілі, -1 якщо шляху немає.
+func (g *grid) shortestPath(from point, targets []point) ([]point, int) {
+	si, sj, ok := g.nearestFree(from, 4)
+	if !ok {
+		return nil, -1
+	}
+	goal := make(map[int]int)
+	for t, p := range targets {
+		if i, j, ok := g.nearestFree(p, 4); ok {
+			goal[j*g.w+i] = t
+		}
+	}
+	if len(goal) == 0 {
+		return nil, -1
+	}
+
+	// Дейкстра з вагами 1 і √2; сітка невелика, тому достатньо простої черги з пріоритетом
+	distTo := make([]float64, g.w*g.h)
+	prev := make([]int, g.w*g.h)
+	for k := range distTo {
+		distTo[k] = math.Inf(1)
+		prev[k] = -1
+	}
+	start := sj*g.w + si
+	distTo[start] = 0
+	queue := &pathQueue{}
+	queue.push(start, 0)
+
+	reached := -1
+	for queue.len() > 0 {
+		k, d := queue.pop()
+		if d > distTo[k] {
+			continue
+		}
+		if _, ok := goal[k]; ok {
+			reached = k
+			break
+		}
+		i, j := k%g.w, k/g.w
+		for dj := -1; dj <= 1; dj++ {
+			for di := -1; di <= 1; di++ {
+				if di == 0 && dj == 0 {
+					continue
+				}
+				ni, nj := i+di, j+dj
+				if g.isBlocked(ni, nj) {
+					continue
+				}
+				step := 1.0
+				if di != 0 && dj != 0 {
+					if g.isBlocked(i+di, j) || g.isBlocked(i, j+dj) {
+						continue
+					}
+					step = math.Sqrt2
+				}
+				nk := nj*g.w + ni
+				if nd := d + step; nd < distTo[nk] {
+					distTo[nk] = nd
+					prev[nk] = k
+					queue.push(nk, nd)
+				}
+			}
+		}
+	}
+	if reached < 0 {
+		return nil, -1
+	}
+
+	var cells []point
+	for k := reached; k >= 0; k = prev[k] {
+		cells = append(cells, g.center(k%g.w, k/g.w))
+	}
+	for a, b := 0, len(cells)-1; a < b; a, b = a+1, b-1 {
+		cells[a], cells[b] = cells[b], cells[a]
+	}
+	return g.smooth(cells), goal[reached]
+}
+
+// smooth спрямляє ламану: з кожної точки переходить до найдальшої видимої.
+func (g *grid) smooth(pts []point) []point {
+	if len(pts) <= 2 {
+		return pts
+	}
+	out := []point{pts[0]}
+	for i := 0; i < len(pts)-1; {
+		next := i + 1
+		for j := len(pts) - 1; j > i+1; j-- {
+			if g.visible(pts[i], pts[j]) {
+				next = j
+				break
+			}
+		}
+		out = append(out, pts[next])
+		i = next
+	}
+	return out
+}
+
+// visible перевіряє, чи не перетинає відрізок заблокованих комірок.
+func (g *grid) visible(a, b point) bool {
+	steps := int(math.Ceil(dist(a, b)/(g.cell/2))) + 1
+	for s := 0; s <= steps; s++ {
+		t := float64(s) / float64(steps)
+		i, j, _ := g.cellOf(point{a.X + t*(b.X-a.X), a.Y + t*(b.Y-a.Y)})
+		if g.isBlocked(i, j) {
+			return false
+		}
+	}
+	return true
+}
+
+// pathQueue - мінімальна бінарна купа (комірка, відстань).
+type pathQueue struct {
+	keys  []int
+	dists []float64
+}
+
+func (q *pathQueue) len() int { return len(q.keys) }
+
+func (q *pathQueue) push(k int, d float64) {
+	q.keys = append(q.keys, k)
+	q.dists = append(q.dists, d)
+	for i := len(q.keys) - 1; i > 0; {
+		p := (i - 1) / 2
+		if q.dists[p] <= q.dists[i] {
+			break
+		}
+		q.swap(i, p)
+		i = p
+	}
+}
+
+func (q *pathQueue) pop() (int, float64) {
+	k, d := q.keys[0], q.dists[0]
+	last := len(q.keys) - 1
+	q.swap(0, last)
+	q.keys, q.dists = q.keys[:last], q.dists[:last]
+	for i := 0; ; {
+		l, r, m := 2*i+1, 2*i+2, i
+		if l < last && q.dists[l] < q.dists[m] {
+			m = l
+		}
+		if r < last && q.dists[r] < q.dists[m] {
+			m = r
+		}
+		if m == i {
+			break
+		}
+		q.swap(i, m)
+		i = m
+	}
+	return k, d
+}
+
+func (q *pathQueue) swap(i, j int) {
+	q.keys[i], q.keys[j] = q.keys[j], q.keys[i]
+	q.dists[i], q.dists[j] = q.dists[j], q.dists[i]
+}
+
+// planGrid будує сітку плану: стіни й контури .outline заблоковані із запасом clearance,
+// а проходи крізь двері (.doors) звільнені.
+func planGrid(svg *html.Node, cell, clearance float64) *grid {
+	g, _, doors := wallGrid(svg, cell, clearance)
+	// Двері малюються поверх стін, тож прохід крізь них треба звільнити
+	for _, d := range doors {
+		g.markSegment(d, clearance+cell, false)
+	}
+	return g
+}
+
+// wallGrid будує сітку, де стіни й контури .outline заблоковані із запасом clearance,
+// а дверні прорізи лишаються закритими, і повертає відрізки стін і дверей.
+func wallGrid(svg *html.Node, cell, clearance float64) (*grid, []segment, []segment) {
+	vx, vy, vw, vh, _ := parseViewBox(svg)
+	g := newGrid(vx, vy, vw, vh, cell)
+
+	var walls, doors []segment
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		switch {
+		case n.Data == "defs" || n.Data == "symbol":
+			return true
+		case isWall(n):
+			for _, s := range wallEdges(n) {
+				g.markSegment(s, clearance, true)
+				walls = append(walls, s)
+			}
+		case n.Data == "line" && isDoor(n):
+			doors = append(doors, lineSegment(n))
+		case (n.Data == "polygon" || n.Data == "polyline") && hasClass(n, "outline"):
+			pts := polygonPoints(n)
+			if len(pts) > 1 {
+				for _, e := range edges(pts) {
+					g.markSegment(e, clearance, true)
+					walls = append(walls, e)
+				}
+			}
+		}
+		return false
+	})
+	return g, walls, doors
+}
@@ -0,0 +1,48 @@
+package plan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// parseSVG розбирає розмітку SVG з тесту.
+func parseSVG(t *testing.T, markup string) *Document {
+	t.Helper()
+	d, err := Extractor{}.Extract(context.Background(), strings.NewReader(markup))
+	if err != nil {
+		t.Fatalf("розбір SVG: %v", err)
+	}
+	return d
+}
+
+// loadPlan читає план з кореня репозиторію (full.html, plan1.html...).
+func loadPlan(t *testing.T, name string) *Document {
+	t.Helper()
+	f, err := os.Open(filepath.Join("..", name))
+	if err != nil {
+		t.Fatalf("відкриття %s: %v", name, err)
+	}
+	defer f.Close()
+	d, err := Extractor{}.Extract(context.Background(), f)
+	if err != nil {
+		t.Fatalf("витягнення SVG з %s: %v", name, err)
+	}
+	return d
+}
+
+// elementsByTag повертає елементи документа з тегом tag у порядку обходу.
+func elementsByTag(d *Document, tag string) []*html.Node {
+	var out []*html.Node
+	traverse(d.SVG, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == tag {
+			out = append(out, n)
+		}
+		return false
+	})
+	return out
+}
@@ -0,0 +1,677 @@
+package plan
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// imdfMaxPrincipalOpenings - скільки головних входів на поверх вважається правдоподібним.
+const imdfMaxPrincipalOpenings = 4
+
+// IMDFVersion - версія Indoor Mapping Data Format, якій відповідає пакет.
+const IMDFVersion = "1.0.0"
+
+// IMDFOptions - дані закладу, будівлі й адреси для пакета IMDF.
+type IMDFOptions struct {
+	Geo GeoReference `json:"geo"`
+	// Venue - назва закладу (обов'язкова)
+	Venue string `json:"venue"`
+	// Building - назва будівлі; порожня - як Venue
+	Building string `json:"building,omitempty"`
+	// Category - категорія закладу IMDF (businesscampus, university, hotel...); порожня - businesscampus
+	Category string `json:"category,omitempty"`
+	// Address, Locality - вулиця з номером будинку і населений пункт (обов'язкові)
+	Address  string `json:"address"`
+	Locality string `json:"locality"`
+	// Province - регіон за ISO 3166-2 (UA-30)
+	Province string `json:"province,omitempty"`
+	// Country - країна за ISO 3166-1 alpha-2; порожня - UA
+	Country    string `json:"country,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	// Language - мова назв (BCP 47); порожня - uk
+	Language string `json:"language,omitempty"`
+}
+
+// IMDFLevel - поверх пакета IMDF.
+type IMDFLevel struct {
+	Doc *Document
+	// Ordinal - номер рівня IMDF: 0 - наземний поверх, від'ємні - підземні
+	Ordinal   int
+	Name      string
+	ShortName string
+	// Stairs - сходові клітки зі спільними для будівлі ID (див. LoadFloors); nil - знайти на плані
+	Stairs []Stairwell
+}
+
+// IMDFPackage - пакет IMDF: manifest.json і файли об'єктів (venue.geojson, unit.geojson...).
+type IMDFPackage struct {
+	Manifest IMDFManifest
+	Files    map[string]*FeatureCollection
+	// Warnings - об'єкти плану, які не вдалося перенести (кімната без замкненого контуру тощо)
+	Warnings []string
+}
+
+// IMDFManifest - вміст manifest.json.
+type IMDFManifest struct {
+	Version     string `json:"version"`
+	Created     string `json:"created"`
+	GeneratedBy string `json:"generated_by"`
+	Language    string `json:"language"`
+	Extensions  any    `json:"extensions"`
+}
+
+// imdfUnitCategories - категорії приміщень за словами в назві; перше збігання перемагає.
+var imdfUnitCategories = []struct{ word, category string }{
+	{"коридор", "walkway"},
+	{"хол", "walkway"},
+	{"тамбур", "walkway"},
+	{"вестибюль", "lobby"},
+	{"санвузол", "restroom"},
+	{"туалет", "restroom"},
+	{"вбиральня", "restroom"},
+	{"душ", "shower"},
+	{"сход", "stairs"},
+	{"ліфт", "elevator"},
+	{"кухн", "kitchen"},
+	{"комор", "storage"},
+	{"склад", "storage"},
+	{"кабінет", "office"},
+	{"офіс", "office"},
+}
+
+// imdfAmenityCategories - категорії зручностей IMDF для символів плану; решта - unspecified.
+var imdfAmenityCategories = map[string]string{
+	"toilet":            "restroom",
+	"shower-cabin":      "shower",
+	"fire-extinguisher": "fireextinguisher",
+	"fire-alarm-button": "firealarm",
+	"first-aid":         "firstaid",
+	"assembly-point":    "meetingpoint",
+	"you-are-here":      "youarehere",
+}
+
+// imdfAmenityUnits - категорії приміщень без підпису за обладнанням у них; решта - room.
+var imdfAmenityUnits = map[string]string{
+	"toilet":       "restroom",
+	"sink":         "restroom",
+	"shower-cabin": "shower",
+}
+
+// imdfSignSymbols - знаки, які не є зручностями: виходи передаються як openings.
+var imdfSignSymbols = map[string]bool{
+	"exit-sign":            true,
+	"emergency-exit-left":  true,
+	"emergency-exit-right": true,
+}
+
+// IMDF будує пакет Indoor Mapping Data Format з планів поверхів: address, venue, building,
+// footprint, а для кожного поверху - level (контури .outline), unit (кімнати, як у GeoJSON, і сходові клітки),
+// opening (двері та виходи) і amenity (символи обладнання в кімнатах). Ідентифікатори - UUID,
+// похідні від назви закладу й об'єкта, тож повторний експорт дає ті самі id.
+// Пакет перевіряється (див. ValidateIMDF); помилка описує всі відсутні обов'язкові поля.
+func IMDF(levels []IMDFLevel, opts IMDFOptions) (*IMDFPackage, error) {
+	if len(levels) == 0 {
+		return nil, Errorf("пакет IMDF: немає жодного поверху")
+	}
+	if opts.Building == "" {
+		opts.Building = opts.Venue
+	}
+	if opts.Category == "" {
+		opts.Category = "businesscampus"
+	}
+	if opts.Country == "" {
+		opts.Country = "UA"
+	}
+	if opts.Language == "" {
+		opts.Language = "uk"
+	}
+	ref := opts.Geo.withDefaults()
+	b := &imdfBuilder{opts: opts, files: make(map[string]*FeatureCollection)}
+	for _, name := range []string{"address", "venue", "building", "footprint", "level", "unit", "opening", "amenity"} {
+		b.files[name] = &FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+	}
+
+	addressID := b.id("address")
+	buildingID := b.id("building")
+	b.add("address", addressID, nil, map[string]any{
+		"address":            opts.Address,
+		"unit":               nil,
+		"locality":           opts.Locality,
+		"province":           nullable(opts.Province),
+		"country":            opts.Country,
+		"postal_code":        nullable(opts.PostalCode),
+		"postal_code_ext":    nil,
+		"postal_code_vanity": nil,
+	})
+
+	var footprint [][][][2]float64
+	var center point
+	lowest := 0
+	for i, l := range levels {
+		if l.Ordinal < levels[lowest].Ordinal {
+			lowest = i
+		}
+	}
+	for i, l := range levels {
+		outlines := levelOutlines(l.Doc)
+		shape := make([][][][2]float64, len(outlines))
+		for k, o := range outlines {
+			shape[k] = ref.polygon([][]point{o})
+		}
+		c := polygonBox(concat(outlines)).center()
+		if i == lowest {
+			footprint, center = shape, c
+		}
+		levelID := b.id("level", fmt.Sprint(l.Ordinal))
+		b.add("level", levelID, &Geometry{"MultiPolygon", shape}, map[string]any{
+			"category":      "unspecified",
+			"restriction":   nil,
+			"outdoor":       false,
+			"ordinal":       l.Ordinal,
+			"name":          b.label(l.Name),
+			"short_name":    b.label(l.ShortName),
+			"display_point": ref.point(c),
+			"address_id":    nil,
+			"building_ids":  []string{buildingID},
+		})
+		b.level(l, levelID, ref)
+	}
+
+	b.add("building", buildingID, nil, map[string]any{
+		"name":          b.label(opts.Building),
+		"alt_name":      nil,
+		"category":      "unspecified",
+		"restriction":   nil,
+		"display_point": ref.point(center),
+		"address_id":    addressID,
+	})
+	b.add("footprint", b.id("footprint"), &Geometry{"MultiPolygon", footprint}, map[string]any{
+		"category":     "ground",
+		"name":         nil,
+		"building_ids": []string{buildingID},
+	})
+	b.add("venue", b.id("venue"), &Geometry{"MultiPolygon", footprint}, map[string]any{
+		"category":      opts.Category,
+		"restriction":   nil,
+		"name":          b.label(opts.Venue),
+		"alt_name":      nil,
+		"hours":         nil,
+		"phone":         nil,
+		"website":       nil,
+		"display_point": ref.point(center),
+		"address_id":    addressID,
+	})
+
+	pkg := &IMDFPackage{
+		Manifest: IMDFManifest{
+			Version:     IMDFVersion,
+			Created:     time.Now().UTC().Format(time.RFC3339),
+			GeneratedBy: "simple-plan",
+			Language:    opts.Language,
+		},
+		Files:    make(map[string]*FeatureCollection),
+		Warnings: b.warnings,
+	}
+	for name, fc := range b.files {
+		pkg.Files[name+".geojson"] = fc
+	}
+	if err := ValidateIMDF(pkg); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// imdfBuilder накопичує об'єкти пакета за типами.
+type imdfBuilder struct {
+	opts     IMDFOptions
+	files    map[string]*FeatureCollection
+	warnings []string
+}
+
+// id повертає UUID (версія 5 за схемою, SHA-1) з назви закладу та ключа об'єкта.
+func (b *imdfBuilder) id(parts ...string) string {
+	sum := sha1.Sum([]byte(b.opts.Venue + "\x00" + strings.Join(parts, "\x00")))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// label повертає назву IMDF мовою пакета або nil для порожньої.
+func (b *imdfBuilder) label(s string) any {
+	if s == "" {
+		return nil
+	}
+	return map[string]string{b.opts.Language: s}
+}
+
+// add додає об'єкт типу kind.
+func (b *imdfBuilder) add(kind, id string, geom *Geometry, props map[string]any) {
+	fc := b.files[kind]
+	fc.Features = append(fc.Features, Feature{Type: "Feature", ID: id, FeatureType: kind, Geometry: geom, Properties: props})
+}
+
+// level додає приміщення, прорізи й зручності поверху.
+func (b *imdfBuilder) level(l IMDFLevel, levelID string, ref GeoReference) {
+	floor := fmt.Sprint(l.Ordinal)
+	type unit struct {
+		id    string
+		rings [][]point
+	}
+	var units []unit
+	var doors, amenities []planFeature
+	for i, f := range planFeatures(l.Doc) {
+		switch f.kind {
+		case featureRoom:
+			if len(f.rings) == 0 {
+				b.warnings = append(b.warnings, fmt.Sprintf("%s: %q не лежить у замкненій кімнаті, пропущено", l.Name, f.name))
+				continue
+			}
+			id := b.id("unit", floor, fmt.Sprint(i))
+			units = append(units, unit{id, f.rings})
+			b.add("unit", id, ref.geometry(f), map[string]any{
+				"category":      unitCategory(f.name),
+				"restriction":   nil,
+				"accessibility": nil,
+				"name":          b.label(f.name),
+				"alt_name":      nil,
+				"level_id":      levelID,
+				"display_point": ref.point(f.at),
+			})
+		case featureDoor, featureExit:
+			doors = append(doors, f)
+		case featureEquipment:
+			amenities = append(amenities, f)
+		}
+	}
+	unitAt := func(p point) (string, bool) {
+		for _, u := range units {
+			if pointInPolygon(p, u.rings[0]) {
+				return u.id, true
+			}
+		}
+		return "", false
+	}
+
+	// Сходові клітки поза кімнатами з підписами стають окремими приміщеннями
+	stairs := l.Stairs
+	if stairs == nil {
+		stairs = FindStairwells(l.Doc)
+	}
+	for i, s := range stairs {
+		if _, ok := unitAt(s.center()); ok {
+			continue
+		}
+		rect := []point{{s.X, s.Y}, {s.X + s.W, s.Y}, {s.X + s.W, s.Y + s.H}, {s.X, s.Y + s.H}}
+		name := s.ID
+		if name == "" {
+			name = s.Label
+		}
+		if name == "" {
+			name = "С" + strconv.Itoa(i+1)
+		}
+		name = "Сходи " + name
+		id := b.id("unit", floor, "stairs", strconv.Itoa(i))
+		units = append(units, unit{id, [][]point{rect}})
+		b.add("unit", id, &Geometry{"Polygon", ref.polygon([][]point{rect})}, map[string]any{
+			"category":      "stairs",
+			"restriction":   nil,
+			"accessibility": nil,
+			"name":          b.label(name),
+			"alt_name":      nil,
+			"level_id":      levelID,
+			"display_point": ref.point(s.center()),
+		})
+	}
+
+	for i, f := range doors {
+		category := "pedestrian"
+		if f.kind == featureExit {
+			category = "pedestrian.principal"
+		}
+		mid := point{(f.line[0].X + f.line[1].X) / 2, (f.line[0].Y + f.line[1].Y) / 2}
+		b.add("opening", b.id("opening", floor, fmt.Sprint(i)), ref.geometry(f), map[string]any{
+			"category":       category,
+			"accessibility":  nil,
+			"access_control": nil,
+			"door":           map[string]any{"type": "door", "automatic": false, "material": nil},
+			"name":           b.label(f.number),
+			"alt_name":       nil,
+			"display_point":  ref.point(mid),
+			"level_id":       levelID,
+		})
+	}
+
+	// Обладнання в замкненому приміщенні без підпису створює для нього unit без назви
+	var index *roomIndex
+	regionUnits := make(map[int]string)
+	for i, f := range amenities {
+		if imdfSignSymbols[f.symbol] {
+			continue
+		}
+		unitID, ok := unitAt(f.at)
+		if !ok {
+			if index == nil {
+				index = newRoomIndex(l.Doc.SVG)
+			}
+			if region, closed := index.at(f.at); closed {
+				if unitID, ok = regionUnits[region]; !ok {
+					unitID = b.id("unit", floor, "region", strconv.Itoa(region))
+					regionUnits[region] = unitID
+					category := imdfAmenityUnits[f.symbol]
+					if category == "" {
+						category = "room"
+					}
+					b.add("unit", unitID, &Geometry{"Polygon", ref.polygon(index.outline(region))}, map[string]any{
+						"category":      category,
+						"restriction":   nil,
+						"accessibility": nil,
+						"name":          nil,
+						"alt_name":      nil,
+						"level_id":      levelID,
+						"display_point": ref.point(f.at),
+					})
+				}
+				ok = true
+			}
+		}
+		if !ok {
+			b.warnings = append(b.warnings, fmt.Sprintf("%s: %s (%s, %s) не лежить у жодному приміщенні, пропущено",
+				l.Name, f.symbol, formatNumber(f.at.X), formatNumber(f.at.Y)))
+			continue
+		}
+		category := imdfAmenityCategories[f.symbol]
+		if category == "" {
+			category = "unspecified"
+		}
+		b.add("amenity", b.id("amenity", floor, fmt.Sprint(i)), ref.point(f.at), map[string]any{
+			"category":       category,
+			"accessibility":  nil,
+			"name":           b.label(f.name),
+			"alt_name":       nil,
+			"hours":          nil,
+			"phone":          nil,
+			"website":        nil,
+			"unit_ids":       []string{unitID},
+			"address_id":     nil,
+			"correlation_id": nil,
+		})
+	}
+}
+
+// unitCategory визначає категорію приміщення IMDF за назвою.
+func unitCategory(name string) string {
+	lower := strings.ToLower(name)
+	for _, c := range imdfUnitCategories {
+		if strings.Contains(lower, c.word) {
+			return c.category
+		}
+	}
+	return "room"
+}
+
+// levelOutlines повертає контури .outline поверху, а без них - прямокутник viewBox.
+func levelOutlines(d *Document) [][]point {
+	var out [][]point
+	traverse(d.SVG, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		if n.Data == "defs" || n.Data == "symbol" {
+			return true
+		}
+		if n.Data == "polygon" && hasClass(n, "outline") {
+			if pts := polygonPoints(n); len(pts) >= 3 {
+				out = append(out, pts)
+			}
+		}
+		return false
+	})
+	if len(out) == 0 {
+		x, y, w, h, _ := parseViewBox(d.SVG)
+		out = append(out, []point{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}})
+	}
+	return out
+}
+
+// concat об'єднує списки точок.
+func concat(lists [][]point) []point {
+	var out []point
+	for _, l := range lists {
+		out = append(out, l...)
+	}
+	return out
+}
+
+// nullable повертає nil для порожнього рядка.
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// imdfSchema - обов'язкові властивості типу об'єкта IMDF (можуть бути null), ті з них,
+// що не можуть бути null, і допустимі геометрії (порожньо - геометрія null).
+var imdfSchema = map[string]struct {
+	props, nonNull, geometry []string
+}{
+	"address": {
+		props:   []string{"address", "unit", "locality", "province", "country", "postal_code", "postal_code_ext", "postal_code_vanity"},
+		nonNull: []string{"address", "locality", "country"},
+	},
+	"venue": {
+		props:    []string{"category", "restriction", "name", "alt_name", "hours", "phone", "website", "display_point", "address_id"},
+		nonNull:  []string{"category", "name", "display_point", "address_id"},
+		geometry: []string{"Polygon", "MultiPolygon"},
+	},
+	"building": {
+		props:   []string{"name", "alt_name", "category", "restriction", "display_point", "address_id"},
+		nonNull: []string{"category"},
+	},
+	"footprint": {
+		props:    []string{"category", "name", "building_ids"},
+		nonNull:  []string{"category", "building_ids"},
+		geometry: []string{"Polygon", "MultiPolygon"},
+	},
+	"level": {
+		props:    []string{"category", "restriction", "outdoor", "ordinal", "name", "short_name", "display_point", "address_id", "building_ids"},
+		nonNull:  []string{"category", "outdoor", "ordinal", "name", "short_name"},
+		geometry: []string{"Polygon", "MultiPolygon"},
+	},
+	"unit": {
+		props:    []string{"category", "restriction", "accessibility", "name", "alt_name", "level_id", "display_point"},
+		nonNull:  []string{"category", "level_id"},
+		geometry: []string{"Polygon", "MultiPolygon"},
+	},
+	"opening": {
+		props:    []string{"category", "accessibility", "access_control", "door", "name", "alt_name", "display_point", "level_id"},
+		nonNull:  []string{"category", "level_id"},
+		geometry: []string{"LineString"},
+	},
+	"amenity": {
+		props:    []string{"category", "accessibility", "name", "alt_name", "hours", "phone", "website", "unit_ids", "address_id", "correlation_id"},
+		nonNull:  []string{"category", "unit_ids"},
+		geometry: []string{"Point"},
+	},
+}
+
+// imdfReferences - властивості-посилання і тип об'єкта, на який вони вказують.
+var imdfReferences = map[string]string{
+	"address_id":   "address",
+	"building_ids": "building",
+	"level_id":     "level",
+	"unit_ids":     "unit",
+}
+
+var (
+	uuidRe    = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	countryRe = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// ValidateIMDF перевіряє пакет: наявність venue, address і level, UUID і унікальність id,
+// обов'язкові властивості кожного типу, допустимі геометрії, посилання на інші об'єкти
+// та унікальність ordinal поверхів.
+func ValidateIMDF(p *IMDFPackage) error {
+	var problems []error
+	report := func(format string, args ...any) {
+		problems = append(problems, Errorf(format, args...))
+	}
+	if p.Manifest.Version == "" || p.Manifest.Language == "" {
+		report("manifest.json: не вказано version чи language")
+	}
+
+	ids := make(map[string]string)
+	names := make([]string, 0, len(p.Files))
+	for name := range p.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, f := range p.Files[name].Features {
+			if !uuidRe.MatchString(f.ID) {
+				report("%s: id %q не є UUID", name, f.ID)
+			} else if other, ok := ids[f.ID]; ok {
+				report("%s: id %s уже використано в %s", name, f.ID, other)
+			}
+			ids[f.ID] = f.FeatureType
+		}
+	}
+
+	ordinals := make(map[any]bool)
+	// Прорізи кожного поверху: усього й головних входів
+	var levels []string
+	openings, principal := make(map[string]int), make(map[string]int)
+	for _, name := range names {
+		for _, f := range p.Files[name].Features {
+			schema, ok := imdfSchema[f.FeatureType]
+			if !ok {
+				report("%s: невідомий feature_type %q", name, f.FeatureType)
+				continue
+			}
+			where := fmt.Sprintf("%s %s", f.FeatureType, f.ID)
+			for _, key := range schema.props {
+				if _, ok := f.Properties[key]; !ok {
+					report("%s: немає властивості %s", where, key)
+				}
+			}
+			for _, key := range schema.nonNull {
+				if v, ok := f.Properties[key]; ok && isEmptyValue(v) {
+					report("%s: властивість %s не може бути порожньою", where, key)
+				}
+			}
+			switch {
+			case len(schema.geometry) == 0 && f.Geometry != nil:
+				report("%s: геометрія має бути null", where)
+			case len(schema.geometry) > 0 && (f.Geometry == nil || !slices.Contains(schema.geometry, f.Geometry.Type)):
+				report("%s: геометрія має бути %s", where, strings.Join(schema.geometry, " або "))
+			}
+			for key, kind := range imdfReferences {
+				for _, ref := range referenceIDs(f.Properties[key]) {
+					if ids[ref] != kind {
+						report("%s: %s посилається на відсутній %s %s", where, key, kind, ref)
+					}
+				}
+			}
+			switch f.FeatureType {
+			case "address":
+				if c, _ := f.Properties["country"].(string); c != "" && !countryRe.MatchString(c) {
+					report("%s: country %q має бути кодом ISO 3166-1 (UA)", where, c)
+				}
+			case "level":
+				if ord := f.Properties["ordinal"]; ordinals[ord] {
+					report("%s: ordinal %v уже має інший поверх", where, ord)
+				} else {
+					ordinals[ord] = true
+				}
+			case "opening":
+				level, _ := f.Properties["level_id"].(string)
+				if openings[level] == 0 {
+					levels = append(levels, level)
+				}
+				openings[level]++
+				if f.Properties["category"] == "pedestrian.principal" {
+					principal[level]++
+				}
+			}
+		}
+	}
+	// Головні входи - кілька на будівлю; якщо ними позначено більшість дверей поверху,
+	// внутрішні двері прийнято за виходи
+	for _, level := range levels {
+		if principal[level] > imdfMaxPrincipalOpenings && 2*principal[level] > openings[level] {
+			report("level %s: %d з %d прорізів позначено головними входами (pedestrian.principal), очікується не більше %d",
+				level, principal[level], openings[level], imdfMaxPrincipalOpenings)
+		}
+	}
+	for _, kind := range []string{"address", "venue", "building", "level"} {
+		if fc := p.Files[kind+".geojson"]; fc == nil || len(fc.Features) == 0 {
+			report("%s.geojson: немає жодного об'єкта", kind)
+		}
+	}
+	if len(problems) > 0 {
+		return Errorf("пакет IMDF не пройшов перевірку:\n%w", errors.Join(problems...))
+	}
+	return nil
+}
+
+// isEmptyValue перевіряє, чи значення властивості порожнє: null, "" чи порожній список.
+func isEmptyValue(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	}
+	return false
+}
+
+// referenceIDs повертає id з властивості-посилання (рядок або список).
+func referenceIDs(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// WriteDir записує пакет у каталог dir: manifest.json і файли об'єктів.
+func (p *IMDFPackage) WriteDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Errorf("%w: помилка створення каталогу %s: %w", ErrWrite, dir, err)
+	}
+	files := map[string]any{"manifest.json": p.Manifest}
+	for name, fc := range p.Files {
+		files[name] = fc
+	}
+	for name, v := range files {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return Errorf("%w %s: %w", ErrWrite, name, err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return Errorf("%w файлу %s: %w", ErrWrite, path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package plan
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// imdfOptions - обов'язкові дані закладу для тестових пакетів.
+var imdfOptions = IMDFOptions{
+	Geo:      GeoReference{Lat: 50.45, Lon: 30.52},
+	Venue:    "Бізнес-центр",
+	Address:  "вул. Хрещатик, 1",
+	Locality: "Київ",
+}
+
+// imdfFeatures повертає об'єкти файлу пакета.
+func imdfFeatures(t *testing.T, p *IMDFPackage, kind string) []Feature {
+	t.Helper()
+	fc := p.Files[kind+".geojson"]
+	if fc == nil {
+		t.Fatalf("у пакеті немає %s.geojson", kind)
+	}
+	return fc.Features
+}
+
+func TestIMDFRequiredFields(t *testing.T) {
+	levels := []IMDFLevel{{Doc: loadPlan(t, "full.html"), Name: "Перший поверх", ShortName: "1"}}
+	if _, err := IMDF(levels, imdfOptions); err != nil {
+		t.Fatalf("повний пакет не пройшов перевірку: %v", err)
+	}
+
+	opts := imdfOptions
+	opts.Venue, opts.Locality, opts.Country = "", "", "Україна"
+	_, err := IMDF(levels, opts)
+	if err == nil {
+		t.Fatal("пакет без назви закладу й населеного пункту пройшов перевірку")
+	}
+	// Помилка перелічує всі проблеми, а не лише першу
+	for _, want := range []string{
+		"властивість name не може бути порожньою",
+		"властивість locality не може бути порожньою",
+		`country "Україна" має бути кодом ISO 3166-1`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("у помилці немає %q:\n%v", want, err)
+		}
+	}
+
+	if _, err := IMDF(nil, imdfOptions); err == nil {
+		t.Error("пакет без поверхів має повертати помилку")
+	}
+	// Два поверхи з однаковим ordinal
+	twice := append(levels, IMDFLevel{Doc: loadPlan(t, "full.html"), Name: "Другий поверх", ShortName: "2"})
+	if _, err := IMDF(twice, imdfOptions); err == nil || !strings.Contains(err.Error(), "ordinal 0 уже має інший поверх") {
+		t.Errorf("очікувалась помилка про повтор ordinal, отримано %v", err)
+	}
+}
+
+func TestIMDFDeterministicIDs(t *testing.T) {
+	build := func(venue string) *IMDFPackage {
+		opts := imdfOptions
+		opts.Venue = venue
+		p, err := IMDF([]IMDFLevel{{Doc: loadPlan(t, "full.html"), Name: "Перший поверх", ShortName: "1"}}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	ids := func(p *IMDFPackage) []string {
+		var out []string
+		for _, kind := range []string{"address", "venue", "building", "footprint", "level", "unit", "opening", "amenity"} {
+			for _, f := range imdfFeatures(t, p, kind) {
+				out = append(out, f.ID)
+			}
+		}
+		return out
+	}
+
+	first, second := ids(build("Бізнес-центр")), ids(build("Бізнес-центр"))
+	if !slices.Equal(first, second) {
+		t.Error("повторний експорт дав інші id")
+	}
+	for _, id := range first {
+		if !uuidRe.MatchString(id) || id[14] != '5' || !strings.ContainsAny(id[19:20], "89ab") {
+			t.Errorf("id %s не є UUID версії 5", id)
+		}
+	}
+	if other := ids(build("Інший заклад")); len(other) != len(first) || other[0] == first[0] {
+		t.Error("id мають залежати від назви закладу")
+	}
+}
+
+func TestIMDFAmenityUnits(t *testing.T) {
+	d := parseSVG(t, `<svg viewBox="0 0 400 200">
+		<style>
+			.wall { stroke: #000; stroke-width: 4; }
+			.room-name { font-size: 10px; }
+		</style>
+		<polygon class="outline" points="0,0 300,0 300,100 0,100"/>
+		<polyline class="wall" points="0,0 100,0 100,100 0,100 0,0"/>
+		<polygon class="room" points="100,0 300,0 300,100 100,100"/>
+		<text class="room-name" x="150" y="50">кухня</text>
+		<use href="#toilet" x="20" y="20" width="20" height="20"/>
+		<use href="#sink" x="60" y="60" width="20" height="20"/>
+		<use href="#fire-extinguisher" x="250" y="20" width="20" height="20"/>
+		<use href="#exit-sign" x="250" y="60" width="20" height="20"/>
+		<use href="#first-aid" x="350" y="150" width="20" height="20"/>
+	</svg>`)
+	p, err := IMDF([]IMDFLevel{{Doc: d, Name: "Перший поверх", ShortName: "1"}}, imdfOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	units := make(map[string]Feature)
+	for _, f := range imdfFeatures(t, p, "unit") {
+		units[f.ID] = f
+	}
+	if len(units) != 2 {
+		t.Fatalf("очікувалось 2 приміщення (кухня і вбиральня без підпису), отримано %d", len(units))
+	}
+	amenities := imdfFeatures(t, p, "amenity")
+	// Знак виходу не є зручністю, аптечка поза приміщеннями пропускається з попередженням
+	if len(amenities) != 3 {
+		t.Fatalf("очікувалось 3 зручності, отримано %d", len(amenities))
+	}
+	unitOf := func(a Feature) Feature { return units[a.Properties["unit_ids"].([]string)[0]] }
+
+	toilet, sink, extinguisher := amenities[0], amenities[1], amenities[2]
+	if toilet.Properties["category"] != "restroom" || unitOf(toilet).Properties["category"] != "restroom" || unitOf(toilet).Properties["name"] != nil {
+		t.Errorf("унітаз у кімнаті без підпису має створити вбиральню без назви: %v, %v", toilet.Properties, unitOf(toilet).Properties)
+	}
+	if unitOf(sink).ID != unitOf(toilet).ID {
+		t.Error("друге обладнання в тій самій області має потрапити в те саме приміщення")
+	}
+	if name, _ := unitOf(extinguisher).Properties["name"].(map[string]string); extinguisher.Properties["category"] != "fireextinguisher" || name["uk"] != "кухня" {
+		t.Errorf("вогнегасник має належати кухні: %v, %v", extinguisher.Properties, name)
+	}
+	if len(p.Warnings) != 1 || !strings.Contains(p.Warnings[0], "first-aid") {
+		t.Errorf("попередження: %q", p.Warnings)
+	}
+}
+
+func TestValidateIMDFPrincipalOpenings(t *testing.T) {
+	p, err := IMDF([]IMDFLevel{{Doc: loadPlan(t, "full.html"), Name: "Перший поверх", ShortName: "1"}}, imdfOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openings := imdfFeatures(t, p, "opening")
+	var principal int
+	for _, f := range openings {
+		if f.Properties["category"] == "pedestrian.principal" {
+			principal++
+		}
+	}
+	if principal == 0 || principal > imdfMaxPrincipalOpenings {
+		t.Fatalf("на full.html %d головних входів з %d прорізів", principal, len(openings))
+	}
+
+	// Усі двері поверху позначено головними входами - так буває, коли внутрішні двері прийнято за виходи
+	for _, f := range openings {
+		f.Properties["category"] = "pedestrian.principal"
+	}
+	if err := ValidateIMDF(p); err == nil || !strings.Contains(err.Error(), "позначено головними входами") {
+		t.Fatalf("очікувалась помилка про головні входи, отримано %v", err)
+	}
+}
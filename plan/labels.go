@@ -0,0 +1,365 @@
+package plan
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Параметри розміщення підписів за замовчуванням.
+const (
+	defaultLabelCell   = 5.0
+	defaultMinFontSize = 8.0
+	defaultDoorGap     = 100.0
+)
+
+// LabelOptions - параметри автоматичного розміщення підписів кімнат.
+type LabelOptions struct {
+	// Cell - крок сітки в одиницях viewBox; 0 - 5
+	Cell float64 `json:"cell,omitempty"`
+	// Shrink - зменшувати font-size, якщо підпис не вміщується в кімнату
+	Shrink bool `json:"shrink,omitempty"`
+	// MinFontSize - найменший розмір шрифту при зменшенні; 0 - 8
+	MinFontSize float64 `json:"min_font_size,omitempty"`
+	// DoorGap - найбільший розрив між стінами на одній лінії, який вважається дверним прорізом; 0 - 100
+	DoorGap float64 `json:"door_gap,omitempty"`
+}
+
+// LabelPlacement - результат розміщення одного підпису .room-name.
+type LabelPlacement struct {
+	Label string `json:"label"`
+	Path  string `json:"path"`
+	// X, Y - центр підпису в координатах viewBox
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	FontSize float64 `json:"font_size"`
+	// Placed - false, якщо підпис не лежить у замкненій кімнаті (наприклад, "Вихід" надворі) і лишився на місці
+	Placed bool `json:"placed"`
+	// Fits - підпис повністю вміщується в кімнату
+	Fits bool `json:"fits"`
+}
+
+// PlaceRoomLabels розміщує підписи .room-name у візуальних центрах кімнат.
+// Кімната підпису - область, в якій лежить його центр, обмежена стінами, контурами .outline
+// і дверима; дверні прорізи (лінії .doors і розриви до opts.DoorGap між стінами на одній прямій)
+// вважаються закритими. Підпис ставиться в полюс недосяжності - точку, найвіддаленішу від стін;
+// якщо там він не вміщується, обирається найвіддаленіша точка, де вміщується, а з opts.Shrink
+// зменшується font-size. Підписи поза замкненими кімнатами лишаються на місці.
+func PlaceRoomLabels(d *Document, opts LabelOptions) []LabelPlacement {
+	if opts.Cell <= 0 {
+		opts.Cell = defaultLabelCell
+	}
+	if opts.MinFontSize <= 0 {
+		opts.MinFontSize = defaultMinFontSize
+	}
+	if opts.DoorGap <= 0 {
+		opts.DoorGap = defaultDoorGap
+	}
+	svg := d.SVG
+	styles := parseClassStyles(svg)
+	g, _, doors := wallGrid(svg, opts.Cell, opts.Cell)
+	for _, s := range append(doors, wallGaps(svg, opts.DoorGap)...) {
+		g.markSegment(s, opts.Cell, true)
+	}
+	rooms := newRoomMap(g)
+
+	var out []LabelPlacement
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		if n.Data == "defs" || n.Data == "symbol" {
+			return true
+		}
+		if n.Data == "text" && hasClass(n, "room-name") {
+			out = append(out, rooms.place(n, styles, opts))
+		}
+		return false
+	})
+	return out
+}
+
+// wallGaps повертає відрізки, що закривають розриви до maxGap між горизонтальними
+// чи вертикальними стінами на одній прямій: так на планах позначаються дверні прорізи.
+func wallGaps(svg *html.Node, maxGap float64) []segment {
+	// Стіни зводяться до інтервалів на прямих: ключ - напрям і координата прямої
+	type line struct {
+		vertical bool
+		at       float64
+	}
+	spans := make(map[line][][2]float64)
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		if n.Data == "defs" || n.Data == "symbol" {
+			return true
+		}
+		if !isWall(n) {
+			return false
+		}
+		for _, s := range wallEdges(n) {
+			switch {
+			case math.Abs(s.A.X-s.B.X) <= 0.5:
+				l := line{true, math.Round(s.A.X)}
+				spans[l] = append(spans[l], [2]float64{math.Min(s.A.Y, s.B.Y), math.Max(s.A.Y, s.B.Y)})
+			case math.Abs(s.A.Y-s.B.Y) <= 0.5:
+				l := line{false, math.Round(s.A.Y)}
+				spans[l] = append(spans[l], [2]float64{math.Min(s.A.X, s.B.X), math.Max(s.A.X, s.B.X)})
+			}
+		}
+		return false
+	})
+
+	var gaps []segment
+	for l, list := range spans {
+		sort.Slice(list, func(i, j int) bool { return list[i][0] < list[j][0] })
+		end := list[0][1]
+		for _, sp := range list[1:] {
+			if gap := sp[0] - end; gap > 0 && gap <= maxGap {
+				if l.vertical {
+					gaps = append(gaps, segment{point{l.at, end}, point{l.at, sp[0]}})
+				} else {
+					gaps = append(gaps, segment{point{end, l.at}, point{sp[0], l.at}})
+				}
+			}
+			end = max(end, sp[1])
+		}
+	}
+	return gaps
+}
+
+// roomMap - розбиття вільних комірок сітки на кімнати (зв'язні області).
+type roomMap struct {
+	g     *grid
+	room  []int // номер кімнати комірки, -1 - ще не визначено або заблоковано
+	rooms []*roomArea
+}
+
+// roomArea - одна кімната на сітці.
+type roomArea struct {
+	// order - комірки від найвіддаленішої від стін (першою йде полюс недосяжності)
+	order []int
+	// open - область доходить до краю сітки, тобто не замкнена стінами
+	open bool
+	// taken - комірки, вже зайняті розміщеними підписами
+	taken map[int]bool
+}
+
+// newRoomMap створює порожнє розбиття; кімнати визначаються при першому зверненні.
+func newRoomMap(g *grid) *roomMap {
+	m := &roomMap{g: g, room: make([]int, g.w*g.h)}
+	for k := range m.room {
+		m.room[k] = -1
+	}
+	return m
+}
+
+// roomAt повертає номер кімнати, в якій лежить точка (або найближчої вільної комірки).
+func (m *roomMap) roomAt(p point) (int, bool) {
+	i, j, ok := m.g.nearestFree(p, 3)
+	if !ok {
+		return 0, false
+	}
+	k := j*m.g.w + i
+	if m.room[k] < 0 {
+		m.fill(k)
+	}
+	return m.room[k], true
+}
+
+// fill знаходить зв'язну область (4 напрямки) з комірки start і впорядковує її комірки
+// за відстанню до межі області; за однакової відстані ближчі до центру мас ідуть першими,
+// а далі - за номером комірки, щоб результат не залежав від початкового положення підпису.
+func (m *roomMap) fill(start int) {
+	g := m.g
+	id := len(m.rooms)
+	area := &roomArea{taken: make(map[int]bool)}
+	m.rooms = append(m.rooms, area)
+
+	cells, open := g.flood(start, m.room, id)
+	area.open = open
+	var sum point
+	for _, k := range cells {
+		c := g.center(k%g.w, k/g.w)
+		sum.X += c.X
+		sum.Y += c.Y
+	}
+	centroid := point{sum.X / float64(len(cells)), sum.Y / float64(len(cells))}
+
+	depth := m.depth(id, cells)
+	sort.Slice(cells, func(a, b int) bool {
+		if depth[cells[a]] != depth[cells[b]] {
+			return depth[cells[a]] > depth[cells[b]]
+		}
+		da := dist(g.center(cells[a]%g.w, cells[a]/g.w), centroid)
+		db := dist(g.center(cells[b]%g.w, cells[b]/g.w), centroid)
+		if da != db {
+			return da < db
+		}
+		return cells[a] < cells[b]
+	})
+	area.order = cells
+}
+
+// depth обчислює для кожної комірки кімнати відстань (у комірках) до її межі:
+// Дейкстра з усіх прикордонних комірок з вагами 1 і √2.
+func (m *roomMap) depth(id int, cells []int) map[int]float64 {
+	g := m.g
+	inside := func(i, j int) bool {
+		return i >= 0 && j >= 0 && i < g.w && j < g.h && m.room[j*g.w+i] == id
+	}
+	depth := make(map[int]float64, len(cells))
+	queue := &pathQueue{}
+	for _, k := range cells {
+		i, j := k%g.w, k/g.w
+		depth[k] = math.Inf(1)
+		if !inside(i+1, j) || !inside(i-1, j) || !inside(i, j+1) || !inside(i, j-1) {
+			depth[k] = 1
+			queue.push(k, 1)
+		}
+	}
+	for queue.len() > 0 {
+		k, d := queue.pop()
+		if d > depth[k] {
+			continue
+		}
+		i, j := k%g.w, k/g.w
+		for dj := -1; dj <= 1; dj++ {
+			for di := -1; di <= 1; di++ {
+				if (di == 0 && dj == 0) || !inside(i+di, j+dj) {
+					continue
+				}
+				step := 1.0
+				if di != 0 && dj != 0 {
+					step = math.Sqrt2
+				}
+				nk := (j+dj)*g.w + i + di
+				if nd := d + step; nd < depth[nk] {
+					depth[nk] = nd
+					queue.push(nk, nd)
+				}
+			}
+		}
+	}
+	return depth
+}
+
+// boxCells повертає комірки, які перекриває прямокутник w×h з центром c.
+func (m *roomMap) boxCells(c point, w, h float64) []int {
+	g := m.g
+	i0, j0, _ := g.cellOf(point{c.X - w/2, c.Y - h/2})
+	i1, j1, _ := g.cellOf(point{c.X + w/2, c.Y + h/2})
+	var cells []int
+	for j := j0; j <= j1; j++ {
+		for i := i0; i <= i1; i++ {
+			if i < 0 || j < 0 || i >= g.w || j >= g.h {
+				return nil
+			}
+			cells = append(cells, j*g.w+i)
+		}
+	}
+	return cells
+}
+
+// bestCell шукає найвіддаленішу від стін комірку кімнати, в яку вміщується підпис w×h,
+// не перекриваючи вже розміщених.
+func (m *roomMap) bestCell(id int, w, h float64) (int, bool) {
+	area := m.rooms[id]
+	for _, k := range area.order {
+		cells := m.boxCells(m.g.center(k%m.g.w, k/m.g.w), w, h)
+		fits := len(cells) > 0
+		for _, c := range cells {
+			if m.room[c] != id || area.taken[c] {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// place переносить підпис у найкращу точку його кімнати.
+func (m *roomMap) place(n *html.Node, styles classStyles, opts LabelOptions) LabelPlacement {
+	b := textBox(n, styles)
+	center := b.center()
+	size := styles.fontSize(n)
+	res := LabelPlacement{Label: textContent(n), Path: elementPath(n), X: center.X, Y: center.Y, FontSize: size}
+
+	id, ok := m.roomAt(center)
+	if !ok || m.rooms[id].open || len(m.rooms[id].order) == 0 {
+		return res
+	}
+	area := m.rooms[id]
+
+	k, fits := m.bestCell(id, b.W, b.H)
+	newSize := size
+	if !fits && opts.Shrink {
+		for s := math.Floor(size - 1); s >= opts.MinFontSize; s-- {
+			if sk, ok := m.bestCell(id, b.W*s/size, b.H*s/size); ok {
+				k, fits, newSize = sk, true, s
+				break
+			}
+		}
+	}
+	if !fits {
+		k = area.order[0]
+	}
+
+	c := m.g.center(k%m.g.w, k/m.g.w)
+	for _, cell := range m.boxCells(c, b.W*newSize/size, b.H*newSize/size) {
+		area.taken[cell] = true
+	}
+	if newSize != size {
+		setFontSize(n, newSize)
+	}
+	moveText(n, c, styles)
+
+	res.X, res.Y, res.FontSize = c.X, c.Y, newSize
+	res.Placed, res.Fits = true, fits
+	return res
+}
+
+// moveText ставить текст так, щоб центр його рамки (див. textBox) потрапив у точку c
+// в координатах viewBox; text-anchor елемента не змінюється.
+func moveText(n *html.Node, c point, styles classStyles) {
+	inv, ok := nodeTransform(n).invert()
+	if !ok {
+		return
+	}
+	local := inv.apply(c)
+	size := styles.fontSize(n)
+	w := estimateTextWidth(textContent(n), size, styles.isBold(n))
+
+	x := local.X - w/2
+	switch styles.property(n, "text-anchor") {
+	case "middle":
+		x = local.X
+	case "end":
+		x = local.X + w/2
+	}
+	// Рамка тексту - від 0.8 font-size над базовою лінією до 0.2 під нею
+	setAttr(n, "x", formatNumber(x))
+	setAttr(n, "y", formatNumber(local.Y+size*0.3))
+}
+
+// setFontSize змінює розмір шрифту тексту: в атрибуті font-size, якщо він є, інакше в inline style.
+func setFontSize(n *html.Node, size float64) {
+	value := formatNumber(size) + "px"
+	if getAttr(n, "font-size") != "" {
+		setAttr(n, "font-size", value)
+		return
+	}
+	var decls []string
+	for _, decl := range strings.Split(getAttr(n, "style"), ";") {
+		if k, _, ok := strings.Cut(decl, ":"); ok && strings.TrimSpace(k) != "font-size" {
+			decls = append(decls, strings.TrimSpace(decl))
+		}
+	}
+	setAttr(n, "style", strings.Join(append(decls, "font-size: "+value), "; "))
+}
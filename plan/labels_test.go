@@ -0,0 +1,88 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+)
+
+// labelsStyle - стилі контурів і підписів для тестових планів.
+const labelsStyle = `<style>
+	.outline { fill: #FFF; stroke: #000; stroke-width: 4; }
+	.wall { stroke: #000; stroke-width: 4; }
+	.room-name { font-size: 10px; }
+</style>`
+
+func TestPlaceRoomLabelsLShape(t *testing.T) {
+	lShape := []point{{0, 0}, {200, 0}, {200, 60}, {60, 60}, {60, 200}, {0, 200}}
+	d := parseSVG(t, `<svg viewBox="-20 -20 260 260">`+labelsStyle+`
+		<polygon class="outline" points="0,0 200,0 200,60 60,60 60,200 0,200"/>
+		<text class="room-name" x="150" y="30">зал</text>
+	</svg>`)
+
+	placements := PlaceRoomLabels(d, LabelOptions{})
+	if len(placements) != 1 {
+		t.Fatalf("очікувався 1 підпис, отримано %d", len(placements))
+	}
+	p := placements[0]
+	if !p.Placed || !p.Fits {
+		t.Fatalf("підпис не розміщено: %+v", p)
+	}
+	// Полюс недосяжності L-подібної кімнати - у куті, де сходяться крила (близько 35,35),
+	// а не в центрі рамки (100,100), що лежить поза кімнатою
+	c := point{p.X, p.Y}
+	if !pointInPolygon(c, lShape) {
+		t.Fatalf("підпис поставлено поза кімнатою: (%v, %v)", p.X, p.Y)
+	}
+	if dist(c, point{35, 35}) > 10 {
+		t.Errorf("підпис у (%v, %v), очікувався полюс недосяжності близько (35, 35)", p.X, p.Y)
+	}
+	text := elementsByTag(d, "text")[0]
+	if box := textBox(text, parseClassStyles(d.SVG)); dist(box.center(), c) > 1 {
+		t.Errorf("текст не перенесено: центр рамки %v, очікувався %v", box.center(), c)
+	}
+}
+
+func TestPlaceRoomLabelsTooSmall(t *testing.T) {
+	room := `<svg viewBox="-10 -10 200 100">` + labelsStyle + `
+		<polygon class="outline" points="0,0 40,0 40,20 0,20"/>
+		<text class="room-name" x="20" y="14" text-anchor="middle">електрощитова кімната</text>
+		<text class="room-name" x="150" y="80">Вихід</text>
+	</svg>`
+
+	placements := PlaceRoomLabels(parseSVG(t, room), LabelOptions{})
+	if len(placements) != 2 {
+		t.Fatalf("очікувалось 2 підписи, отримано %d", len(placements))
+	}
+	small, outside := placements[0], placements[1]
+	if !small.Placed || small.Fits || small.FontSize != 10 {
+		t.Errorf("підпис, що не вміщується, має лишитися в кімнаті без зміни шрифту: %+v", small)
+	}
+	if outside.Placed || outside.X != 150+estimateTextWidth("Вихід", 10, false)/2 {
+		t.Errorf("підпис поза кімнатою не має рухатися: %+v", outside)
+	}
+
+	// Підпис не вміщується навіть найменшим шрифтом - розмір не змінюється
+	shrunk := PlaceRoomLabels(parseSVG(t, room), LabelOptions{Shrink: true})
+	if shrunk[0].Fits || shrunk[0].FontSize != 10 {
+		t.Errorf("-shrink не має змінювати шрифт, якщо підпис не вміщується й найменшим: %+v", shrunk[0])
+	}
+}
+
+func TestPlaceRoomLabelsShrink(t *testing.T) {
+	corridor := `<svg viewBox="-10 -10 200 100">` + labelsStyle + `
+		<polygon class="outline" points="0,0 120,0 120,40 0,40"/>
+		<text class="room-name" x="10" y="30" font-size="30px">коридор</text>
+	</svg>`
+
+	if p := PlaceRoomLabels(parseSVG(t, corridor), LabelOptions{}); p[0].Fits {
+		t.Fatalf("без -shrink підпис 30px не мав би вміститися: %+v", p[0])
+	}
+	d := parseSVG(t, corridor)
+	p := PlaceRoomLabels(d, LabelOptions{Shrink: true})[0]
+	if !p.Fits || p.FontSize >= 30 || p.FontSize < defaultMinFontSize {
+		t.Fatalf("підпис мав зменшитися й вміститися: %+v", p)
+	}
+	if got := getAttr(elementsByTag(d, "text")[0], "font-size"); !strings.HasSuffix(got, "px") || got == "30px" {
+		t.Errorf("font-size не оновлено: %q", got)
+	}
+}
@@ -0,0 +1,321 @@
+package plan
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Розміри елементів легенди в одиницях viewBox.
+const (
+	legendPadding    = 15.0
+	legendTitleSize  = 18.0
+	legendTextSize   = 15.0
+	legendTitleH     = 35.0
+	legendIconBox    = 30.0
+	legendRowHeight  = 40.0
+	legendIconGap    = 20.0
+	legendColumnGap  = 30.0
+	legendPageMargin = 20.0
+)
+
+// DefaultLegendTitle - заголовок легенди за замовчуванням.
+const DefaultLegendTitle = "УМОВНІ ПОЗНАЧЕННЯ:"
+
+// DefaultSymbolNames містить підписи для символів, що використовуються в наших планах.
+var DefaultSymbolNames = map[string]string{
+	"toilet":            "Унітаз",
+	"sink":              "Умивальник",
+	"electrical-panel":  "Електрощиток",
+	"fire-extinguisher": "Вогнегасник",
+	"exit-sign":         "Евакуаційний вихід",
+	"you-are-here":      "Ви перебуваєте тут",
+	"shower-cabin":      "Душова кабіна",
+
+	// Знаки безпеки з вбудованої бібліотеки (ISO 7010)
+	"fire-hydrant":         "Пожежний кран",
+	"fire-alarm-button":    "Кнопка пожежної сигналізації",
+	"first-aid":            "Аптечка першої допомоги",
+	"assembly-point":       "Місце збору",
+	"emergency-exit-left":  "Евакуаційний вихід ліворуч",
+	"emergency-exit-right": "Евакуаційний вихід праворуч",
+}
+
+// defaultLegendStyles - стилі класів легенди, які додаються, якщо документ їх не визначає.
+var defaultLegendStyles = map[string]string{
+	"legend-frame": ".legend-frame { fill: #FFF; stroke: #000; stroke-width: 2; }",
+	"legend-title": ".legend-title { font-family: Arial; font-size: 18px; font-weight: bold; fill: #000; }",
+	"legend-text":  ".legend-text { font-family: Arial; font-size: 15px; fill: #000; }",
+}
+
+// LegendPlacements - допустимі місця розміщення легенди.
+var LegendPlacements = []string{
+	"bottom-left", "bottom-right", "top-left", "top-right",
+	"left", "right", "top", "bottom",
+}
+
+// LegendOptions налаштовує генерацію легенди.
+type LegendOptions struct {
+	Title     string            // заголовок легенди
+	Names     map[string]string // підписи символів (id → текст), мають пріоритет над стандартними
+	Order     []string          // бажаний порядок id символів; решта йде в порядку появи на плані
+	Placement string            // кут або сторона: bottom-left, top-right, left, bottom...
+	Columns   int               // кількість колонок (мінімум 1)
+}
+
+// legendEntry - один рядок легенди.
+type legendEntry struct {
+	SymbolID string
+	Label    string
+	Width    float64 // розмір іконки, вписаної в legendIconBox
+	Height   float64
+}
+
+// GenerateLegend будує блок <g id="legend"> із символів, на які посилаються <use> елементи плану.
+// Існуючу легенду (якщо є) замінює новою. Повертає кількість позицій легенди.
+func GenerateLegend(d *Document, opts LegendOptions) (int, error) {
+	svg := d.SVG
+	if !validPlacement(opts.Placement) {
+		return 0, Errorf("невідоме розміщення легенди %q (допустимі: %s)", opts.Placement, strings.Join(LegendPlacements, ", "))
+	}
+	if opts.Columns < 1 {
+		opts.Columns = 1
+	}
+	if opts.Title == "" {
+		opts.Title = DefaultLegendTitle
+	}
+
+	vx, vy, vw, vh, ok := parseViewBox(svg)
+	if !ok {
+		return 0, Errorf("SVG не має коректного viewBox або width/height")
+	}
+
+	oldLegend := findElementByID(svg, "legend")
+	entries := collectLegendEntries(svg, oldLegend, opts)
+	if len(entries) == 0 {
+		return 0, Errorf("на плані не використовується жодного символу з <defs>")
+	}
+
+	legend, w, h := layoutLegend(entries, opts)
+	x, y := placeLegend(opts.Placement, vx, vy, vw, vh, w, h)
+	setAttr(legend, "transform", fmt.Sprintf("translate(%s, %s)", formatNumber(x), formatNumber(y)))
+
+	classes := []string{"legend-frame", "legend-title", "legend-text"}
+	if style := missingStyles(svg, classes, defaultLegendStyles); style != nil {
+		legend.InsertBefore(style, legend.FirstChild)
+	}
+
+	if oldLegend != nil && oldLegend.Parent != nil {
+		oldLegend.Parent.InsertBefore(legend, oldLegend)
+		oldLegend.Parent.RemoveChild(oldLegend)
+	} else {
+		svg.AppendChild(legend)
+	}
+
+	return len(entries), nil
+}
+
+// collectLegendEntries знаходить символи, реально використані на плані (поза старою легендою),
+// та впорядковує їх згідно з opts.Order.
+func collectLegendEntries(svg, oldLegend *html.Node, opts LegendOptions) []legendEntry {
+	symbols := make(map[string]*html.Node)
+	var used []string
+	seen := make(map[string]bool)
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n == oldLegend {
+			return
+		}
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "symbol":
+				if id := getAttr(n, "id"); id != "" {
+					symbols[id] = n
+				}
+			case "use":
+				if id := useHref(n); id != "" && !seen[id] {
+					seen[id] = true
+					used = append(used, id)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(svg)
+
+	// Спочатку символи з явного порядку, потім решта в порядку появи
+	var ordered []string
+	placed := make(map[string]bool)
+	for _, id := range opts.Order {
+		if seen[id] && !placed[id] {
+			ordered = append(ordered, id)
+			placed[id] = true
+		}
+	}
+	for _, id := range used {
+		if !placed[id] {
+			ordered = append(ordered, id)
+			placed[id] = true
+		}
+	}
+
+	var entries []legendEntry
+	for _, id := range ordered {
+		symbol, ok := symbols[id]
+		if !ok {
+			continue
+		}
+		w, h := fitSymbol(symbol, legendIconBox)
+		entries = append(entries, legendEntry{
+			SymbolID: id,
+			Label:    symbolLabel(symbol, id, opts.Names),
+			Width:    w,
+			Height:   h,
+		})
+	}
+	return entries
+}
+
+// symbolLabel визначає підпис символу: з налаштувань, зі стандартного словника,
+// з коментаря перед <symbol> у <defs>, або id як крайній випадок.
+func symbolLabel(symbol *html.Node, id string, names map[string]string) string {
+	if label, ok := names[id]; ok {
+		return label
+	}
+	if label, ok := DefaultSymbolNames[id]; ok {
+		return label
+	}
+	for p := symbol.PrevSibling; p != nil; p = p.PrevSibling {
+		if p.Type == html.TextNode && strings.TrimSpace(p.Data) == "" {
+			continue
+		}
+		if p.Type == html.CommentNode {
+			comment := strings.Trim(strings.TrimSpace(p.Data), "\"")
+			if comment != "" && !strings.Contains(comment, "==") {
+				return comment
+			}
+		}
+		break
+	}
+	return id
+}
+
+// fitSymbol вписує символ у квадрат size зі збереженням пропорцій його viewBox.
+func fitSymbol(symbol *html.Node, size float64) (float64, float64) {
+	_, _, w, h, ok := parseViewBox(symbol)
+	if !ok || w <= 0 || h <= 0 {
+		return size, size
+	}
+	scale := size / math.Max(w, h)
+	return w * scale, h * scale
+}
+
+// layoutLegend розкладає позиції по колонках і повертає групу легенди та її розміри.
+func layoutLegend(entries []legendEntry, opts LegendOptions) (*html.Node, float64, float64) {
+	rows := (len(entries) + opts.Columns - 1) / opts.Columns
+
+	// Ширина кожної колонки визначається найдовшим підписом у ній
+	var colWidths []float64
+	for start := 0; start < len(entries); start += rows {
+		end := min(start+rows, len(entries))
+		var textW float64
+		for _, e := range entries[start:end] {
+			textW = math.Max(textW, estimateTextWidth(e.Label, legendTextSize, false))
+		}
+		colWidths = append(colWidths, legendIconBox+legendIconGap+textW)
+	}
+
+	contentW := 0.0
+	for i, cw := range colWidths {
+		if i > 0 {
+			contentW += legendColumnGap
+		}
+		contentW += cw
+	}
+	contentW = math.Max(contentW, estimateTextWidth(opts.Title, legendTitleSize, true))
+
+	width := math.Ceil(contentW + 2*legendPadding)
+	height := math.Ceil(legendPadding + legendTitleH + float64(rows-1)*legendRowHeight + legendIconBox + legendPadding)
+
+	g := newElement("g", "id", "legend")
+	g.AppendChild(newElement("rect",
+		"x", "0", "y", "0",
+		"width", formatNumber(width), "height", formatNumber(height),
+		"class", "legend-frame"))
+	g.AppendChild(newTextElement("text", opts.Title,
+		"x", formatNumber(legendPadding), "y", formatNumber(legendPadding+legendTitleSize),
+		"class", "legend-title"))
+
+	colX := legendPadding
+	for col, cw := range colWidths {
+		for row := 0; row < rows; row++ {
+			i := col*rows + row
+			if i >= len(entries) {
+				break
+			}
+			e := entries[i]
+			rowY := legendPadding + legendTitleH + float64(row)*legendRowHeight
+
+			g.AppendChild(newElement("use",
+				"href", "#"+e.SymbolID,
+				"x", formatNumber(colX+(legendIconBox-e.Width)/2),
+				"y", formatNumber(rowY+(legendIconBox-e.Height)/2),
+				"width", formatNumber(e.Width),
+				"height", formatNumber(e.Height)))
+			g.AppendChild(newTextElement("text", e.Label,
+				"x", formatNumber(colX+legendIconBox+legendIconGap),
+				"y", formatNumber(rowY+legendIconBox/2+legendTextSize/3),
+				"class", "legend-text"))
+		}
+		colX += cw + legendColumnGap
+	}
+
+	return g, width, height
+}
+
+// placeLegend обчислює верхній лівий кут легенди для заданого розміщення.
+func placeLegend(placement string, vx, vy, vw, vh, w, h float64) (float64, float64) {
+	left := vx + legendPageMargin
+	right := vx + vw - legendPageMargin - w
+	top := vy + legendPageMargin
+	bottom := vy + vh - legendPageMargin - h
+	centerX := vx + (vw-w)/2
+	centerY := vy + (vh-h)/2
+
+	switch placement {
+	case "top-left":
+		return left, top
+	case "top-right":
+		return right, top
+	case "bottom-right":
+		return right, bottom
+	case "left":
+		return left, centerY
+	case "right":
+		return right, centerY
+	case "top":
+		return centerX, top
+	case "bottom":
+		return centerX, bottom
+	default: // bottom-left
+		return left, bottom
+	}
+}
+
+// validPlacement перевіряє, чи підтримується розміщення (порожнє означає bottom-left).
+func validPlacement(p string) bool {
+	if p == "" {
+		return true
+	}
+	for _, v := range LegendPlacements {
+		if v == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// legendPlan - план із трьома символами, з яких використано лише два.
+const legendPlan = `<svg viewBox="0 0 800 600">
+	<defs>
+		<symbol id="toilet" viewBox="0 0 20 40"><rect width="20" height="40"/></symbol>
+		<!-- Кавоварка -->
+		<symbol id="coffee" viewBox="0 0 10 10"><circle cx="5" cy="5" r="5"/></symbol>
+		<symbol id="unused" viewBox="0 0 10 10"><rect width="10" height="10"/></symbol>
+	</defs>
+	<use href="#coffee" x="10" y="10" width="20" height="20"/>
+	<use href="#toilet" x="50" y="10" width="20" height="40"/>
+	<use href="#coffee" x="90" y="10" width="20" height="20"/>
+	<use href="#missing" x="130" y="10" width="20" height="20"/>
+</svg>`
+
+// legendLabels повертає підписи позицій легенди по порядку.
+func legendLabels(legend *html.Node) []string {
+	var labels []string
+	traverse(legend, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "text" && hasClass(n, "legend-text") {
+			labels = append(labels, textContent(n))
+		}
+		return false
+	})
+	return labels
+}
+
+func TestGenerateLegend(t *testing.T) {
+	d := parseSVG(t, legendPlan)
+	n, err := GenerateLegend(d, LegendOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Невикористаний символ і посилання на відсутній символ у легенду не потрапляють
+	if n != 2 {
+		t.Errorf("позицій легенди %d, очікувалось 2", n)
+	}
+	legend := findElementByID(d.SVG, "legend")
+	if legend == nil {
+		t.Fatal("легенду не додано")
+	}
+	// Порядок появи на плані; підпис зі словника, з коментаря перед символом
+	if got := strings.Join(legendLabels(legend), "|"); got != "Кавоварка|Унітаз" {
+		t.Errorf("підписи легенди: %q", got)
+	}
+	// Іконка вписується в квадрат зі збереженням пропорцій viewBox
+	for _, u := range elementsByTag(d, "use") {
+		if u.Parent == legend && useHref(u) == "toilet" && (getAttr(u, "width") != "15" || getAttr(u, "height") != "30") {
+			t.Errorf("іконка унітаза %sx%s, очікувалось 15x30", getAttr(u, "width"), getAttr(u, "height"))
+		}
+	}
+	if len(elementsByTag(d, "style")) != 1 {
+		t.Error("стилі легенди не додано")
+	}
+
+	// Повторна генерація замінює легенду, а не дублює її, і не рахує її власні <use>
+	n, err = GenerateLegend(d, LegendOptions{
+		Order: []string{"toilet"},
+		Names: map[string]string{"coffee": "Кава"},
+		Title: "Легенда",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var legends int
+	traverse(d.SVG, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && getAttr(n, "id") == "legend" {
+			legends++
+		}
+		return false
+	})
+	if legends != 1 || n != 2 {
+		t.Errorf("після повторної генерації легенд %d, позицій %d", legends, n)
+	}
+	legend = findElementByID(d.SVG, "legend")
+	if got := strings.Join(legendLabels(legend), "|"); got != "Унітаз|Кава" {
+		t.Errorf("підписи з явним порядком і власними назвами: %q", got)
+	}
+}
+
+func TestGenerateLegendPlacement(t *testing.T) {
+	// Рамка легенди: дві позиції в одну колонку - 15+35+40+30+15 = 135 заввишки, ширина за підписами
+	cases := []struct {
+		placement string
+		x, y      func(w, h float64) float64
+	}{
+		{"", func(w, h float64) float64 { return 20 }, func(w, h float64) float64 { return 600 - 20 - h }},
+		{"top-left", func(w, h float64) float64 { return 20 }, func(w, h float64) float64 { return 20 }},
+		{"bottom-right", func(w, h float64) float64 { return 800 - 20 - w }, func(w, h float64) float64 { return 600 - 20 - h }},
+		{"top", func(w, h float64) float64 { return (800 - w) / 2 }, func(w, h float64) float64 { return 20 }},
+		{"left", func(w, h float64) float64 { return 20 }, func(w, h float64) float64 { return (600 - h) / 2 }},
+	}
+	for _, c := range cases {
+		d := parseSVG(t, legendPlan)
+		if _, err := GenerateLegend(d, LegendOptions{Placement: c.placement}); err != nil {
+			t.Fatal(err)
+		}
+		legend := findElementByID(d.SVG, "legend")
+		var frame *html.Node
+		for _, r := range elementsByTag(d, "rect") {
+			if hasClass(r, "legend-frame") {
+				frame = r
+			}
+		}
+		w, h := attrFloat(frame, "width"), attrFloat(frame, "height")
+		if h != 135 {
+			t.Errorf("висота легенди %v, очікувалось 135", h)
+		}
+		want := fmt.Sprintf("translate(%s, %s)", formatNumber(c.x(w, h)), formatNumber(c.y(w, h)))
+		if got := getAttr(legend, "transform"); got != want {
+			t.Errorf("розміщення %q: %s, очікувалось %s", c.placement, got, want)
+		}
+	}
+
+	if _, err := GenerateLegend(parseSVG(t, legendPlan), LegendOptions{Placement: "center"}); err == nil {
+		t.Error("невідоме розміщення має повертати помилку")
+	}
+	if _, err := GenerateLegend(parseSVG(t, `<svg viewBox="0 0 100 100"><rect width="10" height="10"/></svg>`), LegendOptions{}); err == nil {
+		t.Error("план без символів має повертати помилку")
+	}
+}
@@ -0,0 +1,439 @@
+package plan
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Допуски перевірок у одиницях viewBox.
+const (
+	lintWallTolerance = 5.0 // максимальна відстань дверей від лінії стіни
+	lintAxisTolerance = 0.5 // відхилення, за якого ребро ще вважається горизонтальним/вертикальним
+)
+
+// Severity - рівень серйозності діагностики.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic - одне повідомлення лінтера.
+type Diagnostic struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line,omitempty"`
+	Path     string   `json:"path"`
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+}
+
+// lintRule - правило перевірки плану.
+type lintRule struct {
+	ID          string
+	Description string
+	Check       func(*lintContext)
+}
+
+// lintRules - усі правила лінтера в порядку виконання.
+var lintRules = []lintRule{
+	{"door-off-wall", "двері не лежать на жодній стіні чи контурі", checkDoorsOnWalls},
+	{"polygon-invalid", "некоректний polygon (непарна кількість координат, менше 3 точок, нульова площа)", checkPolygonsValid},
+	{"polygon-not-closed", "контур не замикається явно або polyline/path не замкнений", checkPolygonsClosed},
+	{"label-outside-room", "підпис кімнати поза кімнатою, яку він називає", checkLabelsInside},
+	{"label-overlap", "підпис перекриває інший підпис, стіну чи двері", checkLabelOverlaps},
+	{"door-number-duplicate", "повторюваний номер дверей", checkDoorNumbersUnique},
+	{"door-number-invalid", "номер дверей не є числом", checkDoorNumbersNumeric},
+	{"symbol-undefined", "<use> посилається на невизначений символ", checkSymbolsDefined},
+	{"symbol-unused", "символ визначено, але не використано", checkSymbolsUsed},
+	{"class-undefined", "клас не визначено в жодному <style>", checkClassesDefined},
+}
+
+// lintContext містить розібраний план і збирає діагностики.
+type lintContext struct {
+	file   string
+	svg    *html.Node
+	styles classStyles
+	lines  map[*html.Node]int
+	diags  []Diagnostic
+
+	// Поточне правило, для якого збираються діагностики
+	rule string
+}
+
+// report додає діагностику для елемента.
+func (c *lintContext) report(n *html.Node, sev Severity, format string, args ...any) {
+	c.diags = append(c.diags, Diagnostic{
+		File:     c.file,
+		Line:     c.lines[n],
+		Path:     elementPath(n),
+		Severity: sev,
+		Rule:     c.rule,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// elements повертає всі елементи SVG з заданим тегом і (якщо не порожній) класом.
+func (c *lintContext) elements(tag, class string) []*html.Node {
+	var out []*html.Node
+	traverse(c.svg, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == tag && (class == "" || hasClass(n, class)) {
+			out = append(out, n)
+		}
+		return false
+	})
+	return out
+}
+
+// Lint перевіряє HTML/SVG документ і повертає діагностики, відсортовані за рядком.
+// disabled містить id правил, які треба пропустити.
+func Lint(file string, data []byte, disabled map[string]bool) ([]Diagnostic, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, Errorf("%w HTML: %w", ErrParse, err)
+	}
+	svg := findSVG(doc)
+	if svg == nil {
+		return nil, Errorf("%s: %w", file, ErrNoSVG)
+	}
+
+	ctx := &lintContext{
+		file:   file,
+		svg:    svg,
+		styles: parseClassStyles(svg),
+		lines:  sourceLines(data, doc),
+	}
+	for _, rule := range lintRules {
+		if disabled[rule.ID] {
+			continue
+		}
+		ctx.rule = rule.ID
+		rule.Check(ctx)
+	}
+
+	sort.SliceStable(ctx.diags, func(i, j int) bool {
+		return ctx.diags[i].Line < ctx.diags[j].Line
+	})
+	return ctx.diags, nil
+}
+
+// sourceLines зіставляє елементи дерева з номерами рядків початкових тегів у вихідному тексті.
+// Парсер не зберігає позиції, тому теги токенізуються окремо і зіставляються по порядку;
+// елементи, додані парсером неявно (html, head, body), пропускаються.
+func sourceLines(data []byte, doc *html.Node) map[*html.Node]int {
+	type tagPos struct {
+		name string
+		line int
+	}
+	var tags []tagPos
+
+	z := html.NewTokenizer(bytes.NewReader(data))
+	line := 1
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+		if tt == html.StartTagToken || tt == html.SelfClosingTagToken {
+			name, _ := z.TagName()
+			tags = append(tags, tagPos{strings.ToLower(string(name)), line})
+		}
+		line += bytes.Count(raw, []byte("\n"))
+	}
+
+	lines := make(map[*html.Node]int)
+	next := 0
+	traverse(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		name := strings.ToLower(n.Data)
+		for i := next; i < len(tags); i++ {
+			if tags[i].name == name {
+				lines[n] = tags[i].line
+				next = i + 1
+				break
+			}
+		}
+		return false
+	})
+	return lines
+}
+
+// wallSegments повертає всі стіни плану: лінії .wall та ребра контурів .outline.
+func (c *lintContext) wallSegments() []segment {
+	var walls []segment
+	for _, n := range c.elements("line", "wall") {
+		walls = append(walls, lineSegment(n))
+	}
+	for _, n := range c.elements("polyline", "wall") {
+		walls = append(walls, wallEdges(n)...)
+	}
+	for _, n := range c.elements("polygon", "outline") {
+		walls = append(walls, edges(polygonPoints(n))...)
+	}
+	return walls
+}
+
+// checkDoorsOnWalls перевіряє, що кожна лінія .doors лежить на прямій якоїсь стіни
+// і перетинається з нею або торкається її (двері вирізають проріз між двома відрізками стіни).
+func checkDoorsOnWalls(c *lintContext) {
+	walls := c.wallSegments()
+	for _, n := range c.elements("line", "doors") {
+		door := lineSegment(n)
+		if !doorOnWall(door, walls) {
+			c.report(n, SeverityError, "двері (%s,%s)-(%s,%s) не лежать на жодній стіні",
+				formatNumber(door.A.X), formatNumber(door.A.Y), formatNumber(door.B.X), formatNumber(door.B.Y))
+		}
+	}
+}
+
+// doorOnWall перевіряє, чи відрізок дверей колінеарний якійсь стіні та дотикається до неї.
+func doorOnWall(door segment, walls []segment) bool {
+	for _, w := range walls {
+		if w.length() == 0 {
+			continue
+		}
+		if w.distToLine(door.A) > lintWallTolerance || w.distToLine(door.B) > lintWallTolerance {
+			continue
+		}
+		t1, t2 := w.project(door.A), w.project(door.B)
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		if t2 >= -lintWallTolerance && t1 <= w.length()+lintWallTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPolygonsValid перевіряє координати всіх polygon.
+func checkPolygonsValid(c *lintContext) {
+	for _, n := range c.elements("polygon", "") {
+		pts, even := parsePoints(getAttr(n, "points"))
+		switch {
+		case !even:
+			c.report(n, SeverityError, "непарна кількість координат у points")
+		case len(pts) < 3:
+			c.report(n, SeverityError, "polygon має лише %d точок", len(pts))
+		case polygonArea(pts) == 0 && c.styles.property(n, "fill") != "none":
+			// Незафарбовані ламані (наприклад, блискавка в символі електрощитка) мають право на нульову площу
+			c.report(n, SeverityError, "polygon має нульову площу")
+		}
+	}
+}
+
+// checkPolygonsClosed шукає контури, які не замикаються явно.
+// Для polygon замикаюче ребро додається автоматично, тож якщо всі ребра контуру
+// горизонтальні чи вертикальні, а замикаюче - похиле, найімовірніше пропущено точку.
+func checkPolygonsClosed(c *lintContext) {
+	for _, n := range c.elements("polygon", "outline") {
+		pts, _ := parsePoints(getAttr(n, "points"))
+		if len(pts) < 3 {
+			continue
+		}
+		es := edges(pts)
+		closing := es[len(es)-1]
+		if closing.isAxisAligned(lintAxisTolerance) {
+			continue
+		}
+		orthogonal := true
+		for _, e := range es[:len(es)-1] {
+			if !e.isAxisAligned(lintAxisTolerance) {
+				orthogonal = false
+				break
+			}
+		}
+		if orthogonal {
+			c.report(n, SeverityWarning, "контур замикається похилим ребром (%s,%s)-(%s,%s); можливо, пропущено точку",
+				formatNumber(closing.A.X), formatNumber(closing.A.Y), formatNumber(closing.B.X), formatNumber(closing.B.Y))
+		}
+	}
+
+	for _, n := range c.elements("polyline", "outline") {
+		pts, _ := parsePoints(getAttr(n, "points"))
+		if len(pts) >= 2 && dist(pts[0], pts[len(pts)-1]) > lintAxisTolerance {
+			c.report(n, SeverityError, "polyline контуру не замкнена: початок (%s,%s), кінець (%s,%s)",
+				formatNumber(pts[0].X), formatNumber(pts[0].Y), formatNumber(pts[len(pts)-1].X), formatNumber(pts[len(pts)-1].Y))
+		}
+	}
+
+	for _, n := range c.elements("path", "outline") {
+		d := strings.TrimSpace(getAttr(n, "d"))
+		if d != "" && !strings.HasSuffix(strings.ToUpper(d), "Z") {
+			c.report(n, SeverityError, "path контуру не закінчується командою Z")
+		}
+	}
+}
+
+// checkLabelsInside перевіряє, що кожен підпис кімнати цілком лежить у кімнаті, яку він називає:
+// найменшому контурі .room, що містить центр підпису, а без такого - у замкненій області плану
+// (як у GeoJSON і таблиці площ). Підписи виходів ("Вихід ...") свідомо розміщуються за межами
+// будівлі і не перевіряються.
+func checkLabelsInside(c *lintContext) {
+	var shapes [][]point
+	for _, n := range c.elements("polygon", "room") {
+		shapes = append(shapes, polygonPoints(n))
+	}
+	for _, n := range c.elements("rect", "room") {
+		shapes = append(shapes, rectPoints(n))
+	}
+	if len(shapes) == 0 && len(c.wallSegments()) == 0 {
+		return
+	}
+	index := newRoomIndex(c.svg)
+
+	for _, n := range c.elements("text", "room-name") {
+		label := textContent(n)
+		if strings.HasPrefix(label, "Вихід") {
+			continue
+		}
+		b := textBox(n, c.styles)
+		center := b.center()
+		room := roomFeatures([]*html.Node{n}, shapes, c.styles, index)[0]
+		if len(room.rings) == 0 {
+			c.report(n, SeverityWarning, "підпис %q (центр %s,%s) не знаходиться в жодній замкненій кімнаті",
+				label, formatNumber(center.X), formatNumber(center.Y))
+			continue
+		}
+		for _, p := range []point{{b.X, b.Y}, {b.X + b.W, b.Y}, {b.X + b.W, b.Y + b.H}, {b.X, b.Y + b.H}} {
+			if !inRings(p, room.rings) {
+				c.report(n, SeverityWarning, "підпис %q виходить за межі своєї кімнати (кут %s,%s)",
+					label, formatNumber(p.X), formatNumber(p.Y))
+				break
+			}
+		}
+	}
+}
+
+// inRings перевіряє, чи лежить точка всередині зовнішнього кільця і поза отворами.
+func inRings(p point, rings [][]point) bool {
+	if !pointInPolygon(p, rings[0]) {
+		return false
+	}
+	for _, hole := range rings[1:] {
+		if pointInPolygon(p, hole) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkLabelOverlaps шукає підписи кімнат і номери дверей, що перекривають інші підписи, стіни чи двері.
+func checkLabelOverlaps(c *lintContext) {
+	for _, col := range FindLabelCollisions(&Document{SVG: c.svg}, CollisionOptions{}) {
+		c.report(col.node, SeverityWarning, "підпис %q перекриває %s", col.Label, strings.Join(col.With, ", "))
+	}
+}
+
+// checkDoorNumbersUnique шукає однакові номери дверей.
+func checkDoorNumbersUnique(c *lintContext) {
+	first := make(map[string]*html.Node)
+	for _, n := range c.elements("text", "door-number") {
+		num := textContent(n)
+		if prev, ok := first[num]; ok {
+			c.report(n, SeverityError, "номер дверей %q повторюється (уперше: рядок %d)", num, c.lines[prev])
+			continue
+		}
+		first[num] = n
+	}
+}
+
+// checkDoorNumbersNumeric перевіряє, що номери дверей - додатні цілі числа.
+func checkDoorNumbersNumeric(c *lintContext) {
+	for _, n := range c.elements("text", "door-number") {
+		num := textContent(n)
+		if v, err := strconv.Atoi(num); err != nil || v <= 0 {
+			c.report(n, SeverityWarning, "номер дверей %q не є додатним числом", num)
+		}
+	}
+}
+
+// checkSymbolsDefined перевіряє, що кожен <use> посилається на визначений елемент
+// (у документі або у вбудованій бібліотеці символів).
+func checkSymbolsDefined(c *lintContext) {
+	defined := make(map[string]bool)
+	traverse(c.svg, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && getAttr(n, "id") != "" {
+			defined[getAttr(n, "id")] = true
+		}
+		return false
+	})
+	for _, n := range c.elements("use", "") {
+		id := useHref(n)
+		if defined[id] {
+			continue
+		}
+		if _, ok := librarySymbol(id); ok {
+			continue
+		}
+		c.report(n, SeverityError, "символ #%s не визначено ні в документі, ні в бібліотеці", id)
+	}
+}
+
+// checkSymbolsUsed шукає символи в <defs>, на які немає жодного <use>.
+func checkSymbolsUsed(c *lintContext) {
+	used := make(map[string]bool)
+	for _, n := range c.elements("use", "") {
+		used[useHref(n)] = true
+	}
+	for _, n := range c.elements("symbol", "") {
+		if id := getAttr(n, "id"); !used[id] {
+			c.report(n, SeverityWarning, "символ #%s визначено, але ніде не використано", id)
+		}
+	}
+}
+
+// checkClassesDefined шукає класи елементів, для яких немає правил у <style>.
+func checkClassesDefined(c *lintContext) {
+	reported := make(map[string]bool)
+	traverse(c.svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		for _, class := range strings.Fields(getAttr(n, "class")) {
+			if _, ok := c.styles[class]; ok || reported[class] {
+				continue
+			}
+			reported[class] = true
+			c.report(n, SeverityWarning, "клас .%s не визначено в жодному <style>", class)
+		}
+		return false
+	})
+}
+
+// WriteDiagnostics виводить діагностики у форматі file:line: Severity [rule] message (path).
+func WriteDiagnostics(w io.Writer, diags []Diagnostic) {
+	for _, d := range diags {
+		fmt.Fprintf(w, "%s:%d: %s [%s] %s (%s)\n", d.File, d.Line, d.Severity, d.Rule, d.Message, d.Path)
+	}
+}
+
+// CountSeverity рахує діагностики заданого рівня.
+func CountSeverity(diags []Diagnostic, sev Severity) int {
+	count := 0
+	for _, d := range diags {
+		if d.Severity == sev {
+			count++
+		}
+	}
+	return count
+}
+
+// LintRuleIDs повертає id усіх правил.
+func LintRuleIDs() []string {
+	ids := make([]string, len(lintRules))
+	for i, r := range lintRules {
+		ids[i] = r.ID
+	}
+	return ids
+}
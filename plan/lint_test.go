@@ -0,0 +1,150 @@
+package plan
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// lintStyle визначає класи, які використовують тестові фрагменти.
+const lintStyle = `<style>
+	.wall { stroke: #000; stroke-width: 5; }
+	.doors { stroke: #FFF; stroke-width: 8; }
+	.outline { fill: #FFF; stroke: #000; stroke-width: 5; }
+	.room-name { font-size: 10px; }
+	.door-number { font-size: 10px; }
+</style>`
+
+func TestLintRules(t *testing.T) {
+	cases := []struct {
+		rule, bad, good string
+	}{
+		{
+			"door-off-wall",
+			`<line class="wall" x1="0" y1="0" x2="100" y2="0"/><line class="doors" x1="40" y1="50" x2="60" y2="50"/>`,
+			`<line class="wall" x1="0" y1="0" x2="100" y2="0"/><line class="doors" x1="40" y1="0" x2="60" y2="0"/>`,
+		},
+		{
+			"polygon-invalid",
+			`<polygon points="0,0 10,0 10"/>`,
+			`<polygon points="0,0 10,0 10,10"/>`,
+		},
+		{
+			"polygon-not-closed",
+			`<polygon class="outline" points="0,0 100,0 100,100 50,100 50,50"/>`,
+			`<polygon class="outline" points="0,0 100,0 100,100 0,100"/>`,
+		},
+		{
+			"label-outside-room",
+			`<polygon class="outline" points="0,0 100,0 100,100 0,100"/><text class="room-name" x="300" y="300">кухня</text>`,
+			`<polygon class="outline" points="0,0 100,0 100,100 0,100"/><text class="room-name" x="30" y="50">кухня</text>`,
+		},
+		{
+			"label-overlap",
+			`<text class="room-name" x="100" y="100">кухня</text><text class="room-name" x="105" y="102">склад</text>`,
+			`<text class="room-name" x="100" y="100">кухня</text><text class="room-name" x="300" y="300">склад</text>`,
+		},
+		{
+			"door-number-duplicate",
+			`<text class="door-number" x="10" y="10">1</text><text class="door-number" x="200" y="10">1</text>`,
+			`<text class="door-number" x="10" y="10">1</text><text class="door-number" x="200" y="10">2</text>`,
+		},
+		{
+			"door-number-invalid",
+			`<text class="door-number" x="10" y="10">1а</text>`,
+			`<text class="door-number" x="10" y="10">3</text>`,
+		},
+		{
+			"symbol-undefined",
+			`<use href="#no-such-symbol" x="0" y="0" width="10" height="10"/>`,
+			`<defs><symbol id="box" viewBox="0 0 10 10"><rect width="10" height="10"/></symbol></defs><use href="#box" width="10" height="10"/>`,
+		},
+		{
+			"symbol-unused",
+			`<defs><symbol id="box" viewBox="0 0 10 10"><rect width="10" height="10"/></symbol></defs>`,
+			`<defs><symbol id="box" viewBox="0 0 10 10"><rect width="10" height="10"/></symbol></defs><use href="#box" width="10" height="10"/>`,
+		},
+		{
+			"class-undefined",
+			`<rect class="wall furniture" width="10" height="10"/>`,
+			`<rect class="wall" width="10" height="10"/>`,
+		},
+	}
+	if len(cases) != len(lintRules) {
+		t.Fatalf("перевірено %d правил з %d", len(cases), len(lintRules))
+	}
+
+	for _, c := range cases {
+		t.Run(c.rule, func(t *testing.T) {
+			if !slices.Contains(LintRuleIDs(), c.rule) {
+				t.Fatalf("правила %s немає", c.rule)
+			}
+			disabled := make(map[string]bool)
+			for _, id := range LintRuleIDs() {
+				disabled[id] = id != c.rule
+			}
+			lint := func(body string) []Diagnostic {
+				markup := "<svg viewBox=\"0 0 400 400\">\n" + lintStyle + "\n" + body + "\n</svg>"
+				diags, err := Lint("test.svg", []byte(markup), disabled)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return diags
+			}
+
+			bad := lint(c.bad)
+			if len(bad) == 0 {
+				t.Fatalf("порушення не знайдено")
+			}
+			for _, d := range bad {
+				if d.Rule != c.rule || d.Line == 0 || d.Path == "" {
+					t.Errorf("неочікувана діагностика: %+v", d)
+				}
+			}
+			if good := lint(c.good); len(good) != 0 {
+				t.Errorf("зайві діагностики для правильного фрагмента: %+v", good)
+			}
+		})
+	}
+}
+
+func TestLintNoSVG(t *testing.T) {
+	if _, err := Lint("empty.html", []byte("<html><body><p>план</p></body></html>"), nil); !errors.Is(err, ErrNoSVG) {
+		t.Fatalf("очікувалась ErrNoSVG, отримано %v", err)
+	}
+}
+
+func TestLintLabelsInOwnRoom(t *testing.T) {
+	disabled := make(map[string]bool)
+	for _, id := range LintRuleIDs() {
+		disabled[id] = id != "label-outside-room"
+	}
+	// Дві кімнати, розділені стіною x=200; права кімната - окремий контур .room
+	plan := func(labels string) []Diagnostic {
+		markup := "<svg viewBox=\"0 0 400 300\">\n" + lintStyle + `
+<polygon class="outline" points="0,0 400,0 400,300 0,300"/>
+<line class="wall" x1="200" y1="0" x2="200" y2="300"/>
+<rect class="room" x="200" y="0" width="200" height="300"/>
+` + labels + "\n</svg>"
+		diags, err := Lint("test.svg", []byte(markup), disabled)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return diags
+	}
+
+	if diags := plan(`<text class="room-name" x="50" y="150">кухня</text><text class="room-name" x="250" y="150">склад</text>`); len(diags) != 0 {
+		t.Errorf("підписи всередині своїх кімнат: %+v", diags)
+	}
+	// Центр лівіше стіни, але підпис заходить у сусідню кімнату
+	diags := plan(`<text class="room-name" x="185" y="150">кухня</text>`)
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "виходить за межі своєї кімнати") {
+		t.Errorf("підпис на стіні між кімнатами: %+v", diags)
+	}
+	// Підпис поза будівлею
+	diags = plan(`<text class="room-name" x="420" y="150">двір</text>`)
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "не знаходиться в жодній замкненій кімнаті") {
+		t.Errorf("підпис поза будівлею: %+v", diags)
+	}
+}
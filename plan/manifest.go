@@ -0,0 +1,168 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// FormatSVG - вихідний файл з розміткою SVG (без рендерингу).
+const FormatSVG = "svg"
+
+// Manifest - опис проєкту: які плани з яких файлів збирати і в які формати.
+type Manifest struct {
+	Plans []PlanSpec `json:"plans"`
+}
+
+// PlanSpec - один план у маніфесті.
+type PlanSpec struct {
+	// Name - унікальна назва для вибору плану та журналу збірки
+	Name string `json:"name"`
+	// Source - HTML/SVG файл плану (відносно маніфесту) або креслення .dxf
+	Source string `json:"source"`
+	// Selector - селектор потрібного <svg>, якщо у файлі їх кілька
+	Selector string `json:"selector,omitempty"`
+	// DXF - параметри імпорту, якщо Source - креслення .dxf
+	DXF *DXFOptions `json:"dxf,omitempty"`
+	// Mirror - віддзеркалити план перед збереженням
+	Mirror bool `json:"mirror,omitempty"`
+	// MirrorNumbers - номери дверей після віддзеркалення: keep (за замовчуванням) або renumber
+	MirrorNumbers string `json:"mirror_numbers,omitempty"`
+	// Walls - очистити геометрію стін (першим кроком, до нумерації дверей)
+	Walls *WallMergeOptions `json:"walls,omitempty"`
+	// Doors - перенумерувати двері (до віддзеркалення, тож номери лишаються за тими самими дверима)
+	Doors *DoorNumbering `json:"doors,omitempty"`
+	// Labels - розмістити підписи кімнат у центрах кімнат (після віддзеркалення)
+	Labels *LabelOptions `json:"labels,omitempty"`
+	// Declutter - розвести підписи, що перекривають інші підписи, стіни чи двері (після розміщення)
+	Declutter *CollisionOptions `json:"declutter,omitempty"`
+	// Title - рамка й титульний блок; без нього розмітка плану не змінюється
+	Title *TitleSpec `json:"title,omitempty"`
+	// Outputs - вихідні файли
+	Outputs []OutputSpec `json:"outputs"`
+}
+
+// TitleSpec - шаблон титульного блоку та змінні для нього.
+type TitleSpec struct {
+	// Template - власний шаблон (відносно маніфесту); порожній - вбудований
+	Template string   `json:"template,omitempty"`
+	Vars     Metadata `json:"vars"`
+}
+
+// OutputSpec - вихідний файл плану.
+type OutputSpec struct {
+	Path string `json:"path"`
+	// Format - svg, png, pdf, dxf або geojson; за замовчуванням визначається з розширення Path
+	Format string `json:"format,omitempty"`
+	// Width, Height, DPI - параметри рендерингу PNG/PDF (див. RenderOptions)
+	Width  int     `json:"width,omitempty"`
+	Height int     `json:"height,omitempty"`
+	DPI    float64 `json:"dpi,omitempty"`
+	// Units - одиниці креслення DXF (mm, cm, m; див. DXFExportOptions)
+	Units string `json:"units,omitempty"`
+	// Geo - географічна прив'язка GeoJSON (див. GeoReference)
+	Geo *GeoReference `json:"geo,omitempty"`
+	// Optimize - записати SVG оптимізованим для публікації (див. Optimize)
+	Optimize *OptimizeOptions `json:"optimize,omitempty"`
+}
+
+// RenderOptions повертає параметри рендерингу виходу.
+func (o OutputSpec) RenderOptions() RenderOptions {
+	return RenderOptions{Format: o.Format, Width: o.Width, Height: o.Height, DPI: o.DPI}
+}
+
+// LoadManifest читає маніфест у форматі JSON, перевіряє його і заповнює формати виходів.
+func LoadManifest(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, Errorf("%w маніфесту: %w", ErrParse, err)
+	}
+	if len(m.Plans) == 0 {
+		return nil, Errorf("маніфест не містить планів")
+	}
+	names := make(map[string]bool)
+	outputs := make(map[string]string)
+	for i := range m.Plans {
+		p := &m.Plans[i]
+		if p.Name == "" {
+			return nil, Errorf("план %d: не вказано назву (name)", i+1)
+		}
+		if names[p.Name] {
+			return nil, Errorf("план %q вказано двічі", p.Name)
+		}
+		names[p.Name] = true
+		if p.Source == "" {
+			return nil, Errorf("план %q: не вказано файл плану (source)", p.Name)
+		}
+		if len(p.Outputs) == 0 {
+			return nil, Errorf("план %q: не вказано жодного виходу (outputs)", p.Name)
+		}
+		for j := range p.Outputs {
+			o := &p.Outputs[j]
+			if o.Path == "" {
+				return nil, Errorf("план %q: вихід %d без шляху (path)", p.Name, j+1)
+			}
+			if o.Format == "" {
+				o.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(o.Path)), ".")
+			}
+			switch o.Format {
+			case FormatSVG, FormatPNG, FormatPDF, FormatDXF, FormatGeoJSON:
+			default:
+				return nil, Errorf("план %q: невідомий формат %q для %s (svg, png, pdf, dxf, geojson)", p.Name, o.Format, o.Path)
+			}
+			if other, ok := outputs[o.Path]; ok {
+				return nil, Errorf("файл %s записують плани %q і %q", o.Path, other, p.Name)
+			}
+			outputs[o.Path] = p.Name
+		}
+	}
+	return &m, nil
+}
+
+// Build витягує план з r (або імпортує креслення DXF) і застосовує перетворення з опису: символи бібліотеки, нумерацію дверей,
+// віддзеркалення, розміщення й розведення підписів та титульний блок (tmplText - текст шаблону, порожній - вбудований).
+func (p PlanSpec) Build(ctx context.Context, r io.Reader, tmplText string) (*Document, error) {
+	var d *Document
+	var err error
+	if strings.EqualFold(filepath.Ext(p.Source), ".dxf") {
+		var opts DXFOptions
+		if p.DXF != nil {
+			opts = *p.DXF
+		}
+		d, _, err = ImportDXF(ctx, r, opts)
+	} else {
+		d, err = Extractor{Selector: p.Selector, InjectSymbols: true}.Extract(ctx, r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.Walls != nil {
+		MergeWalls(d, *p.Walls)
+	}
+	if p.Doors != nil {
+		if _, err := NumberDoors(d, *p.Doors); err != nil {
+			return nil, err
+		}
+	}
+	if p.Mirror {
+		if _, err := MirrorNumbered(ctx, d, p.MirrorNumbers); err != nil {
+			return nil, err
+		}
+	}
+	if p.Labels != nil {
+		PlaceRoomLabels(d, *p.Labels)
+	}
+	if p.Declutter != nil {
+		ResolveLabelCollisions(d, *p.Declutter)
+	}
+	if p.Title != nil {
+		if err := ApplyTitleBlock(d, p.Title.Vars, tmplText); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
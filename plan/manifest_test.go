@@ -0,0 +1,67 @@
+package plan
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadManifest(t *testing.T) {
+	m, err := LoadManifest(strings.NewReader(`{"plans": [
+		{"name": "full", "source": "full.html", "mirror": true, "outputs": [
+			{"path": "out/full.svg"},
+			{"path": "out/full.PNG", "width": 800},
+			{"path": "out/full.bin", "format": "pdf", "dpi": 150}
+		]},
+		{"name": "cad", "source": "plan.dxf", "dxf": {"scale": 0.1}, "outputs": [{"path": "cad.geojson"}]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Plans) != 2 || m.Plans[0].Name != "full" || !m.Plans[0].Mirror || m.Plans[1].DXF == nil {
+		t.Fatalf("розібрано %+v", m.Plans)
+	}
+	// Формат визначається з розширення (без урахування регістру), явний формат має перевагу
+	var formats []string
+	for _, p := range m.Plans {
+		for _, o := range p.Outputs {
+			formats = append(formats, o.Format)
+		}
+	}
+	if got := strings.Join(formats, ","); got != "svg,png,pdf,geojson" {
+		t.Errorf("формати виходів %s", got)
+	}
+	if opts := m.Plans[0].Outputs[2].RenderOptions(); opts.Format != FormatPDF || opts.DPI != 150 {
+		t.Errorf("параметри рендерингу %+v", opts)
+	}
+}
+
+func TestLoadManifestErrors(t *testing.T) {
+	cases := []struct {
+		name, json, want string
+	}{
+		{"порожній", `{"plans": []}`, "не містить планів"},
+		{"невідоме поле", `{"plans": [{"name": "a", "source": "a.html", "mirorr": true, "outputs": [{"path": "a.svg"}]}]}`, "mirorr"},
+		{"без назви", `{"plans": [{"source": "a.html", "outputs": [{"path": "a.svg"}]}]}`, "не вказано назву"},
+		{"назва двічі", `{"plans": [
+			{"name": "a", "source": "a.html", "outputs": [{"path": "a.svg"}]},
+			{"name": "a", "source": "b.html", "outputs": [{"path": "b.svg"}]}]}`, "вказано двічі"},
+		{"без файлу", `{"plans": [{"name": "a", "outputs": [{"path": "a.svg"}]}]}`, "source"},
+		{"без виходів", `{"plans": [{"name": "a", "source": "a.html"}]}`, "outputs"},
+		{"вихід без шляху", `{"plans": [{"name": "a", "source": "a.html", "outputs": [{"width": 10}]}]}`, "без шляху"},
+		{"невідомий формат", `{"plans": [{"name": "a", "source": "a.html", "outputs": [{"path": "a.jpg"}]}]}`, `"jpg"`},
+		{"спільний вихід", `{"plans": [
+			{"name": "a", "source": "a.html", "outputs": [{"path": "x.svg"}]},
+			{"name": "b", "source": "b.html", "outputs": [{"path": "x.svg"}]}]}`, "записують плани"},
+	}
+	for _, c := range cases {
+		_, err := LoadManifest(strings.NewReader(c.json))
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: помилка %v, очікувалась з %q", c.name, err, c.want)
+		}
+	}
+
+	if _, err := LoadManifest(strings.NewReader(`{"plans": [`)); !errors.Is(err, ErrParse) {
+		t.Errorf("некоректний JSON: %v, очікувалась ErrParse", err)
+	}
+}
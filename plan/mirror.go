@@ -0,0 +1,252 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Mirror дзеркально відображає план по горизонталі так само, як create_mirror:
+// кожна група під'їзду (<g transform="translate(x, y)"> з контуром .outline) віддзеркалюється
+// всередині своєї ширини і переноситься на дзеркальну позицію у viewBox,
+// а підписи з групи room-numbers отримують дзеркальний x та text-anchor="end".
+// Символи (<use>) переносяться, але не перевертаються, щоб написи на знаках лишались читабельними.
+func Mirror(ctx context.Context, d *Document) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	svg := d.SVG
+	vx, _, vw, _, ok := parseViewBox(svg)
+	if !ok {
+		return Errorf("SVG не має коректного viewBox або width/height")
+	}
+	viewBoxWidth := vx*2 + vw
+	styles := parseClassStyles(svg)
+	symbols := make(map[string]*html.Node)
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "symbol" && getAttr(n, "id") != "" {
+			symbols[getAttr(n, "id")] = n
+		}
+		return false
+	})
+
+	for c := svg.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Data != "g" {
+			continue
+		}
+		if getAttr(c, "id") == "room-numbers" {
+			mirrorLabels(c, viewBoxWidth, styles)
+			continue
+		}
+		m := parseTransform(getAttr(c, "transform"))
+		if getAttr(c, "transform") == "" || m[0] != 1 || m[3] != 1 || m[1] != 0 || m[2] != 0 {
+			continue
+		}
+		width, ok := groupWidth(c)
+		if !ok {
+			continue
+		}
+		mirrorGroup(c, width, styles, symbols)
+		setAttr(c, "transform", fmt.Sprintf("translate(%s, %s)", formatNumber(viewBoxWidth-m[4]-width), formatNumber(m[5])))
+	}
+	return nil
+}
+
+// Способи нумерації дверей після віддзеркалення.
+const (
+	// MirrorKeepNumbers - номери лишаються за тими самими дверима (порядок на аркуші стає дзеркальним)
+	MirrorKeepNumbers = "keep"
+	// MirrorRenumber - номери переписуються так, щоб у порядку читання вони йшли так само, як на вихідному аркуші
+	MirrorRenumber = "renumber"
+)
+
+// MirrorNumberings - усі способи нумерації після віддзеркалення.
+var MirrorNumberings = []string{MirrorKeepNumbers, MirrorRenumber}
+
+// MirrorNumbered віддзеркалює план, як Mirror, і з numbering = MirrorRenumber переписує номери дверей:
+// номери зіставляються з дверима так само, як у NumberDoors (разом із прорізами в стінах), двері з номерами
+// впорядковуються зліва направо (як DoorOrderLTR) до і після віддзеркалення, і k-ті двері дзеркального
+// аркуша отримують номер k-их дверей вихідного. Номери без дверей (Orphan) і написи, що не є номерами
+// (Invalid), не змінюються. Якщо після віддзеркалення номери зіставились з дверима інакше, ніж до нього,
+// повертається помилка і документ не слід зберігати.
+// Повертає відповідність старих і нових номерів (nil для MirrorKeepNumbers).
+func MirrorNumbered(ctx context.Context, d *Document, numbering string) ([]DoorNumber, error) {
+	switch numbering {
+	case "", MirrorKeepNumbers:
+		return nil, Mirror(ctx, d)
+	case MirrorRenumber:
+	default:
+		return nil, Errorf("невідомий спосіб нумерації після віддзеркалення %q (%s)", numbering, strings.Join(MirrorNumberings, ", "))
+	}
+
+	_, before := numberedDoors(d.SVG)
+	numbers := make([]string, len(before))
+	bound := make(map[*html.Node]bool)
+	for i, n := range before {
+		numbers[i] = textContent(n)
+		bound[n] = true
+	}
+	if err := Mirror(ctx, d); err != nil {
+		return nil, err
+	}
+
+	doors, after := numberedDoors(d.SVG)
+	matched := 0
+	for _, n := range after {
+		if bound[n] {
+			matched++
+		}
+	}
+	if matched != len(before) || len(after) != len(before) {
+		return nil, Errorf("після віддзеркалення біля дверей %d номерів замість %d: перенумерування неможливе", len(after), len(before))
+	}
+
+	var out []DoorNumber
+	for i, n := range after {
+		old := textContent(n)
+		setText(n, numbers[i])
+		out = append(out, DoorNumber{Number: numbers[i], Old: old, X: doors[i].mid.X, Y: doors[i].mid.Y, Section: doors[i].section, Path: elementPath(n)})
+	}
+	styles := parseClassStyles(d.SVG)
+	labels, invalid := splitDoorNumbers(labelsByPriority(d.SVG, []string{"door-number"}))
+	for _, n := range labels {
+		if !bound[n] {
+			c := textBox(n, styles).center()
+			out = append(out, DoorNumber{Number: textContent(n), Old: textContent(n), X: c.X, Y: c.Y, Section: -1, Path: elementPath(n), Orphan: true})
+		}
+	}
+	for _, n := range invalid {
+		c := textBox(n, styles).center()
+		out = append(out, DoorNumber{Number: textContent(n), Old: textContent(n), X: c.X, Y: c.Y, Section: -1, Path: elementPath(n), Invalid: true})
+	}
+	return out, nil
+}
+
+// MirrorHTML читає HTML-сторінку, віддзеркалює перший <svg> і записує сторінку цілком.
+func MirrorHTML(ctx context.Context, w io.Writer, r io.Reader) error {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return Errorf("%w HTML: %w", ErrParse, err)
+	}
+	d, err := Extractor{}.ExtractNode(doc)
+	if err != nil {
+		return err
+	}
+	if err := Mirror(ctx, d); err != nil {
+		return err
+	}
+	return html.Render(w, doc)
+}
+
+// groupWidth визначає ширину групи під'їзду за найбільшим x її контуру .outline.
+func groupWidth(g *html.Node) (float64, bool) {
+	outline := findElement(g, func(n *html.Node) bool { return n.Data == "polygon" && hasClass(n, "outline") })
+	if outline == nil {
+		return 0, false
+	}
+	pts, _ := parsePoints(getAttr(outline, "points"))
+	if len(pts) == 0 {
+		return 0, false
+	}
+	width := pts[0].X
+	for _, p := range pts {
+		width = max(width, p.X)
+	}
+	return width, true
+}
+
+// mirrorGroup віддзеркалює координати x усіх фігур групи відносно її ширини.
+func mirrorGroup(g *html.Node, width float64, styles classStyles, symbols map[string]*html.Node) {
+	for c := g.FirstChild; c != nil; c = c.NextSibling {
+		mirrorElement(c, width, styles, symbols)
+	}
+}
+
+// mirrorElement віддзеркалює елемент і його нащадків відносно прямої x = width/2 системи координат батька.
+// Трансформація елемента переписується так, щоб віддзеркалення в його власних координатах
+// збігалося з віддзеркаленням у батьківських: translate(e, f) лишається тим самим,
+// а нащадки віддзеркалюються відносно ширини width - 2e.
+func mirrorElement(n *html.Node, width float64, styles classStyles, symbols map[string]*html.Node) {
+	if n.Type != html.ElementNode {
+		return
+	}
+	if t := getAttr(n, "transform"); t != "" {
+		m := parseTransform(t)
+		local := width - 2*m[4]
+		if m[0] != 0 {
+			local /= m[0]
+		}
+		// x → width - x у батька дорівнює m' після x → local - x у власних координатах
+		mirrored := matrix{m[0], -m[1], -m[2], m[3], width - m[0]*local - m[4], m[5] + m[1]*local}
+		setAttr(n, "transform", formatMatrix(mirrored))
+		width = local
+	}
+	switch n.Data {
+	case "polygon", "polyline":
+		pts, _ := parsePoints(getAttr(n, "points"))
+		coords := make([]string, len(pts))
+		for i, p := range pts {
+			coords[i] = formatNumber(width-p.X) + "," + formatNumber(p.Y)
+		}
+		setAttr(n, "points", strings.Join(coords, " "))
+	case "line":
+		setAttr(n, "x1", formatNumber(width-attrFloat(n, "x1")))
+		setAttr(n, "x2", formatNumber(width-attrFloat(n, "x2")))
+	case "rect", "image":
+		setAttr(n, "x", formatNumber(width-attrFloat(n, "x")-attrFloat(n, "width")))
+	case "use":
+		// Без width розмір вставки - ширина viewBox символу
+		w := attrFloat(n, "width")
+		if w <= 0 {
+			if symbol := symbols[useHref(n)]; symbol != nil {
+				_, _, w, _, _ = parseViewBox(symbol)
+			}
+		}
+		setAttr(n, "x", formatNumber(width-attrFloat(n, "x")-w))
+	case "circle", "ellipse":
+		setAttr(n, "cx", formatNumber(width-attrFloat(n, "cx")))
+	case "text":
+		mirrorText(n, width, styles)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		mirrorElement(c, width, styles, symbols)
+	}
+}
+
+// formatMatrix записує перетворення як translate, якщо це зсув, і як matrix в іншому разі.
+func formatMatrix(m matrix) string {
+	if m[0] == 1 && m[1] == 0 && m[2] == 0 && m[3] == 1 {
+		return fmt.Sprintf("translate(%s, %s)", formatNumber(m[4]), formatNumber(m[5]))
+	}
+	parts := make([]string, len(m))
+	for i, v := range m {
+		parts[i] = formatNumber(v)
+	}
+	return "matrix(" + strings.Join(parts, " ") + ")"
+}
+
+// mirrorLabels віддзеркалює всі тексти групи підписів відносно ширини viewBox.
+func mirrorLabels(g *html.Node, width float64, styles classStyles) {
+	traverse(g, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "text" {
+			mirrorText(n, width, styles)
+		}
+		return false
+	})
+}
+
+// mirrorText переносить текст на дзеркальну позицію, зберігаючи напрям письма:
+// початок рядка стає його кінцем, тому змінюється text-anchor.
+func mirrorText(n *html.Node, width float64, styles classStyles) {
+	setAttr(n, "x", formatNumber(width-attrFloat(n, "x")))
+	switch styles.property(n, "text-anchor") {
+	case "middle":
+	case "end":
+		setAttr(n, "text-anchor", "start")
+	default:
+		setAttr(n, "text-anchor", "end")
+	}
+}
@@ -0,0 +1,113 @@
+package plan
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMirrorNestedGroups(t *testing.T) {
+	// Секція шириною 200 на позиції 50; вкладені групи зсунуто й масштабовано відносно неї
+	d := parseSVG(t, `<svg viewBox="0 0 400 200">
+		<style>.wall { stroke: #000; stroke-width: 4; }</style>
+		<defs><symbol id="box" viewBox="0 0 20 10"><rect width="20" height="10"/></symbol></defs>
+		<g transform="translate(50, 20)">
+			<polygon class="outline" points="0,0 200,0 200,100 0,100"/>
+			<line class="wall" x1="10" y1="0" x2="60" y2="0"/>
+			<g transform="translate(-100, 0)">
+				<line class="wall" x1="150" y1="50" x2="180" y2="50"/>
+			</g>
+			<g transform="translate(10, 5) scale(2)">
+				<line class="wall" x1="20" y1="10" x2="30" y2="10"/>
+				<g transform="translate(5, 0)"><line class="wall" x1="0" y1="20" x2="10" y2="20"/></g>
+			</g>
+			<use href="#box" x="10" y="60"/>
+			<use href="#box" x="100" y="60" width="40" height="20"/>
+		</g>
+	</svg>`)
+
+	lines := elementsByTag(d, "line")
+	before := make([][2]point, len(lines))
+	for i, n := range lines {
+		m := nodeTransform(n)
+		before[i] = [2]point{m.apply(point{attrFloat(n, "x1"), attrFloat(n, "y1")}), m.apply(point{attrFloat(n, "x2"), attrFloat(n, "y2")})}
+	}
+	if err := Mirror(context.Background(), d); err != nil {
+		t.Fatal(err)
+	}
+
+	// У координатах документа кожна лінія має віддзеркалитися відносно середини viewBox
+	for i, n := range lines {
+		m := nodeTransform(n)
+		a, b := m.apply(point{attrFloat(n, "x1"), attrFloat(n, "y1")}), m.apply(point{attrFloat(n, "x2"), attrFloat(n, "y2")})
+		for k, p := range []point{a, b} {
+			want := point{400 - before[i][k].X, before[i][k].Y}
+			if math.Abs(p.X-want.X) > 1e-9 || math.Abs(p.Y-want.Y) > 1e-9 {
+				t.Errorf("лінія %d, кінець %d: %v, очікувалось %v", i, k, p, want)
+			}
+		}
+	}
+	// Зсув вкладеної групи зберігається, змінюються лише координати всередині неї
+	if g := lines[1].Parent; getAttr(g, "transform") != "translate(-100, 0)" || getAttr(lines[1], "x1") != "250" {
+		t.Errorf("вкладена група: transform=%q x1=%s", getAttr(g, "transform"), getAttr(lines[1], "x1"))
+	}
+
+	// <use> без width займає ширину viewBox символу (20), з width - задану
+	uses := elementsByTag(d, "use")
+	if x := getAttr(uses[0], "x"); x != "170" {
+		t.Errorf("<use> без width: x=%s, очікувалось 170", x)
+	}
+	if x := getAttr(uses[1], "x"); x != "60" {
+		t.Errorf("<use> з width: x=%s, очікувалось 60", x)
+	}
+}
+
+func TestMirrorRenumberGaps(t *testing.T) {
+	// Двері line.doors угорі і два прорізи в стіні y=150 без line.doors; osb - напис, а не номер,
+	// 9 не стоїть біля жодних дверей
+	d := parseSVG(t, `<svg viewBox="0 0 400 300">
+		<style>.door-number { font-size: 20px; }</style>
+		<g transform="translate(0, 0)">
+			<polygon class="outline" points="0,0 400,0 400,300 0,300"/>
+			<line class="doors" x1="40" y1="0" x2="80" y2="0"/>
+			<line class="wall" x1="0" y1="150" x2="100" y2="150"/>
+			<line class="wall" x1="150" y1="150" x2="250" y2="150"/>
+			<line class="wall" x1="300" y1="150" x2="400" y2="150"/>
+		</g>
+		<g id="room-numbers">
+			<text class="door-number" x="55" y="30">1</text>
+			<text class="door-number" x="100" y="60">osb</text>
+			<text class="door-number" x="120" y="140">2</text>
+			<text class="door-number" x="270" y="140">3</text>
+			<text class="door-number" x="190" y="290">9</text>
+		</g>
+	</svg>`)
+	numbers, err := MirrorNumbered(context.Background(), d, MirrorRenumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Після віддзеркалення зліва направо: проріз з 3, проріз з 2, двері з 1 - вони стають 1, 2, 3
+	var got []string
+	for _, n := range numbers {
+		switch {
+		case n.Invalid:
+			got = append(got, n.Number+"!")
+		case n.Orphan:
+			got = append(got, n.Number+"?")
+		default:
+			got = append(got, n.Old+"→"+n.Number)
+		}
+	}
+	if want := "3→1 2→2 1→3 9? osb!"; strings.Join(got, " ") != want {
+		t.Errorf("відповідність номерів %q, очікувалось %q", strings.Join(got, " "), want)
+	}
+	var texts []string
+	for _, n := range elementsByTag(d, "text") {
+		texts = append(texts, textContent(n))
+	}
+	if want := "3 osb 2 1 9"; strings.Join(texts, " ") != want {
+		t.Errorf("підписи після перенумерування %q, очікувалось %q", strings.Join(texts, " "), want)
+	}
+}
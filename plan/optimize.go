@@ -0,0 +1,555 @@
+package plan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// defaultOptimizePrecision - знаків після коми в координатах за замовчуванням.
+const defaultOptimizePrecision = 2
+
+// OptimizeOptions - параметри оптимізації SVG для публікації.
+type OptimizeOptions struct {
+	// Precision - знаків після коми в координатах і розмірах; 0 - 2
+	Precision int `json:"precision,omitempty"`
+	// KeepLines - не перетворювати <line> на <path>: потрібно, якщо результат ще оброблятимуть
+	// команди цього інструмента (вони шукають line.wall, line.doors)
+	KeepLines bool `json:"keep_lines,omitempty"`
+}
+
+// OptimizeReport - що змінила оптимізація.
+type OptimizeReport struct {
+	// Before, After - розмір SVG у байтах без оптимізації (з відступами) і після неї;
+	// заповнює WriteOptimized
+	Before   int `json:"before_bytes"`
+	After    int `json:"after_bytes"`
+	Comments int `json:"comments"`
+	// Metadata - видалені елементи й атрибути редакторів (metadata, inkscape:*, sodipodi:*...)
+	Metadata int `json:"metadata"`
+	// Styles - блоки <style>, злиті в перший
+	Styles int `json:"styles"`
+	// Rules - видалені CSS-правила: дублікати, невикористані класи, злиті однакові
+	Rules int `json:"rules"`
+	// Defs - видалені невикористані елементи <defs> і <symbol>
+	Defs int `json:"defs"`
+	// Lines - елементи <line>, перетворені на <path>; Paths - створені <path>
+	Lines int `json:"lines"`
+	Paths int `json:"paths"`
+}
+
+// editorPrefixes - простори імен редакторів, чиї елементи й атрибути не впливають на вигляд.
+var editorPrefixes = []string{"inkscape:", "sodipodi:", "sketch:", "serif:", "xmlns:inkscape", "xmlns:sodipodi", "xmlns:sketch", "xmlns:serif"}
+
+// numericAttrs - атрибути з координатами й розмірами, числа в яких скорочуються.
+var numericAttrs = map[string]bool{
+	"x": true, "y": true, "x1": true, "y1": true, "x2": true, "y2": true,
+	"cx": true, "cy": true, "r": true, "rx": true, "ry": true, "width": true, "height": true,
+	"points": true, "d": true, "transform": true, "viewBox": true, "stroke-width": true, "font-size": true,
+}
+
+var (
+	numberRe = regexp.MustCompile(`-?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?`)
+	spaceRe  = regexp.MustCompile(`\s+`)
+	commaRe  = regexp.MustCompile(`\s*,\s*`)
+	urlRe    = regexp.MustCompile(`url\(\s*['"]?#([^'")\s]+)`)
+)
+
+// Optimize зменшує документ для публікації: видаляє коментарі й метадані редакторів, скорочує числа
+// до opts.Precision знаків, зливає блоки <style> в один, прибирає дублікати CSS-правил і правила
+// класів, яких немає на плані, об'єднує правила з однаковими властивостями, видаляє невикористані
+// елементи <defs> і <symbol> та перетворює послідовні однакові <line> на один <path>.
+// Вигляд плану не змінюється; пробіли між тегами прибирає Serializer з Optimize.
+func Optimize(d *Document, opts OptimizeOptions) OptimizeReport {
+	if opts.Precision <= 0 {
+		opts.Precision = defaultOptimizePrecision
+	}
+	var r OptimizeReport
+	svg := d.SVG
+	r.Comments, r.Metadata = stripEditorNodes(svg)
+	shortenNumbers(svg, opts.Precision)
+	r.Defs = removeUnusedDefs(svg)
+	r.Styles, r.Rules = mergeStyles(svg)
+	if !opts.KeepLines {
+		r.Lines, r.Paths = linesToPaths(svg)
+	}
+	return r
+}
+
+// WriteOptimized записує в w оптимізований компактний SVG і повертає звіт з розмірами.
+// Документ d не змінюється.
+func WriteOptimized(ctx context.Context, w io.Writer, d *Document, opts OptimizeOptions) (OptimizeReport, error) {
+	var before, after bytes.Buffer
+	if err := (Serializer{}).Serialize(ctx, &before, d); err != nil {
+		return OptimizeReport{}, err
+	}
+	svg := cloneNode(d.SVG)
+	r := Optimize(&Document{SVG: svg}, opts)
+	if err := renderSVG(&after, svg, true); err != nil {
+		return r, Errorf("помилка рендерингу SVG-вузла: %w", err)
+	}
+	r.Before, r.After = before.Len(), after.Len()
+	if _, err := w.Write(after.Bytes()); err != nil {
+		return r, Errorf("%w SVG: %w", ErrWrite, err)
+	}
+	return r, nil
+}
+
+// WriteOptimizeReport виводить звіт оптимізації як текст.
+func WriteOptimizeReport(w io.Writer, r OptimizeReport) {
+	saved := 0.0
+	if r.Before > 0 {
+		saved = 100 * float64(r.Before-r.After) / float64(r.Before)
+	}
+	fmt.Fprintf(w, "розмір: %d → %d байт (-%s%%)\n", r.Before, r.After, formatNumber(saved))
+	fmt.Fprintf(w, "коментарі: %d, метадані редакторів: %d\n", r.Comments, r.Metadata)
+	fmt.Fprintf(w, "блоки <style> злито: %d, CSS-правил прибрано: %d\n", r.Styles, r.Rules)
+	fmt.Fprintf(w, "невикористані defs/symbol: %d\n", r.Defs)
+	fmt.Fprintf(w, "лінії → шляхи: %d → %d\n", r.Lines, r.Paths)
+}
+
+// stripEditorNodes видаляє коментарі, <metadata>, елементи й атрибути редакторів.
+func stripEditorNodes(svg *html.Node) (comments, metadata int) {
+	var remove []*html.Node
+	traverse(svg, func(n *html.Node) bool {
+		switch {
+		case n.Type == html.CommentNode:
+			remove = append(remove, n)
+			comments++
+			return false
+		case n.Type != html.ElementNode:
+			return false
+		case n.Data == "metadata" || hasEditorPrefix(n.Data):
+			remove = append(remove, n)
+			metadata++
+			return true
+		}
+		attrs := n.Attr[:0]
+		for _, a := range n.Attr {
+			if hasEditorPrefix(a.Key) || hasEditorPrefix(a.Namespace+":") {
+				metadata++
+				continue
+			}
+			attrs = append(attrs, a)
+		}
+		n.Attr = attrs
+		return false
+	})
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+	return comments, metadata
+}
+
+// hasEditorPrefix перевіряє, чи належить ім'я простору імен редактора.
+func hasEditorPrefix(name string) bool {
+	for _, p := range editorPrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// shortenNumbers округлює числа в атрибутах координат до precision знаків і стискає пробіли в них.
+func shortenNumbers(svg *html.Node, precision int) {
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		for i, a := range n.Attr {
+			if !numericAttrs[a.Key] {
+				continue
+			}
+			v := numberRe.ReplaceAllStringFunc(a.Val, func(s string) string {
+				f, err := strconv.ParseFloat(s, 64)
+				if err != nil {
+					return s
+				}
+				return shortNumber(f, precision)
+			})
+			v = commaRe.ReplaceAllString(strings.TrimSpace(spaceRe.ReplaceAllString(v, " ")), ",")
+			n.Attr[i].Val = v
+		}
+		return false
+	})
+}
+
+// shortNumber записує число з не більше ніж precision знаками після коми, без зайвих нулів.
+func shortNumber(v float64, precision int) string {
+	p := math.Pow(10, float64(precision))
+	v = math.Round(v*p) / p
+	if v == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// removeUnusedDefs видаляє елементи <defs> і <symbol> з id, на які ніщо не посилається
+// (href, xlink:href, url(#id) в атрибутах і стилях), доки такі є, і порожні <defs>.
+func removeUnusedDefs(svg *html.Node) int {
+	removed := 0
+	for {
+		refs := make(map[string]bool)
+		traverse(svg, func(n *html.Node) bool {
+			switch n.Type {
+			case html.ElementNode:
+				if id := useHref(n); id != "" {
+					refs[id] = true
+				}
+				for _, a := range n.Attr {
+					for _, m := range urlRe.FindAllStringSubmatch(a.Val, -1) {
+						refs[m[1]] = true
+					}
+				}
+			case html.TextNode:
+				if n.Parent != nil && n.Parent.Data == "style" {
+					for _, m := range urlRe.FindAllStringSubmatch(n.Data, -1) {
+						refs[m[1]] = true
+					}
+				}
+			}
+			return false
+		})
+
+		var unused []*html.Node
+		traverse(svg, func(n *html.Node) bool {
+			if n.Type != html.ElementNode {
+				return false
+			}
+			inDefs := n.Parent != nil && n.Parent.Type == html.ElementNode && n.Parent.Data == "defs"
+			if id := getAttr(n, "id"); id != "" && !refs[id] && (inDefs || n.Data == "symbol") && n.Data != "style" {
+				unused = append(unused, n)
+				return true
+			}
+			return false
+		})
+		if len(unused) == 0 {
+			break
+		}
+		for _, n := range unused {
+			n.Parent.RemoveChild(n)
+		}
+		removed += len(unused)
+	}
+
+	var empty []*html.Node
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "defs" && !hasElementChildren(n) {
+			empty = append(empty, n)
+		}
+		return false
+	})
+	for _, n := range empty {
+		n.Parent.RemoveChild(n)
+	}
+	return removed
+}
+
+// hasElementChildren перевіряє, чи має вузол дочірні елементи.
+func hasElementChildren(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return true
+		}
+	}
+	return false
+}
+
+// cssRule - правило CSS: селектори і нормалізовані оголошення "властивість:значення".
+type cssRule struct {
+	selectors []string
+	decls     []string
+}
+
+// mergeStyles зливає всі <style> у перший, видаляє дублікати правил і правила класів, яких немає
+// на плані, об'єднує правила з однаковими оголошеннями й записує CSS без пробілів.
+// CSS з @-правилами лише стискається, бо простий розбір їх не підтримує.
+func mergeStyles(svg *html.Node) (styles, rules int) {
+	var blocks []*html.Node
+	used := make(map[string]bool)
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		if n.Data == "style" {
+			blocks = append(blocks, n)
+		}
+		for _, c := range strings.Fields(getAttr(n, "class")) {
+			used[c] = true
+		}
+		return false
+	})
+	if len(blocks) == 0 {
+		return 0, 0
+	}
+	var css strings.Builder
+	for _, b := range blocks {
+		css.WriteString(textContent(b))
+		css.WriteString("\n")
+	}
+	text := stripCSSComments(css.String())
+
+	var out string
+	if strings.Contains(text, "@") {
+		out = strings.TrimSpace(spaceRe.ReplaceAllString(text, " "))
+	} else {
+		list := parseCSSRules(text)
+		total := len(list)
+		list = dropUnusedRules(list, used)
+		list = dedupRules(list)
+		list = mergeEqualRules(list)
+		rules = total - len(list)
+		var b strings.Builder
+		for _, r := range list {
+			b.WriteString(strings.Join(r.selectors, ","))
+			b.WriteString("{")
+			b.WriteString(strings.Join(r.decls, ";"))
+			b.WriteString("}")
+		}
+		out = b.String()
+	}
+
+	setText(blocks[0], out)
+	for _, b := range blocks[1:] {
+		b.Parent.RemoveChild(b)
+	}
+	return len(blocks) - 1, rules
+}
+
+// stripCSSComments видаляє коментарі /* ... */.
+func stripCSSComments(css string) string {
+	for {
+		start := strings.Index(css, "/*")
+		if start < 0 {
+			return css
+		}
+		end := strings.Index(css[start+2:], "*/")
+		if end < 0 {
+			return css[:start]
+		}
+		css = css[:start] + css[start+2+end+2:]
+	}
+}
+
+// parseCSSRules розбирає CSS без @-правил на правила з нормалізованими селекторами й оголошеннями.
+func parseCSSRules(css string) []cssRule {
+	var list []cssRule
+	for {
+		open := strings.Index(css, "{")
+		if open < 0 {
+			return list
+		}
+		closeIdx := strings.Index(css[open:], "}")
+		if closeIdx < 0 {
+			return list
+		}
+		var r cssRule
+		for _, sel := range strings.Split(css[:open], ",") {
+			if sel = strings.TrimSpace(spaceRe.ReplaceAllString(sel, " ")); sel != "" {
+				r.selectors = append(r.selectors, sel)
+			}
+		}
+		for _, decl := range strings.Split(css[open+1:open+closeIdx], ";") {
+			name, value, ok := strings.Cut(decl, ":")
+			if !ok {
+				continue
+			}
+			r.decls = append(r.decls, strings.TrimSpace(name)+":"+strings.TrimSpace(spaceRe.ReplaceAllString(value, " ")))
+		}
+		css = css[open+closeIdx+1:]
+		if len(r.selectors) > 0 && len(r.decls) > 0 {
+			list = append(list, r)
+		}
+	}
+}
+
+// dropUnusedRules прибирає селектори простих класів (.name), яких немає на плані, і правила без селекторів.
+func dropUnusedRules(list []cssRule, used map[string]bool) []cssRule {
+	var out []cssRule
+	for _, r := range list {
+		var keep []string
+		for _, sel := range r.selectors {
+			simple := strings.HasPrefix(sel, ".") && !strings.ContainsAny(sel[1:], " .>:#[+~*")
+			if !simple || used[sel[1:]] {
+				keep = append(keep, sel)
+			}
+		}
+		if len(keep) > 0 {
+			r.selectors = keep
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// dedupRules видаляє повтори однакових правил, лишаючи останнє, щоб каскад не змінився.
+func dedupRules(list []cssRule) []cssRule {
+	last := make(map[string]int)
+	for i, r := range list {
+		last[ruleKey(r)] = i
+	}
+	var out []cssRule
+	for i, r := range list {
+		if last[ruleKey(r)] == i {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// ruleKey - текстовий ключ правила.
+func ruleKey(r cssRule) string {
+	return strings.Join(r.selectors, ",") + "{" + strings.Join(r.decls, ";")
+}
+
+// mergeEqualRules переносить селектори правила в попереднє з такими самими оголошеннями, якщо
+// жодне правило між ними не задає тих самих властивостей - тоді каскад не змінюється.
+func mergeEqualRules(list []cssRule) []cssRule {
+	props := func(r cssRule) map[string]bool {
+		m := make(map[string]bool)
+		for _, d := range r.decls {
+			name, _, _ := strings.Cut(d, ":")
+			m[name] = true
+		}
+		return m
+	}
+	var out []cssRule
+next:
+	for _, r := range list {
+		body := strings.Join(r.decls, ";")
+		own := props(r)
+		for i := len(out) - 1; i >= 0; i-- {
+			if strings.Join(out[i].decls, ";") == body {
+				out[i].selectors = append(out[i].selectors, r.selectors...)
+				continue next
+			}
+			if overlaps(props(out[i]), own) {
+				break
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// overlaps перевіряє, чи мають два набори спільний елемент.
+func overlaps(a, b map[string]bool) bool {
+	for k := range a {
+		if b[k] {
+			return true
+		}
+	}
+	return false
+}
+
+// linesToPaths замінює послідовні <line> з однаковими атрибутами (крім координат) на один <path>
+// з окремим підшляхом на кожну лінію. Лінії з id чи маркерами лишаються: маркери на <path>
+// ставилися б інакше. Якщо стилі документа використовують маркери, лінії не змінюються зовсім.
+// <line> не заливається, тож шлях без fill: none у стилях отримує fill="none".
+func linesToPaths(svg *html.Node) (lines, paths int) {
+	usesMarkers := false
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type == html.TextNode && n.Parent != nil && n.Parent.Data == "style" && strings.Contains(n.Data, "marker") {
+			usesMarkers = true
+		}
+		return usesMarkers
+	})
+	if usesMarkers {
+		return 0, 0
+	}
+
+	signature := func(n *html.Node) (string, bool) {
+		if n.Type != html.ElementNode || n.Data != "line" || n.FirstChild != nil {
+			return "", false
+		}
+		for _, a := range n.Attr {
+			if a.Key == "id" || strings.HasPrefix(a.Key, "marker") || strings.Contains(a.Val, "marker") {
+				return "", false
+			}
+		}
+		return lineStyleKey(n), true
+	}
+
+	styles := parseClassStyles(svg)
+	var parents []*html.Node
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.FirstChild != nil {
+			parents = append(parents, n)
+		}
+		return false
+	})
+	for _, parent := range parents {
+		var run []*html.Node
+		var key string
+		flush := func() {
+			if len(run) == 0 {
+				return
+			}
+			var d strings.Builder
+			for _, l := range run {
+				x1, y1 := getAttr(l, "x1"), getAttr(l, "y1")
+				x2, y2 := getAttr(l, "x2"), getAttr(l, "y2")
+				d.WriteString("M" + orZero(x1) + " " + orZero(y1))
+				switch {
+				case y1 == y2:
+					d.WriteString("H" + orZero(x2))
+				case x1 == x2:
+					d.WriteString("V" + orZero(y2))
+				default:
+					d.WriteString("L" + orZero(x2) + " " + orZero(y2))
+				}
+			}
+			path := &html.Node{Type: html.ElementNode, Data: "path", Namespace: run[0].Namespace}
+			path.Attr = append(path.Attr, html.Attribute{Key: "d", Val: d.String()})
+			for _, a := range run[0].Attr {
+				if !lineCoords[a.Key] {
+					path.Attr = append(path.Attr, a)
+				}
+			}
+			if styles.property(path, "fill") != "none" {
+				setAttr(path, "fill", "none")
+			}
+			parent.InsertBefore(path, run[0])
+			for _, l := range run {
+				parent.RemoveChild(l)
+			}
+			lines += len(run)
+			paths++
+			run = nil
+		}
+		for c := parent.FirstChild; c != nil; {
+			next := c.NextSibling
+			if c.Type == html.TextNode && strings.TrimSpace(c.Data) == "" {
+				c = next
+				continue
+			}
+			if sig, ok := signature(c); ok {
+				if len(run) > 0 && sig != key {
+					flush()
+				}
+				run, key = append(run, c), sig
+			} else {
+				flush()
+			}
+			c = next
+		}
+		flush()
+	}
+	return lines, paths
+}
+
+// orZero повертає "0" для відсутньої координати (значення SVG за замовчуванням).
+func orZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
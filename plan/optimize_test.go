@@ -0,0 +1,108 @@
+package plan
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"testing"
+)
+
+// rasterize рендерить SVG через oksvg у растр заданої ширини з пропорціями плану.
+func rasterize(t *testing.T, svg []byte, width int) image.Image {
+	t.Helper()
+	d := parseSVG(t, string(svg))
+	w, h, ok := PixelSize(d, 96)
+	if !ok {
+		t.Fatal("не вдалося визначити розмір плану")
+	}
+	img, err := rasterizeWithOksvg(context.Background(), svg, width, width*h/w)
+	if err != nil {
+		t.Fatalf("рендеринг: %v", err)
+	}
+	return img
+}
+
+// diffRatio повертає частку пікселів, яскравість яких відрізняється більше ніж на tolerance.
+func diffRatio(a, b image.Image, tolerance int) float64 {
+	bounds := a.Bounds()
+	diff := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, _ := a.At(x, y).RGBA()
+			r2, g2, b2, _ := b.At(x, y).RGBA()
+			l1, l2 := int(r1+g1+b1)/3>>8, int(r2+g2+b2)/3>>8
+			if l1-l2 > tolerance || l2-l1 > tolerance {
+				diff++
+			}
+		}
+	}
+	return float64(diff) / float64(bounds.Dx()*bounds.Dy())
+}
+
+func TestOptimizeKeepsRendering(t *testing.T) {
+	for _, name := range []string{"plan1.html", "plan2.html", "full.html"} {
+		t.Run(name, func(t *testing.T) {
+			d := loadPlan(t, name)
+			// Обидва варіанти проходять через Serializer, тож різниця - лише від оптимізації
+			var before, after bytes.Buffer
+			if err := (Serializer{}).Serialize(context.Background(), &before, d); err != nil {
+				t.Fatal(err)
+			}
+			r, err := WriteOptimized(context.Background(), &after, d, OptimizeOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.After >= r.Before || r.Lines == 0 {
+				t.Errorf("оптимізація нічого не дала: %+v", r)
+			}
+			if ratio := diffRatio(rasterize(t, before.Bytes(), 600), rasterize(t, after.Bytes(), 600), 24); ratio > 0.002 {
+				t.Errorf("після оптимізації змінилося %.2f%% пікселів", ratio*100)
+			}
+		})
+	}
+}
+
+func TestLinesToPathsFill(t *testing.T) {
+	d := parseSVG(t, `<svg viewBox="0 0 100 100">
+		<style>
+			.wall { stroke: #000; stroke-width: 4; }
+			.doors { fill: #FFF; stroke: #FFF; stroke-width: 8; }
+			.stair-step { fill: none; stroke: #000; }
+		</style>
+		<line class="wall" x1="0" y1="0" x2="100" y2="0"/>
+		<line class="wall" x1="0" y1="0" x2="0" y2="100"/>
+		<line class="doors" x1="10" y1="0" x2="30" y2="0"/>
+		<line class="stair-step" x1="50" y1="50" x2="50" y2="80"/>
+	</svg>`)
+	lines, paths := linesToPaths(d.SVG)
+	if lines != 4 || paths != 3 {
+		t.Fatalf("ліній %d, шляхів %d; очікувалось 4 і 3", lines, paths)
+	}
+	got := make(map[string]string)
+	for _, p := range elementsByTag(d, "path") {
+		got[getAttr(p, "class")] = getAttr(p, "d") + " fill=" + getAttr(p, "fill")
+	}
+	want := map[string]string{
+		// Шлях без fill у стилях залився б чорним, а заливка класу - кольором дверей
+		"wall":       "M0 0H100M0 0V100 fill=none",
+		"doors":      "M10 0H30 fill=none",
+		"stair-step": "M50 50V80 fill=",
+	}
+	for class, w := range want {
+		if got[class] != w {
+			t.Errorf("path.%s: %q, очікувалось %q", class, got[class], w)
+		}
+	}
+	if n := len(elementsByTag(d, "line")); n != 0 {
+		t.Errorf("лишилося %d ліній", n)
+	}
+
+	// Маркери в стилях вимикають перетворення
+	marked := parseSVG(t, `<svg viewBox="0 0 100 100">
+		<style>.arrow { marker-end: url(#head); }</style>
+		<line class="arrow" x1="0" y1="0" x2="100" y2="0"/>
+	</svg>`)
+	if lines, _ := linesToPaths(marked.SVG); lines != 0 || len(elementsByTag(marked, "line")) != 1 {
+		t.Error("лінії при маркерах у стилях не мали змінюватися")
+	}
+}
@@ -0,0 +1,139 @@
+package plan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"io/fs"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// renderWithRsvg конвертує SVG через rsvg-convert (stdin → stdout) у формат png або pdf.
+// width <= 0 означає розмір з документа; dpi <= 0 - роздільна здатність rsvg за замовчуванням.
+func renderWithRsvg(ctx context.Context, path string, svgData []byte, format string, width int, dpi float64) ([]byte, error) {
+	args := []string{"-f", format, "-b", "white", "--keep-aspect-ratio"}
+	if width > 0 {
+		args = append(args, "-w", strconv.Itoa(width))
+	}
+	if dpi > 0 {
+		d := strconv.FormatFloat(dpi, 'f', -1, 64)
+		args = append(args, "--dpi-x", d, "--dpi-y", d)
+	}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdin = bytes.NewReader(svgData)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+			return nil, Errorf("%w: %w", ErrRendererMissing, err)
+		}
+		return nil, Errorf("помилка конвертації через rsvg-convert: %w\nВивід: %s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// rasterizeWithOksvg рендерить SVG у зображення заданого розміру з білим фоном (без тексту).
+func rasterizeWithOksvg(ctx context.Context, svgData []byte, width, height int) (image.Image, error) {
+	// Стилі класів переносяться в атрибути елементів, бо oksvg розбирає <style> лише частково
+	d, err := Extractor{}.Extract(ctx, bytes.NewReader(svgData))
+	if err != nil {
+		return nil, err
+	}
+	inlineClassStyles(d.SVG)
+	var inlined bytes.Buffer
+	if err := (Serializer{}).Serialize(ctx, &inlined, d); err != nil {
+		return nil, err
+	}
+
+	// Видаляємо трансформації, які oksvg не підтримує
+	svgString := replaceTransform(inlined.String())
+
+	// Перевіряємо, чи потрібно дзеркально відобразити
+	needsFlip := bytes.Contains(svgData, []byte(`transform="scale(-1, 1)"`))
+
+	// Парсимо SVG
+	icon, err := oksvg.ReadIconStream(strings.NewReader(svgString))
+	if err != nil {
+		return nil, Errorf("%w SVG: %w", ErrParse, err)
+	}
+
+	// Встановлюємо розміри
+	icon.SetTarget(0, 0, float64(width), float64(height))
+
+	// Створюємо зображення з білим фоном
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+
+	// Рендеримо SVG
+	scanner := rasterx.NewScannerGV(width, height, img, img.Bounds())
+	raster := rasterx.NewDasher(width, height, scanner)
+	icon.Draw(raster, 1.0)
+
+	// Дзеркально відображаємо якщо потрібно
+	if needsFlip {
+		return flipHorizontal(img), nil
+	}
+	return img, nil
+}
+
+// unitsPerInch - кількість одиниць довжини SVG в одному дюймі.
+var unitsPerInch = map[string]float64{
+	"in": 1,
+	"cm": 2.54,
+	"mm": 25.4,
+	"pt": 72,
+	"pc": 6,
+	"px": 96,
+	"":   96,
+}
+
+// PixelSize обчислює розмір растру для заданої роздільної здатності за атрибутами width/height
+// (з одиницями виміру) або, якщо їх немає, за viewBox (1 одиниця = 1 px при 96 dpi).
+func PixelSize(d *Document, dpi float64) (int, int, bool) {
+	svg := d.SVG
+	if dpi <= 0 {
+		dpi = 96
+	}
+	w, okW := physicalInches(getAttr(svg, "width"))
+	h, okH := physicalInches(getAttr(svg, "height"))
+	if okW && okH {
+		return int(math.Round(w * dpi)), int(math.Round(h * dpi)), true
+	}
+	_, _, vw, vh, ok := parseViewBox(svg)
+	if !ok || vw <= 0 || vh <= 0 {
+		return 0, 0, false
+	}
+	return int(math.Round(vw * dpi / 96)), int(math.Round(vh * dpi / 96)), true
+}
+
+// physicalInches переводить довжину SVG (наприклад, "297mm") у дюйми.
+func physicalInches(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.HasSuffix(s, "%") {
+		return 0, false
+	}
+	unit := strings.TrimLeft(s, "0123456789.+-eE")
+	perInch, ok := unitsPerInch[unit]
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, unit), 64)
+	if err != nil || v <= 0 {
+		return 0, false
+	}
+	return v / perInch, true
+}
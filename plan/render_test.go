@@ -0,0 +1,81 @@
+package plan
+
+import (
+	"context"
+	"image/color"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestInlineClassStyles(t *testing.T) {
+	d := parseSVG(t, `<svg viewBox="0 0 100 100">
+		<style>
+			.wall { stroke: #000; stroke-width: 4; }
+			.red { fill: #f00; }
+		</style>
+		<rect id="a" class="red" fill="#0f0" width="10" height="10"/>
+		<rect id="b" class="red wall" style="fill: #00f" width="10" height="10"/>
+		<rect id="c" fill="#0f0" width="10" height="10"/>
+	</svg>`)
+	inlineClassStyles(d.SVG)
+
+	byID := make(map[string]*html.Node)
+	var styles int
+	traverse(d.SVG, func(n *html.Node) bool {
+		if n.Type == html.ElementNode {
+			if n.Data == "style" {
+				styles++
+			}
+			if id := getAttr(n, "id"); id != "" {
+				byID[id] = n
+			}
+		}
+		return false
+	})
+	if styles != 0 {
+		t.Errorf("лишилося %d блоків <style>", styles)
+	}
+	cases := []struct {
+		id, style, fill string
+	}{
+		// Клас важить більше за атрибут представлення, тож fill переходить у style
+		{"a", "fill:#f00", ""},
+		// Inline style важить більше за клас; властивості кількох класів збираються разом
+		{"b", "stroke:#000;stroke-width:4;fill: #00f", ""},
+		// Елемент без класу не змінюється
+		{"c", "", "#0f0"},
+	}
+	for _, c := range cases {
+		n := byID[c.id]
+		if getAttr(n, "style") != c.style || getAttr(n, "fill") != c.fill || getAttr(n, "class") != "" {
+			t.Errorf("%s: style=%q fill=%q class=%q; очікувалось style=%q fill=%q",
+				c.id, getAttr(n, "style"), getAttr(n, "fill"), getAttr(n, "class"), c.style, c.fill)
+		}
+	}
+}
+
+func TestRasterizeWithOksvgClassStyles(t *testing.T) {
+	// Стиль поза <defs> і розірваний коментарем oksvg сам не прочитав би
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100" viewBox="0 0 100 100">
+		<style>
+			.red { fill: #f00; }
+			/* заливки */
+			.blue { fill: #00f; }
+		</style>
+		<rect class="red" x="0" y="0" width="50" height="100"/>
+		<rect class="blue" x="50" y="0" width="50" height="100"/>
+	</svg>`)
+	img, err := rasterizeWithOksvg(context.Background(), svg, 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		x    int
+		want color.RGBA
+	}{{25, color.RGBA{0xff, 0, 0, 0xff}}, {75, color.RGBA{0, 0, 0xff, 0xff}}} {
+		if got := color.RGBAModel.Convert(img.At(c.x, 50)).(color.RGBA); got != c.want {
+			t.Errorf("піксель (%d, 50): %v, очікувалось %v", c.x, got, c.want)
+		}
+	}
+}
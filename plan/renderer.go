@@ -0,0 +1,203 @@
+package plan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"os/exec"
+	"runtime/debug"
+	"strings"
+)
+
+// Формати растрового виводу.
+const (
+	FormatPNG = "png"
+	FormatPDF = "pdf"
+)
+
+// ErrUnsupportedFormat повертається, коли рендерер не підтримує запитаний формат.
+var ErrUnsupportedFormat = errors.New("формат не підтримується рендерером")
+
+// RenderOptions задає параметри рендерингу.
+type RenderOptions struct {
+	Format string // png (за замовчуванням) або pdf
+	// Width, Height - розмір у пікселях; 0 - з документа з урахуванням DPI
+	Width, Height int
+	DPI           float64 // 0 - 96 dpi
+}
+
+// Renderer перетворює SVG-розмітку у растрове зображення чи PDF.
+type Renderer interface {
+	Render(ctx context.Context, w io.Writer, svg io.Reader, opts RenderOptions) error
+}
+
+// RsvgRenderer рендерить через зовнішню програму rsvg-convert (повна підтримка тексту і CSS).
+type RsvgRenderer struct {
+	// Path - шлях до rsvg-convert; порожній - пошук у PATH
+	Path string
+}
+
+// OksvgRenderer - вбудований рендерер на чистому Go (лише PNG, без тексту).
+type OksvgRenderer struct{}
+
+// RsvgAvailable перевіряє, чи встановлений rsvg-convert.
+func RsvgAvailable() bool {
+	_, err := exec.LookPath("rsvg-convert")
+	return err == nil
+}
+
+// DefaultRenderer повертає rsvg-convert, якщо він встановлений, інакше oksvg.
+func DefaultRenderer() Renderer {
+	if RsvgAvailable() {
+		return RsvgRenderer{}
+	}
+	return OksvgRenderer{}
+}
+
+// Render виконує rsvg-convert з SVG на stdin і записує результат у w.
+// Дзеркальні CSS-трансформації видаляються перед рендерингом;
+// Height не використовується - висота визначається пропорціями документа.
+func (r RsvgRenderer) Render(ctx context.Context, w io.Writer, svg io.Reader, opts RenderOptions) error {
+	data, err := io.ReadAll(svg)
+	if err != nil {
+		return Errorf("помилка читання SVG: %w", err)
+	}
+	format := opts.Format
+	if format == "" {
+		format = FormatPNG
+	}
+	if format != FormatPNG && format != FormatPDF {
+		return Errorf("rsvg-convert: %w: %s", ErrUnsupportedFormat, format)
+	}
+	path := r.Path
+	if path == "" {
+		path = "rsvg-convert"
+	}
+	out, err := renderWithRsvg(ctx, path, []byte(replaceTransform(string(data))), format, opts.Width, opts.DPI)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(out); err != nil {
+		return Errorf("%w: %w", ErrWrite, err)
+	}
+	return nil
+}
+
+// Render растеризує SVG у PNG. Якщо розмір не задано, він обчислюється з документа.
+func (OksvgRenderer) Render(ctx context.Context, w io.Writer, svg io.Reader, opts RenderOptions) error {
+	if opts.Format != "" && opts.Format != FormatPNG {
+		return Errorf("oksvg: %w: %s", ErrUnsupportedFormat, opts.Format)
+	}
+	data, err := io.ReadAll(svg)
+	if err != nil {
+		return Errorf("помилка читання SVG: %w", err)
+	}
+
+	width, height := opts.Width, opts.Height
+	if width <= 0 || height <= 0 {
+		d, err := Extractor{}.Extract(ctx, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		pw, ph, ok := PixelSize(d, opts.DPI)
+		if !ok {
+			return Errorf("не вдалося визначити розмір SVG (немає width/height чи viewBox)")
+		}
+		switch {
+		case width > 0:
+			height = width * ph / pw
+		case height > 0:
+			width = height * pw / ph
+		default:
+			width, height = pw, ph
+		}
+	}
+
+	img, err := rasterizeWithOksvg(ctx, data, width, height)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := png.Encode(w, img); err != nil {
+		return Errorf("%w PNG: %w", ErrWrite, err)
+	}
+	return nil
+}
+
+// Version повертає версію rsvg-convert (перший рядок --version) для ключів кешу;
+// якщо програму не вдалося запустити - лише її шлях.
+func (r RsvgRenderer) Version() string {
+	path := r.Path
+	if path == "" {
+		path = "rsvg-convert"
+	}
+	out, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		return path
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return line
+}
+
+// Version повертає версію модуля oksvg, з яким зібрано програму.
+func (OksvgRenderer) Version() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/srwiley/oksvg" {
+				return "oksvg " + dep.Version
+			}
+		}
+	}
+	return "oksvg"
+}
+
+// flipHorizontal дзеркально відображає зображення по горизонталі
+func flipHorizontal(src *image.RGBA) *image.RGBA {
+	bounds := src.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	flipped := image.NewRGBA(bounds)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			flipped.Set(width-1-x, y, src.At(x, y))
+		}
+	}
+
+	return flipped
+}
+
+// replaceTransform видаляє атрибут transform зі SVG тега та CSS трансформації з текстових стилів
+func replaceTransform(svgString string) string {
+	result := svgString
+
+	// 1. Видаляємо transform="scale(-1, 1)" з головного SVG тегу
+	result = string(bytes.ReplaceAll([]byte(result), []byte(`transform="scale(-1, 1)"`), []byte("")))
+
+	// 2. Видаляємо style="transform-origin: center;" з головного SVG тегу
+	result = string(bytes.ReplaceAll([]byte(result), []byte(`style="transform-origin: center;"`), []byte("")))
+
+	// 3. Видаляємо CSS трансформації з текстових стилів (незалежно від відступів)
+	// Видаляємо цілі рядки з цими властивостями
+	lines := bytes.Split([]byte(result), []byte("\n"))
+	var cleanedLines [][]byte
+
+	for _, line := range lines {
+		lineStr := string(bytes.TrimSpace(line))
+		// Пропускаємо рядки з CSS трансформаціями
+		if lineStr == "transform: scale(-1, 1);" ||
+			lineStr == "transform-box: fill-box;" ||
+			lineStr == "transform-origin: center;" {
+			continue
+		}
+		cleanedLines = append(cleanedLines, line)
+	}
+
+	return string(bytes.Join(cleanedLines, []byte("\n")))
+}
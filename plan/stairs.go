@@ -0,0 +1,178 @@
+package plan
+
+import (
+	"math"
+	"regexp"
+	"sort"
+
+	"golang.org/x/net/html"
+)
+
+// stairGap - максимальна відстань між сходинками одного сходового маршу.
+const stairGap = 20
+
+// stairLabelRe шукає позначення сходової клітки в коментарі: "Сходи П2", "Стрілка П3".
+var stairLabelRe = regexp.MustCompile(`(?:Сходи|Стрілка)\s+(П\d+)`)
+
+// Stairwell - сходова клітка на плані поверху: група ліній .stair-step
+// зі стрілкою .arrow, що вказує напрямок руху.
+type Stairwell struct {
+	// ID - позначення в межах будівлі (спільне для клітки на всіх поверхах)
+	ID string `json:"id"`
+	// Label - позначення з коментаря плану (П2), якщо воно є
+	Label string `json:"label,omitempty"`
+	// Path - шлях до першої сходинки
+	Path string `json:"path"`
+	// X, Y, W, H - прямокутник сходинок в абсолютних координатах
+	X, Y, W, H float64 `json:"-"`
+	// Exit - точка виходу зі сходів (вістря стрілки або центр)
+	Exit point `json:"-"`
+}
+
+// center повертає центр сходової клітки.
+func (s Stairwell) center() point {
+	return point{s.X + s.W/2, s.Y + s.H/2}
+}
+
+// FindStairwells знаходить сходові клітки плану. Сходинки групуються за близькістю,
+// позначення береться з коментаря "Сходи П2" чи "Стрілка П2" перед сходинками або стрілкою.
+func FindStairwells(d *Document) []Stairwell {
+	var stairs []Stairwell
+	var arrows []*html.Node
+	traverse(d.SVG, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		if n.Data == "defs" || n.Data == "symbol" {
+			return true
+		}
+		if n.Data == "polygon" && hasClass(n, "arrow") {
+			arrows = append(arrows, n)
+		}
+		if n.Data != "line" || !hasClass(n, "stair-step") {
+			return false
+		}
+		s := lineSegment(n)
+		b := box{math.Min(s.A.X, s.B.X), math.Min(s.A.Y, s.B.Y), math.Abs(s.B.X - s.A.X), math.Abs(s.B.Y - s.A.Y)}
+		for i := range stairs {
+			st := &stairs[i]
+			if b.X <= st.X+st.W+stairGap && st.X <= b.X+b.W+stairGap &&
+				b.Y <= st.Y+st.H+stairGap && st.Y <= b.Y+b.H+stairGap {
+				x1, y1 := math.Max(st.X+st.W, b.X+b.W), math.Max(st.Y+st.H, b.Y+b.H)
+				st.X, st.Y = math.Min(st.X, b.X), math.Min(st.Y, b.Y)
+				st.W, st.H = x1-st.X, y1-st.Y
+				return false
+			}
+		}
+		stairs = append(stairs, Stairwell{Label: precedingLabel(n), Path: elementPath(n), X: b.X, Y: b.Y, W: b.W, H: b.H})
+		return false
+	})
+
+	for i := range stairs {
+		st := &stairs[i]
+		st.Exit = st.center()
+		for _, a := range arrows {
+			pts := polygonPoints(a)
+			if len(pts) == 0 {
+				continue
+			}
+			c := point{}
+			for _, p := range pts {
+				c.X += p.X / float64(len(pts))
+				c.Y += p.Y / float64(len(pts))
+			}
+			if c.X < st.X-stairGap || c.X > st.X+st.W+stairGap || c.Y < st.Y-stairGap || c.Y > st.Y+st.H+stairGap {
+				continue
+			}
+			// Вістря вузького трикутника - вершина, найвіддаленіша від центру
+			tip := pts[0]
+			for _, p := range pts {
+				if dist(p, c) > dist(tip, c) {
+					tip = p
+				}
+			}
+			st.Exit = tip
+			if st.Label == "" {
+				st.Label = precedingLabel(a)
+			}
+			break
+		}
+	}
+
+	sort.SliceStable(stairs, func(a, b int) bool { return stairs[a].X < stairs[b].X })
+	return stairs
+}
+
+// precedingLabel шукає позначення сходів у найближчих попередніх коментарях.
+func precedingLabel(n *html.Node) string {
+	for s, seen := n.PrevSibling, 0; s != nil && seen < 3; s = s.PrevSibling {
+		switch s.Type {
+		case html.CommentNode:
+			if m := stairLabelRe.FindStringSubmatch(s.Data); m != nil {
+				return m[1]
+			}
+			seen++
+		case html.ElementNode:
+			if !hasClass(s, "stair-step") && !hasClass(s, "arrow") {
+				seen++
+			}
+		}
+	}
+	return ""
+}
+
+// exitProbe - відступ від дверей по обидва боки, на якому перевіряється, чи там ще будівля.
+const exitProbe = 10.0
+
+// exitDoors повертає виходи з будівлі: двері без номера .door-number на межі будівлі - контурі
+// .outline, з одного боку якого немає жодного контуру. Двері на спільній межі сусідніх секцій
+// і пронумеровані двері квартир і кімнат - звичайні.
+func exitDoors(svg *html.Node) []segment {
+	var outlines [][]point
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		if n.Data == "defs" || n.Data == "symbol" {
+			return true
+		}
+		if n.Data == "polygon" && hasClass(n, "outline") {
+			outlines = append(outlines, polygonPoints(n))
+		}
+		return false
+	})
+	inside := func(p point) bool {
+		for _, poly := range outlines {
+			if pointInPolygon(p, poly) {
+				return true
+			}
+		}
+		return false
+	}
+
+	doors := planDoors(svg, DoorNumbering{})
+	owner := matchDoorLabels(doors, labelsByPriority(svg, []string{"door-number"}), parseClassStyles(svg), defaultDoorLabelRadius)
+	var exits []segment
+	for i, d := range doors {
+		l := d.seg.length()
+		if l == 0 || owner[i] != nil {
+			continue
+		}
+		onOutline := false
+		for _, poly := range outlines {
+			if doorOnWall(d.seg, edges(poly)) {
+				onOutline = true
+				break
+			}
+		}
+		if !onOutline {
+			continue
+		}
+		// Точки по обидва боки від середини дверей: зовні будівлі хоча б одна з них
+		nx, ny := -(d.seg.B.Y-d.seg.A.Y)/l*exitProbe, (d.seg.B.X-d.seg.A.X)/l*exitProbe
+		if !inside(point{d.mid.X + nx, d.mid.Y + ny}) || !inside(point{d.mid.X - nx, d.mid.Y - ny}) {
+			exits = append(exits, d.seg)
+		}
+	}
+	return exits
+}
@@ -0,0 +1,198 @@
+package plan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Document - SVG-план, витягнутий з HTML.
+// SVG - кореневий елемент <svg> у дереві golang.org/x/net/html; його можна змінювати напряму.
+type Document struct {
+	SVG *html.Node
+}
+
+// Extractor витягує SVG-документ з HTML.
+type Extractor struct {
+	// Selector - простий CSS-селектор (svg, #id, .class, tag#id, tag.class) елемента,
+	// який є <svg> або містить його. Порожній рядок - перший <svg> у документі.
+	Selector string
+	// InjectSymbols додає з бібліотеки символи, на які є <use>, але немає визначення.
+	InjectSymbols bool
+}
+
+// Extract читає HTML і повертає документ з першим <svg>, що відповідає селектору.
+func (e Extractor) Extract(ctx context.Context, r io.Reader) (*Document, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, Errorf("%w HTML: %w", ErrParse, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return e.ExtractNode(doc)
+}
+
+// ExtractNode шукає <svg> у вже розібраному HTML-дереві.
+func (e Extractor) ExtractNode(doc *html.Node) (*Document, error) {
+	svg := findSVG(doc)
+	if e.Selector != "" {
+		svg = nil
+		if target := findElement(doc, func(n *html.Node) bool { return matchSelector(n, e.Selector) }); target != nil {
+			svg = findSVG(target)
+		}
+	}
+	if svg == nil {
+		return nil, ErrNoSVG
+	}
+
+	d := &Document{SVG: svg}
+	if e.InjectSymbols {
+		if _, err := InjectLibrarySymbols(d); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Serializer записує SVG-документ як XML з відступами і самозакриваючими тегами.
+type Serializer struct {
+	// Optimize - якщо задано, документ записується оптимізованим (див. Optimize) і без
+	// відступів; сам d не змінюється.
+	Optimize *OptimizeOptions
+}
+
+// Serialize записує документ у w.
+func (s Serializer) Serialize(ctx context.Context, w io.Writer, d *Document) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.Optimize != nil {
+		_, err := WriteOptimized(ctx, w, d, *s.Optimize)
+		return err
+	}
+	var buf bytes.Buffer
+	if err := renderSVG(&buf, d.SVG, false); err != nil {
+		return Errorf("помилка рендерингу SVG-вузла: %w", err)
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return Errorf("%w SVG: %w", ErrWrite, err)
+	}
+	return nil
+}
+
+// ViewBox повертає viewBox документа (або 0 0 width height, якщо viewBox не задано).
+func (d *Document) ViewBox() (x, y, w, h float64, ok bool) {
+	return parseViewBox(d.SVG)
+}
+
+// renderSVG рендерить SVG-вузол у правильному форматі XML з самозакриваючими тегами.
+// compact - без відступів і переносів рядків, пробіли в тексті стиснуті.
+func renderSVG(w io.Writer, n *html.Node, compact bool) error {
+	// Список SVG елементів, які повинні бути самозакриваючими
+	selfClosingTags := map[string]bool{
+		"circle": true, "ellipse": true, "line": true, "path": true,
+		"polygon": true, "polyline": true, "rect": true, "use": true,
+		"image": true, "stop": true, "animate": true, "animateMotion": true,
+		"animateTransform": true, "set": true,
+	}
+
+	newline, closeTag := "\n", " />\n"
+	text := escapeText
+	if compact {
+		newline, closeTag = "", "/>"
+		text = func(s string) string { return escapeText(spaceRe.ReplaceAllString(s, " ")) }
+	}
+
+	var render func(*html.Node, int) error
+	render = func(n *html.Node, depth int) error {
+		indent := ""
+		for i := 0; i < depth && !compact; i++ {
+			indent += "    "
+		}
+
+		switch n.Type {
+		case html.ElementNode:
+			// Відкриваючий тег
+			fmt.Fprintf(w, "%s<%s", indent, n.Data)
+
+			// Атрибути
+			for _, attr := range n.Attr {
+				fmt.Fprintf(w, " %s=\"%s\"", attr.Key, html.EscapeString(attr.Val))
+			}
+
+			// Перевірка чи є дочірні елементи
+			hasChildren := n.FirstChild != nil
+
+			// Якщо це самозакриваючий тег і немає дітей
+			if selfClosingTags[n.Data] && !hasChildren {
+				fmt.Fprint(w, closeTag)
+			} else if !hasChildren && n.Data != "svg" && n.Data != "g" && n.Data != "defs" && n.Data != "style" && n.Data != "text" {
+				// Інші порожні елементи (крім контейнерів)
+				fmt.Fprint(w, closeTag)
+			} else {
+				fmt.Fprintf(w, ">")
+
+				// Якщо це текстовий контейнер, не додаємо новий рядок
+				if n.Data == "text" || n.Data == "style" {
+					// Рендеримо дітей без відступів
+					for c := n.FirstChild; c != nil; c = c.NextSibling {
+						if c.Type == html.TextNode {
+							fmt.Fprint(w, text(c.Data))
+						} else {
+							render(c, 0)
+						}
+					}
+					fmt.Fprintf(w, "</%s>%s", n.Data, newline)
+				} else {
+					fmt.Fprint(w, newline)
+					// Рендеримо дочірні елементи
+					for c := n.FirstChild; c != nil; c = c.NextSibling {
+						if err := render(c, depth+1); err != nil {
+							return err
+						}
+					}
+					fmt.Fprintf(w, "%s</%s>%s", indent, n.Data, newline)
+				}
+			}
+
+		case html.TextNode:
+			// Пропускаємо порожні текстові вузли (пробіли між тегами)
+			trimmed := bytes.TrimSpace([]byte(n.Data))
+			if len(trimmed) > 0 {
+				if compact {
+					fmt.Fprint(w, text(string(trimmed)))
+				} else {
+					fmt.Fprintf(w, "%s%s\n", indent, escapeText(n.Data))
+				}
+			}
+
+		case html.CommentNode:
+			fmt.Fprintf(w, "%s<!-- %s -->%s", indent, n.Data, newline)
+		}
+
+		return nil
+	}
+
+	return render(n, 0)
+}
+
+// escapeText екранує спецсимволи XML у текстовому вмісті (лапки в тексті лишаються як є).
+func escapeText(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// traverse рекурсивно обходить усі вузли і викликає функцію 'f' для кожного з них.
+// Якщо 'f' повертає true, обхід припиняється.
+func traverse(n *html.Node, f func(*html.Node) bool) {
+	if f(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		traverse(c, f)
+	}
+}
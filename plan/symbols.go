@@ -0,0 +1,121 @@
+package plan
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// symbolLibrary містить вбудовані символи: по одному файлу symbols/<id>.svg
+// з коментарем-назвою та елементом <symbol id="<id>">.
+//
+//go:embed symbols/*.svg
+var symbolLibrary embed.FS
+
+// LibrarySymbolIDs повертає відсортований список id символів бібліотеки.
+func LibrarySymbolIDs() []string {
+	entries, err := fs.ReadDir(symbolLibrary, "symbols")
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".svg") {
+			ids = append(ids, strings.TrimSuffix(e.Name(), ".svg"))
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// LibraryHash повертає SHA-256 вмісту бібліотеки символів: змінюється разом з будь-яким символом,
+// тож придатний для ключів кешу результатів, у які вбудовуються символи.
+func LibraryHash() string {
+	h := sha256.New()
+	for _, id := range LibrarySymbolIDs() {
+		data, _ := librarySymbol(id)
+		h.Write([]byte(id + "\n" + data + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// librarySymbol повертає розмітку символу з бібліотеки.
+func librarySymbol(id string) (string, bool) {
+	data, err := symbolLibrary.ReadFile(path.Join("symbols", id+".svg"))
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// InjectLibrarySymbols додає в <defs> символи бібліотеки, на які посилаються <use>,
+// але які не визначені в самому документі. Визначення з документа мають пріоритет.
+// Повертає id доданих символів.
+func InjectLibrarySymbols(d *Document) ([]string, error) {
+	svg := d.SVG
+	defined := make(map[string]bool)
+	var referenced []string
+	seen := make(map[string]bool)
+
+	traverse(svg, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		if id := getAttr(n, "id"); id != "" {
+			defined[id] = true
+		}
+		if n.Data == "use" {
+			if id := useHref(n); id != "" && !seen[id] {
+				seen[id] = true
+				referenced = append(referenced, id)
+			}
+		}
+		return false
+	})
+
+	var missing []string
+	for _, id := range referenced {
+		if !defined[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	defs := ensureDefs(svg)
+	var injected []string
+	for _, id := range missing {
+		markup, ok := librarySymbol(id)
+		if !ok {
+			continue
+		}
+		nodes, err := html.ParseFragment(strings.NewReader(markup), defs)
+		if err != nil {
+			return injected, Errorf("%w символу %s з бібліотеки: %w", ErrParse, id, err)
+		}
+		for _, n := range nodes {
+			defs.AppendChild(n)
+		}
+		injected = append(injected, id)
+	}
+	return injected, nil
+}
+
+// ensureDefs повертає перший <defs> документа, створюючи його на початку SVG за потреби.
+func ensureDefs(svg *html.Node) *html.Node {
+	for c := svg.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "defs" {
+			return c
+		}
+	}
+	defs := newElement("defs")
+	svg.InsertBefore(defs, svg.FirstChild)
+	return defs
+}
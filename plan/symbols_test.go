@@ -0,0 +1,79 @@
+package plan
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestLibrarySymbols(t *testing.T) {
+	ids := LibrarySymbolIDs()
+	if !slices.IsSorted(ids) || !slices.Contains(ids, "toilet") || !slices.Contains(ids, "first-aid") {
+		t.Fatalf("символи бібліотеки: %v", ids)
+	}
+	// Кожен файл бібліотеки - символ з тим самим id, viewBox і назвою для легенди
+	for _, id := range ids {
+		markup, _ := librarySymbol(id)
+		d := parseSVG(t, `<svg viewBox="0 0 100 100"><defs>`+markup+`</defs></svg>`)
+		symbols := elementsByTag(d, "symbol")
+		if len(symbols) != 1 || getAttr(symbols[0], "id") != id {
+			t.Errorf("%s: очікувався один <symbol id=%q>", id, id)
+			continue
+		}
+		if _, _, w, h, ok := parseViewBox(symbols[0]); !ok || w <= 0 || h <= 0 {
+			t.Errorf("%s: символ без коректного viewBox", id)
+		}
+		if label := symbolLabel(symbols[0], id, nil); label == id {
+			t.Errorf("%s: символ без назви для легенди", id)
+		}
+	}
+}
+
+func TestInjectLibrarySymbols(t *testing.T) {
+	d := parseSVG(t, `<svg viewBox="0 0 100 100">
+		<defs><symbol id="sink" viewBox="0 0 10 10"><rect id="own" width="10" height="10"/></symbol></defs>
+		<use href="#toilet" x="0" y="0"/>
+		<use xlink:href="#sink" x="20" y="0"/>
+		<use href="#toilet" x="40" y="0"/>
+		<use href="#no-such-symbol" x="60" y="0"/>
+	</svg>`)
+	injected, err := InjectLibrarySymbols(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Лише використані й не визначені в документі символи; невідомі id пропускаються
+	if strings.Join(injected, ",") != "toilet" {
+		t.Errorf("додано символи %v, очікувалось [toilet]", injected)
+	}
+	var ids []string
+	for _, s := range elementsByTag(d, "symbol") {
+		ids = append(ids, getAttr(s, "id"))
+	}
+	if strings.Join(ids, ",") != "sink,toilet" {
+		t.Errorf("символи після вставки: %v", ids)
+	}
+	if findElementByID(d.SVG, "own") == nil {
+		t.Error("власне визначення символу замінено бібліотечним")
+	}
+
+	// Повторна вставка нічого не додає
+	if injected, err := InjectLibrarySymbols(d); err != nil || len(injected) != 0 {
+		t.Errorf("повторна вставка: %v, %v", injected, err)
+	}
+}
+
+func TestInjectLibrarySymbolsCreatesDefs(t *testing.T) {
+	d := parseSVG(t, `<svg viewBox="0 0 100 100"><rect width="10" height="10"/><use href="#first-aid"/></svg>`)
+	if _, err := InjectLibrarySymbols(d); err != nil {
+		t.Fatal(err)
+	}
+	first := d.SVG.FirstChild
+	if first == nil || first.Type != html.ElementNode || first.Data != "defs" {
+		t.Fatal("<defs> не створено на початку SVG")
+	}
+	if len(elementsByTag(d, "symbol")) != 1 {
+		t.Error("символ first-aid не додано в нові <defs>")
+	}
+}
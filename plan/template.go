@@ -0,0 +1,227 @@
+package plan
+
+import (
+	_ "embed"
+	"html"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// DefaultTitleBlockTemplate - шаблон рамки, заголовка та грифа затвердження за замовчуванням.
+//
+//go:embed templates/title_block.svg.tmpl
+var DefaultTitleBlockTemplate string
+
+// Розміри елементів титульного блоку в одиницях viewBox.
+const (
+	titleFrameInset   = 5.0
+	titleFontSize     = 36.0
+	subtitleFontSize  = 26.0
+	titlePadding      = 20.0
+	titleMargin       = 20.0
+	logoSize          = 80.0
+	approvalWidth     = 260.0
+	approvalHeight    = 140.0
+	approvalCornerCut = 50.0
+)
+
+// defaultTitleStyles - стилі класів титульного блоку, які додаються, якщо документ їх не визначає.
+var defaultTitleStyles = map[string]string{
+	"frame":            ".frame { fill: none; stroke: #000; stroke-width: 3; }",
+	"plan-title":       ".plan-title { font-family: Arial; font-size: 36px; font-weight: bold; fill: #df0404; text-anchor: middle; }",
+	"plan-title2":      ".plan-title2 { font-family: Arial; font-size: 26px; font-weight: bold; fill: #df0404; text-anchor: middle; }",
+	"title-background": ".title-background { fill: #FFF; stroke: #000; stroke-width: 0; }",
+	"plan-info":        ".plan-info { font-family: Arial; font-size: 16px; fill: #000; }",
+	"plan-signature":   ".plan-signature { font-family: Arial; font-size: 14px; fill: #000; }",
+	"legend-frame":     ".legend-frame { fill: #FFF; stroke: #000; stroke-width: 2; }",
+	"legend-title":     ".legend-title { font-family: Arial; font-size: 18px; font-weight: bold; fill: #000; }",
+}
+
+// titleBlockClasses - класи, які ручна розмітка рамки та заголовка використовувала в наших планах.
+var titleBlockClasses = []string{"frame", "plan-title", "plan-title2", "title-background"}
+
+// Metadata - дані плану, якими заповнюється шаблон.
+type Metadata struct {
+	Title            string `json:"title"`
+	Subtitle         string `json:"subtitle"`
+	Address          string `json:"address"`
+	Floor            string `json:"floor"`
+	Date             string `json:"date"`
+	ApprovedBy       string `json:"approved_by"`
+	ApprovedPosition string `json:"approved_position"`
+	Logo             string `json:"logo"`
+}
+
+// titleBlockLayout - метадані разом з обчисленими координатами для шаблону.
+type titleBlockLayout struct {
+	Meta                       Metadata
+	Frame, TitleBg, Logo       box
+	Approval                   box
+	ApprovalCutX, ApprovalCutY float64
+	CenterX                    float64
+	TitleY, SubtitleY          float64
+	InfoX, AddressY, FloorY    float64
+}
+
+// ApplyTitleBlock заповнює шаблон метаданими, розміщує результат відносно viewBox
+// і замінює ним ручну розмітку рамки та заголовка.
+// Якщо tmplText порожній, використовується вбудований шаблон.
+func ApplyTitleBlock(d *Document, meta Metadata, tmplText string) error {
+	svg := d.SVG
+	if tmplText == "" {
+		tmplText = DefaultTitleBlockTemplate
+	}
+	if meta.Title == "" {
+		meta.Title = "ПЛАН ЕВАКУАЦІЇ"
+	}
+
+	vx, vy, vw, vh, ok := parseViewBox(svg)
+	if !ok {
+		return Errorf("SVG не має коректного viewBox або width/height")
+	}
+
+	tmpl, err := template.New("title-block").Funcs(template.FuncMap{"num": formatNumber}).Parse(tmplText)
+	if err != nil {
+		return Errorf("%w шаблону: %w", ErrParse, err)
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, layoutTitleBlock(escapeMetadata(meta), meta, vx, vy, vw, vh)); err != nil {
+		return Errorf("помилка заповнення шаблону: %w", err)
+	}
+
+	nodes, err := nethtml.ParseFragment(strings.NewReader(sb.String()), svg)
+	if err != nil {
+		return Errorf("%w результату шаблону: %w", ErrParse, err)
+	}
+	if !slices.ContainsFunc(nodes, func(n *nethtml.Node) bool { return n.Type == nethtml.ElementNode }) {
+		return Errorf("%w результату шаблону: шаблон не створив жодного елемента", ErrParse)
+	}
+
+	removeManualTitleBlock(svg)
+
+	// Титульний блок вставляється одразу після <defs>, щоб план малювався поверх рамки
+	var anchor *nethtml.Node
+	for c := svg.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == nethtml.ElementNode && c.Data == "defs" {
+			anchor = c.NextSibling
+			break
+		}
+	}
+	if anchor == nil {
+		anchor = svg.FirstChild
+	}
+	for _, n := range nodes {
+		svg.InsertBefore(n, anchor)
+	}
+
+	classes := []string{"plan-info", "plan-signature", "legend-frame", "legend-title"}
+	classes = append(classes, titleBlockClasses...)
+	if style := missingStyles(svg, classes, defaultTitleStyles); style != nil {
+		ensureDefs(svg).AppendChild(style)
+	}
+
+	return nil
+}
+
+// layoutTitleBlock обчислює координати елементів титульного блоку.
+// Ширина фону заголовка рахується за неекранованим текстом.
+func layoutTitleBlock(meta, raw Metadata, vx, vy, vw, vh float64) titleBlockLayout {
+	l := titleBlockLayout{
+		Meta:    meta,
+		Frame:   box{vx + titleFrameInset, vy + titleFrameInset, vw - 2*titleFrameInset, vh - 2*titleFrameInset},
+		CenterX: vx + vw/2,
+		TitleY:  vy + titlePadding + titleFontSize,
+	}
+
+	textW := estimateTextWidth(raw.Title, titleFontSize, true)
+	bgH := titlePadding + titleFontSize + titlePadding/2
+	if raw.Subtitle != "" {
+		l.SubtitleY = l.TitleY + subtitleFontSize + titlePadding/2
+		textW = math.Max(textW, estimateTextWidth(raw.Subtitle, subtitleFontSize, true))
+		bgH += subtitleFontSize + titlePadding/2
+	}
+	bgW := textW + 2*titlePadding
+	l.TitleBg = box{l.CenterX - bgW/2, vy, bgW, bgH}
+
+	l.Logo = box{vx + titleMargin, vy + titleMargin, logoSize, logoSize}
+	l.InfoX = vx + titleMargin
+	if raw.Logo != "" {
+		l.InfoX += logoSize + titlePadding
+	}
+	l.AddressY = vy + titleMargin + titlePadding + 16
+	l.FloorY = l.AddressY + 24
+
+	l.Approval = box{vx + vw - titleMargin - approvalWidth, vy + vh - titleMargin - approvalHeight, approvalWidth, approvalHeight}
+	l.ApprovalCutX = approvalWidth - approvalCornerCut
+	l.ApprovalCutY = approvalCornerCut
+	return l
+}
+
+// escapeMetadata екранує текстові поля, щоб їх можна було безпечно вставити в розмітку.
+func escapeMetadata(m Metadata) Metadata {
+	return Metadata{
+		Title:            html.EscapeString(m.Title),
+		Subtitle:         html.EscapeString(m.Subtitle),
+		Address:          html.EscapeString(m.Address),
+		Floor:            html.EscapeString(m.Floor),
+		Date:             html.EscapeString(m.Date),
+		ApprovedBy:       html.EscapeString(m.ApprovedBy),
+		ApprovedPosition: html.EscapeString(m.ApprovedPosition),
+		Logo:             html.EscapeString(m.Logo),
+	}
+}
+
+// removeManualTitleBlock видаляє попередній титульний блок і ручну розмітку рамки та заголовка,
+// включно з написом "ЗАТВЕРДЖУЮ:" і рамкою грифа, що йде одразу за ним.
+func removeManualTitleBlock(svg *nethtml.Node) {
+	var toRemove []*nethtml.Node
+	traverse(svg, func(n *nethtml.Node) bool {
+		if n.Type != nethtml.ElementNode {
+			return false
+		}
+		if getAttr(n, "id") == "title-block" {
+			toRemove = append(toRemove, n)
+			return true
+		}
+		// Легенда має власну рамку і заголовок — її не чіпаємо
+		if getAttr(n, "id") == "legend" {
+			return true
+		}
+		for _, class := range titleBlockClasses {
+			if hasClass(n, class) {
+				toRemove = append(toRemove, n)
+				return false
+			}
+		}
+		if n.Data == "text" && strings.HasPrefix(textContent(n), "ЗАТВЕРДЖУЮ") {
+			toRemove = append(toRemove, n)
+			if next := nextElementSibling(n); next != nil && next.Data == "polygon" && hasClass(next, "legend-frame") {
+				toRemove = append(toRemove, next)
+			}
+		}
+		return false
+	})
+	for _, n := range toRemove {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+// LoadTemplate читає користувацький шаблон з файлу; порожнє ім'я означає вбудований шаблон.
+func LoadTemplate(filename string) (string, error) {
+	if filename == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", Errorf("помилка читання шаблону %s: %w", filename, err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,49 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMissingStyles(t *testing.T) {
+	// .plan-title2 не визначає .plan-title, а складний селектор не рахується визначенням класу
+	d := parseSVG(t, `<svg viewBox="0 0 100 100">
+		<style>
+			.plan-title2 { font-size: 26px; }
+			.frame, .title-background { fill: none; }
+			g .legend-frame { fill: #FFF; }
+		</style>
+	</svg>`)
+	classes := []string{"frame", "plan-title", "plan-title2", "title-background", "legend-frame"}
+	style := missingStyles(d.SVG, classes, defaultTitleStyles)
+	if style == nil {
+		t.Fatal("відсутні стилі не знайдено")
+	}
+	want := defaultTitleStyles["plan-title"] + "\n" + defaultTitleStyles["legend-frame"]
+	if got := textContent(style); got != want {
+		t.Errorf("додані стилі:\n%s\nочікувалось:\n%s", got, want)
+	}
+
+	if style := missingStyles(d.SVG, []string{"frame", "plan-title2"}, defaultTitleStyles); style != nil {
+		t.Errorf("визначені класи додано повторно: %s", textContent(style))
+	}
+}
+
+func TestApplyTitleBlockStyles(t *testing.T) {
+	d := parseSVG(t, `<svg viewBox="0 0 1000 800">
+		<style>.plan-title2 { font-size: 20px; }</style>
+	</svg>`)
+	if err := ApplyTitleBlock(d, Metadata{Title: "План", Subtitle: "Поверх 1"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	var css strings.Builder
+	for _, n := range elementsByTag(d, "style") {
+		css.WriteString(textContent(n))
+	}
+	if !strings.Contains(css.String(), defaultTitleStyles["plan-title"]) {
+		t.Error("стиль .plan-title не додано, хоча визначено лише .plan-title2")
+	}
+	if strings.Contains(css.String(), defaultTitleStyles["plan-title2"]) {
+		t.Error("стиль .plan-title2 додано поверх визначеного в документі")
+	}
+}
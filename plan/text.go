@@ -0,0 +1,103 @@
+package plan
+
+import (
+	"math"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// estimateTextWidth приблизно оцінює ширину рядка в одиницях viewBox
+// для пропорційного шрифту типу Arial заданого розміру.
+// Точні метрики шрифту недоступні без растеризації, тому використовуються
+// усереднені ширини гліфів (частки від font-size).
+func estimateTextWidth(s string, fontSize float64, bold bool) float64 {
+	var em float64
+	for _, r := range s {
+		em += runeWidth(r)
+	}
+	if bold {
+		em *= 1.08
+	}
+	return em * fontSize
+}
+
+// runeWidth повертає усереднену ширину символу в частках em.
+func runeWidth(r rune) float64 {
+	switch {
+	case r == ' ':
+		return 0.28
+	case r == 'i' || r == 'l' || r == 'j' || r == 'і' || r == 'ї' || r == 'I' || r == 'І' || r == 'Ї':
+		return 0.25
+	case r == '.' || r == ',' || r == ':' || r == ';' || r == '!' || r == '\'' || r == '|':
+		return 0.28
+	case r == 'm' || r == 'w' || r == 'ш' || r == 'щ' || r == 'ж' || r == 'ю' || r == 'ы':
+		return 0.8
+	case r == 'M' || r == 'W' || r == 'Ш' || r == 'Щ' || r == 'Ж' || r == 'Ю' || r == 'Ф':
+		return 0.9
+	case unicode.IsDigit(r):
+		return 0.56
+	case unicode.IsUpper(r):
+		return 0.68
+	case unicode.IsLetter(r):
+		return 0.55
+	default:
+		return 0.5
+	}
+}
+
+// textBox оцінює прямокутник, який займає текстовий елемент, в абсолютних координатах
+// з урахуванням text-anchor, розміру шрифту з CSS і трансформацій батьківських груп.
+func textBox(n *html.Node, styles classStyles) box {
+	fontSize := styles.fontSize(n)
+	w := estimateTextWidth(textContent(n), fontSize, styles.isBold(n))
+	x, y := attrFloat(n, "x"), attrFloat(n, "y")
+
+	switch styles.property(n, "text-anchor") {
+	case "middle":
+		x -= w / 2
+	case "end":
+		x -= w
+	}
+
+	// Базова лінія знаходиться приблизно на 0.8 висоти шрифту від верху гліфів
+	top := y - fontSize*0.8
+	m := nodeTransform(n)
+	a := m.apply(point{x, top})
+	b := m.apply(point{x + w, top + fontSize})
+	return box{math.Min(a.X, b.X), math.Min(a.Y, b.Y), math.Abs(b.X - a.X), math.Abs(b.Y - a.Y)}
+}
+
+// center повертає центр прямокутника.
+func (b box) center() point {
+	return point{b.X + b.W/2, b.Y + b.H/2}
+}
+
+// inflate розширює прямокутник на r з кожного боку.
+func (b box) inflate(r float64) box {
+	return box{b.X - r, b.Y - r, b.W + 2*r, b.H + 2*r}
+}
+
+// overlaps перевіряє, чи перекриваються прямокутники.
+func (b box) overlaps(o box) bool {
+	return b.X < o.X+o.W && o.X < b.X+b.W && b.Y < o.Y+o.H && o.Y < b.Y+b.H
+}
+
+// contains перевіряє, чи лежить точка всередині прямокутника.
+func (b box) contains(p point) bool {
+	return p.X >= b.X && p.X <= b.X+b.W && p.Y >= b.Y && p.Y <= b.Y+b.H
+}
+
+// crossedBy перевіряє, чи проходить відрізок через прямокутник.
+func (b box) crossedBy(s segment) bool {
+	if b.contains(s.A) || b.contains(s.B) {
+		return true
+	}
+	corners := []point{{b.X, b.Y}, {b.X + b.W, b.Y}, {b.X + b.W, b.Y + b.H}, {b.X, b.Y + b.H}}
+	for _, e := range edges(corners) {
+		if s.intersects(e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,372 @@
+package plan
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// defaultWallTolerance - допуск злиття стін за замовчуванням, одиниць viewBox.
+const defaultWallTolerance = 1.0
+
+// WallMergeOptions - параметри очищення геометрії стін.
+type WallMergeOptions struct {
+	// Tolerance - наскільки відрізки можуть відходити від спільної прямої, а кінці - один від одного,
+	// щоб вважатися з'єднаними; найбільший розрив, що закривається. 0 - 1. Розриви, в яких
+	// стоять двері (.doors), не закриваються
+	Tolerance float64 `json:"tolerance,omitempty"`
+	// KeepLines - лише зливати колінеарні відрізки в <line>, не з'єднуючи стіни в ламані <polyline>
+	KeepLines bool `json:"keep_lines,omitempty"`
+}
+
+// WallMergeReport - що змінило очищення стін.
+type WallMergeReport struct {
+	// Before, After - кількість елементів стін до й після
+	Before int `json:"before"`
+	After  int `json:"after"`
+	// Duplicates - видалені відрізки, повністю перекриті іншими, і відрізки нульової довжини
+	Duplicates int `json:"duplicates"`
+	// Merged - відрізки, приєднані до колінеарних сусідів
+	Merged int `json:"merged"`
+	// Gaps - закриті розриви між колінеарними відрізками
+	Gaps int `json:"gaps"`
+	// Polylines - ламані, з'єднані з відрізків, що сходяться кінцями
+	Polylines int `json:"polylines"`
+}
+
+// MergeWalls очищує геометрію стін: видаляє дублікати, зливає колінеарні відрізки, що
+// перекриваються, торкаються чи розходяться не більше ніж на допуск, і з'єднує стіни, що
+// сходяться кінцями (рівно дві в точці), у <polyline class="wall">. Зливаються лише лінії
+// .wall без id з одним батьком і однаковими атрибутами; координати лишаються локальними.
+func MergeWalls(d *Document, opts WallMergeOptions) WallMergeReport {
+	tol := opts.Tolerance
+	if tol <= 0 {
+		tol = defaultWallTolerance
+	}
+	styles := parseClassStyles(d.SVG)
+
+	type groupKey struct {
+		parent *html.Node
+		style  string
+	}
+	var keys []groupKey
+	groups := make(map[groupKey][]*html.Node)
+	var doors []segment
+	traverse(d.SVG, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		switch {
+		case n.Data == "defs" || n.Data == "symbol":
+			return true
+		case n.Data == "line" && isDoor(n):
+			doors = append(doors, lineSegment(n))
+		case n.Data == "line" && hasClass(n, "wall") && getAttr(n, "id") == "":
+			k := groupKey{n.Parent, lineStyleKey(n)}
+			if groups[k] == nil {
+				keys = append(keys, k)
+			}
+			groups[k] = append(groups[k], n)
+		}
+		return false
+	})
+
+	var r WallMergeReport
+	for _, k := range keys {
+		lines := groups[k]
+		r.Before += len(lines)
+		segs := make([]segment, len(lines))
+		for i, n := range lines {
+			segs[i] = segment{point{attrFloat(n, "x1"), attrFloat(n, "y1")}, point{attrFloat(n, "x2"), attrFloat(n, "y2")}}
+		}
+		// Розрив закривається, лише якщо в ньому немає дверей (у координатах документа)
+		m := nodeTransform(lines[0])
+		open := func(gap segment) bool {
+			g := segment{m.apply(gap.A), m.apply(gap.B)}
+			for _, door := range doors {
+				mid := point{(door.A.X + door.B.X) / 2, (door.A.Y + door.B.Y) / 2}
+				if g.distToSegment(mid) <= tol || door.distToSegment(point{(g.A.X + g.B.X) / 2, (g.A.Y + g.B.Y) / 2}) <= tol {
+					return true
+				}
+			}
+			return false
+		}
+		merged := mergeCollinear(segs, tol, open, &r)
+
+		var chains [][]point
+		if opts.KeepLines {
+			for _, s := range merged {
+				chains = append(chains, []point{s.A, s.B})
+			}
+		} else {
+			chains = chainSegments(merged, tol, open)
+		}
+
+		// Незмінені відрізки лишаються тими самими елементами, решта створюється заново
+		keep := make(map[*html.Node]bool)
+		var created []*html.Node
+		for _, pts := range chains {
+			if len(pts) == 2 {
+				if n := sameLine(lines, segs, segment{pts[0], pts[1]}, keep); n != nil {
+					keep[n] = true
+					continue
+				}
+				created = append(created, wallElement(lines[0], "line",
+					"x1", formatNumber(pts[0].X), "y1", formatNumber(pts[0].Y),
+					"x2", formatNumber(pts[1].X), "y2", formatNumber(pts[1].Y)))
+				continue
+			}
+			coords := make([]string, len(pts))
+			for i, p := range pts {
+				coords[i] = formatNumber(p.X) + "," + formatNumber(p.Y)
+			}
+			el := wallElement(lines[0], "polyline", "points", strings.Join(coords, " "))
+			// <line> не заливається, а ламана без fill: none залилася б чорним
+			if styles.property(lines[0], "fill") != "none" {
+				setAttr(el, "fill", "none")
+			}
+			created = append(created, el)
+			r.Polylines++
+		}
+		for _, n := range created {
+			k.parent.InsertBefore(n, lines[0])
+		}
+		for _, n := range lines {
+			if !keep[n] {
+				k.parent.RemoveChild(n)
+			}
+		}
+		r.After += len(keep) + len(created)
+	}
+	return r
+}
+
+// mergeCollinear зливає відрізки, що лежать на одній прямій з точністю tol і перекриваються,
+// торкаються чи розходяться не більше ніж на tol, крім розривів, для яких open повертає true (прорізи).
+func mergeCollinear(segs []segment, tol float64, open func(gap segment) bool, r *WallMergeReport) []segment {
+	// base - найдовший відрізок прямої
+	type line struct {
+		base    segment
+		members []segment
+	}
+	var list []*line
+	for _, s := range segs {
+		l := s.length()
+		if l == 0 {
+			r.Duplicates++
+			continue
+		}
+		var target *line
+		for _, c := range list {
+			b := c.base
+			sin := math.Abs((b.B.X-b.A.X)*(s.B.Y-s.A.Y)-(b.B.Y-b.A.Y)*(s.B.X-s.A.X)) / (b.length() * l)
+			if sin <= 0.01 && b.distToLine(s.A) <= tol && b.distToLine(s.B) <= tol {
+				target = c
+				break
+			}
+		}
+		if target == nil {
+			target = &line{base: s}
+			list = append(list, target)
+		}
+		target.members = append(target.members, s)
+		if l > target.base.length() {
+			target.base = s
+		}
+	}
+
+	var out []segment
+	for _, c := range list {
+		// Відрізки проєктуються на найдовший з них, тож короткі й трохи зсунуті вирівнюються за ним
+		b := c.base
+		spans := make([][2]float64, len(c.members))
+		for i, s := range c.members {
+			t0, t1 := b.project(s.A), b.project(s.B)
+			spans[i] = [2]float64{math.Min(t0, t1), math.Max(t0, t1)}
+		}
+		at := func(t float64) point {
+			k := t / b.length()
+			return point{b.A.X + k*(b.B.X-b.A.X), b.A.Y + k*(b.B.Y-b.A.Y)}
+		}
+		sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+		cur := spans[0]
+		for _, sp := range spans[1:] {
+			switch {
+			case sp[1] <= cur[1]:
+				r.Duplicates++
+			case sp[0] <= cur[1]:
+				cur[1] = sp[1]
+				r.Merged++
+			case sp[0]-cur[1] <= tol && !open(segment{at(cur[1]), at(sp[0])}):
+				cur[1] = sp[1]
+				r.Merged++
+				r.Gaps++
+			default:
+				out = append(out, segment{at(cur[0]), at(cur[1])})
+				cur = sp
+			}
+		}
+		out = append(out, segment{at(cur[0]), at(cur[1])})
+	}
+	return out
+}
+
+// chainSegments з'єднує відрізки в ламані через точки, де з точністю tol сходяться рівно два кінці
+// (кінці, між якими open бачить проріз, не з'єднуються). Замкнений контур повертається
+// з повтором першої точки в кінці.
+func chainSegments(segs []segment, tol float64, open func(gap segment) bool) [][]point {
+	end := func(e int) point {
+		if e%2 == 0 {
+			return segs[e/2].A
+		}
+		return segs[e/2].B
+	}
+	// Вузли - точки, в яких сходяться кінці; ends[v] - кінці відрізків у вузлі v
+	node := make([]int, 2*len(segs))
+	var at []point
+	var ends [][]int
+	for e := range node {
+		p := end(e)
+		node[e] = -1
+		for v, q := range at {
+			if d := dist(p, q); d == 0 || (d <= tol && !open(segment{p, q})) {
+				node[e] = v
+				break
+			}
+		}
+		if node[e] < 0 {
+			node[e] = len(at)
+			at = append(at, p)
+			ends = append(ends, nil)
+		}
+		ends[node[e]] = append(ends[node[e]], e)
+	}
+
+	used := make([]bool, len(segs))
+	walk := func(e int) []point {
+		pts := []point{at[node[e]]}
+		for {
+			i := e / 2
+			used[i] = true
+			v := node[e^1]
+			pts = append(pts, at[v])
+			next := -1
+			if len(ends[v]) == 2 {
+				for _, f := range ends[v] {
+					if f/2 != i && !used[f/2] {
+						next = f
+					}
+				}
+			}
+			if next < 0 {
+				return pts
+			}
+			e = next
+		}
+	}
+
+	var chains [][]point
+	for i, s := range segs {
+		// Відрізок, коротший за допуск, не з'єднується, щоб не стягнути його в точку
+		if node[2*i] == node[2*i+1] {
+			used[i] = true
+			chains = append(chains, []point{s.A, s.B})
+		}
+	}
+	for i := range segs {
+		for _, e := range []int{2 * i, 2*i + 1} {
+			if !used[i] && len(ends[node[e]]) != 2 {
+				chains = append(chains, walk(e))
+			}
+		}
+	}
+	// Решта - замкнені контури
+	for i := range segs {
+		if !used[i] {
+			chains = append(chains, walk(2*i))
+		}
+	}
+	// Відрізок без з'єднань лишається з власними координатами, а не координатами вузлів
+	for k, pts := range chains {
+		if len(pts) == 2 {
+			for i, s := range segs {
+				if (dist(s.A, pts[0]) <= tol && dist(s.B, pts[1]) <= tol) || (dist(s.A, pts[1]) <= tol && dist(s.B, pts[0]) <= tol) {
+					chains[k] = []point{segs[i].A, segs[i].B}
+					break
+				}
+			}
+		}
+	}
+	return chains
+}
+
+// sameLine повертає елемент з lines, чий відрізок збігається з s (в будь-якому напрямку) і ще не використаний.
+func sameLine(lines []*html.Node, segs []segment, s segment, used map[*html.Node]bool) *html.Node {
+	const eps = 1e-6
+	for i, n := range lines {
+		o := segs[i]
+		if used[n] {
+			continue
+		}
+		if (dist(o.A, s.A) < eps && dist(o.B, s.B) < eps) || (dist(o.A, s.B) < eps && dist(o.B, s.A) < eps) {
+			return n
+		}
+	}
+	return nil
+}
+
+// wallElement створює елемент стіни з атрибутами geometry, за якими йдуть атрибути зразка, крім координат і id.
+func wallElement(sample *html.Node, tag string, geometry ...string) *html.Node {
+	n := newElement(tag, geometry...)
+	n.Namespace = sample.Namespace
+	for _, a := range sample.Attr {
+		if !lineCoords[a.Key] && a.Key != "id" {
+			n.Attr = append(n.Attr, a)
+		}
+	}
+	return n
+}
+
+// lineCoords - атрибути координат <line>.
+var lineCoords = map[string]bool{"x1": true, "y1": true, "x2": true, "y2": true}
+
+// lineStyleKey повертає ключ атрибутів лінії, крім координат: лінії з однаковим ключем
+// виглядають однаково і їх можна об'єднувати.
+func lineStyleKey(n *html.Node) string {
+	var parts []string
+	for _, a := range n.Attr {
+		if !lineCoords[a.Key] {
+			parts = append(parts, a.Namespace+":"+a.Key+"="+a.Val)
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// isWall перевіряє, чи елемент - стіна: <line> або <polyline> з класом wall.
+func isWall(n *html.Node) bool {
+	return (n.Data == "line" || n.Data == "polyline") && hasClass(n, "wall")
+}
+
+// wallEdges повертає відрізки стіни в абсолютних координатах: саму лінію або ланки ламаної.
+func wallEdges(n *html.Node) []segment {
+	if n.Data == "line" {
+		return []segment{lineSegment(n)}
+	}
+	pts := polygonPoints(n)
+	var out []segment
+	for i := 0; i+1 < len(pts); i++ {
+		out = append(out, segment{pts[i], pts[i+1]})
+	}
+	return out
+}
+
+// WriteWallReport виводить звіт очищення стін як текст.
+func WriteWallReport(w io.Writer, r WallMergeReport) {
+	fmt.Fprintf(w, "елементів стін: %d → %d\n", r.Before, r.After)
+	fmt.Fprintf(w, "дублікатів видалено: %d, відрізків злито: %d, розривів закрито: %d\n", r.Duplicates, r.Merged, r.Gaps)
+	fmt.Fprintf(w, "ламаних: %d\n", r.Polylines)
+}
@@ -0,0 +1,171 @@
+package plan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// wallsStyle - стилі стін і дверей для тестів очищення стін.
+const wallsStyle = `<style>
+	.wall { stroke: #000; stroke-width: 4; }
+	.doors { stroke: #FFF; stroke-width: 8; }
+</style>`
+
+// mergeWalls зливає стіни фрагмента body і повертає звіт та координати стін після злиття:
+// "x1,y1 x2,y2" для <line> і points для <polyline>.
+func mergeWalls(t *testing.T, body string, opts WallMergeOptions) (WallMergeReport, []string) {
+	t.Helper()
+	d := parseSVG(t, `<svg viewBox="-50 -50 400 400">`+wallsStyle+body+`</svg>`)
+	r := MergeWalls(d, opts)
+	var shapes []string
+	traverse(d.SVG, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && hasClass(n, "wall") {
+			switch n.Data {
+			case "line":
+				shapes = append(shapes, getAttr(n, "x1")+","+getAttr(n, "y1")+" "+getAttr(n, "x2")+","+getAttr(n, "y2"))
+			case "polyline":
+				if getAttr(n, "fill") != "none" {
+					t.Errorf("ламана стіни без fill=\"none\" залилася б: %s", getAttr(n, "points"))
+				}
+				shapes = append(shapes, getAttr(n, "points"))
+			}
+		}
+		return false
+	})
+	return r, shapes
+}
+
+func TestMergeWallsDuplicates(t *testing.T) {
+	r, shapes := mergeWalls(t, `
+		<line class="wall" x1="0" y1="0" x2="200" y2="0"/>
+		<line class="wall" x1="0" y1="0" x2="200" y2="0"/>
+		<line class="wall" x1="200" y1="0" x2="0" y2="0"/>
+		<line class="wall" x1="50" y1="0.5" x2="120" y2="0.5"/>
+		<line class="wall" x1="80" y1="0" x2="80" y2="0"/>
+	`, WallMergeOptions{})
+	if r.Before != 5 || r.After != 1 || r.Duplicates != 4 || r.Merged != 0 {
+		t.Errorf("звіт %+v, очікувалось 5 → 1 з 4 дублікатами", r)
+	}
+	// Незмінена стіна лишається тим самим елементом з тими самими координатами
+	if !slices.Equal(shapes, []string{"0,0 200,0"}) {
+		t.Errorf("стіни %v", shapes)
+	}
+}
+
+func TestMergeWallsGaps(t *testing.T) {
+	walls := `
+		<line class="wall" x1="0" y1="0" x2="50" y2="0"/>
+		<line class="wall" x1="40" y1="0" x2="100" y2="0"/>
+		<line class="wall" x1="100.5" y1="0" x2="200" y2="0"/>
+		<line class="wall" x1="240" y1="0" x2="300" y2="0"/>`
+
+	// Розрив 0,5 закривається, 40 - більший за допуск
+	r, shapes := mergeWalls(t, walls, WallMergeOptions{})
+	if r.Merged != 2 || r.Gaps != 1 || !slices.Equal(shapes, []string{"0,0 200,0", "240,0 300,0"}) {
+		t.Errorf("допуск 1: %+v, %v", r, shapes)
+	}
+
+	// З допуском 50 закривається і розрив 40, якщо в ньому немає дверей
+	r, shapes = mergeWalls(t, walls, WallMergeOptions{Tolerance: 50})
+	if r.Gaps != 2 || !slices.Equal(shapes, []string{"0,0 300,0"}) {
+		t.Errorf("допуск 50: %+v, %v", r, shapes)
+	}
+	r, shapes = mergeWalls(t, walls+`<line class="doors" x1="200" y1="0" x2="240" y2="0"/>`, WallMergeOptions{Tolerance: 50})
+	if r.Gaps != 1 || !slices.Equal(shapes, []string{"0,0 200,0", "240,0 300,0"}) {
+		t.Errorf("проріз з дверима закрито: %+v, %v", r, shapes)
+	}
+}
+
+func TestMergeWallsJunctions(t *testing.T) {
+	// Кут з двох стін з'єднується в ламану, а в T-подібному вузлі сходяться три кінці - ланцюжка немає
+	r, shapes := mergeWalls(t, `
+		<line class="wall" x1="0" y1="0" x2="100" y2="0"/>
+		<line class="wall" x1="100" y1="100" x2="100" y2="0.4"/>
+		<line class="wall" x1="200" y1="0" x2="300" y2="0"/>
+		<line class="wall" x1="300" y1="0" x2="300" y2="100"/>
+		<line class="wall" x1="300" y1="0" x2="350" y2="-50"/>
+	`, WallMergeOptions{})
+	want := []string{"0,0 100,0 100,100", "200,0 300,0", "300,0 300,100", "300,0 350,-50"}
+	if r.Polylines != 1 || r.After != 4 || !slices.Equal(slices.Sorted(slices.Values(shapes)), want) {
+		t.Errorf("кут і T-вузол: %+v, %v", r, shapes)
+	}
+
+	// KeepLines не з'єднує кут у ламану
+	r, shapes = mergeWalls(t, `
+		<line class="wall" x1="0" y1="0" x2="100" y2="0"/>
+		<line class="wall" x1="100" y1="0" x2="100" y2="100"/>
+	`, WallMergeOptions{KeepLines: true})
+	if r.Polylines != 0 || len(shapes) != 2 {
+		t.Errorf("KeepLines: %+v, %v", r, shapes)
+	}
+}
+
+func TestMergeWallsClosedLoop(t *testing.T) {
+	r, shapes := mergeWalls(t, `
+		<line class="wall" x1="0" y1="0" x2="100" y2="0"/>
+		<line class="wall" x1="100" y1="0" x2="100" y2="100"/>
+		<line class="wall" x1="0" y1="100" x2="100" y2="100"/>
+		<line class="wall" x1="0" y1="100" x2="0" y2="0"/>
+	`, WallMergeOptions{})
+	if r.Polylines != 1 || r.After != 1 || len(shapes) != 1 {
+		t.Fatalf("замкнений контур: %+v, %v", r, shapes)
+	}
+	pts, _ := parsePoints(shapes[0])
+	if len(pts) != 5 || pts[0] != pts[4] {
+		t.Errorf("контур має бути замкнений повтором першої точки: %v", shapes[0])
+	}
+}
+
+func TestMergeWallsKeepsAnalysis(t *testing.T) {
+	// Підписи, перевірки й GeoJSON на full.html не мають залежати від того, чи стіни злито
+	type analysis struct {
+		labels  []LabelPlacement
+		lint    map[string]int
+		geojson string
+	}
+	analyze := func(d *Document) analysis {
+		var a analysis
+		a.labels = PlaceRoomLabels(d, LabelOptions{})
+		var buf bytes.Buffer
+		if err := (Serializer{}).Serialize(context.Background(), &buf, d); err != nil {
+			t.Fatal(err)
+		}
+		diags, err := Lint("full.html", buf.Bytes(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.lint = make(map[string]int)
+		for _, diag := range diags {
+			a.lint[diag.Rule]++
+		}
+		data, err := json.Marshal(GeoJSON(d, GeoReference{Lat: 50.45, Lon: 30.52}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.geojson = string(data)
+		return a
+	}
+
+	before := analyze(loadPlan(t, "full.html"))
+	d := loadPlan(t, "full.html")
+	if r := MergeWalls(d, WallMergeOptions{}); r.Polylines == 0 || r.After >= r.Before {
+		t.Fatalf("на full.html стіни мали злитися в ламані: %+v", r)
+	}
+	after := analyze(d)
+
+	if !slices.Equal(before.labels, after.labels) {
+		t.Errorf("розміщення підписів змінилося:\n%+v\n%+v", before.labels, after.labels)
+	}
+	if !maps.Equal(before.lint, after.lint) {
+		t.Errorf("діагностики змінилися: %v → %v", before.lint, after.lint)
+	}
+	if before.geojson != after.geojson {
+		t.Error("GeoJSON змінився після злиття стін")
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"simple-plan/plan"
+)
+
+// servePollInterval - як часто перевіряються зміни вхідних файлів.
+const servePollInterval = 500 * time.Millisecond
+
+// previewBuild - результат обробки одного файлу плану для сторінки перегляду.
+type previewBuild struct {
+	Source    string
+	SVG       string // імена файлів у каталозі результатів
+	MirrorSVG string
+	PNG       string
+	Err       string
+}
+
+// previewServer перебудовує плани при змінах і віддає сторінку перегляду.
+type previewServer struct {
+	sources []string
+	outDir  string
+	width   int
+
+	mu      sync.RWMutex
+	builds  []previewBuild
+	version int
+
+	hub *reloadHub
+}
+
+// reloadHub розсилає підключеним браузерам сигнал перезавантаження (server-sent events).
+type reloadHub struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]struct{}
+}
+
+// subscribe реєструє нового клієнта.
+func (h *reloadHub) subscribe() chan struct{} {
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	h.clients[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+// unsubscribe видаляє клієнта.
+func (h *reloadHub) unsubscribe(ch chan struct{}) {
+	h.mu.Lock()
+	delete(h.clients, ch)
+	h.mu.Unlock()
+}
+
+// broadcast надсилає сигнал усім клієнтам, не блокуючись на повільних.
+func (h *reloadHub) broadcast() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// newPreviewServer створює сервер перегляду; результати зберігаються в outDir.
+func newPreviewServer(sources []string, outDir string, width int) (*previewServer, error) {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, plan.Errorf("%w: помилка створення каталогу %s: %w", plan.ErrWrite, outDir, err)
+	}
+	return &previewServer{
+		sources: sources,
+		outDir:  outDir,
+		width:   width,
+		hub:     &reloadHub{clients: make(map[chan struct{}]struct{})},
+	}, nil
+}
+
+// rebuild обробляє всі вхідні файли і повідомляє браузери.
+func (s *previewServer) rebuild() {
+	builds := make([]previewBuild, len(s.sources))
+	for i, src := range s.sources {
+		builds[i] = buildPreview(src, previewName(i, src), s.outDir, s.width)
+		if builds[i].Err != "" {
+			slog.Error("помилка обробки", "file", src, "err", builds[i].Err)
+		}
+	}
+
+	s.mu.Lock()
+	s.builds = builds
+	s.version++
+	s.mu.Unlock()
+
+	slog.Info("плани перебудовано", "files", len(builds))
+	s.hub.broadcast()
+}
+
+// previewName повертає основу імен результатів для i-го вхідного файлу. Номер у списку
+// розрізняє однакові імена файлів з різних каталогів (a/plan.html і b/plan.html).
+func previewName(i int, src string) string {
+	return fmt.Sprintf("%d-%s", i+1, strings.TrimSuffix(filepath.Base(src), filepath.Ext(src)))
+}
+
+// buildPreview витягує SVG, створює дзеркальну версію та PNG для одного файлу;
+// результати називаються name.svg, name.mirror.svg і name.mirror.png.
+func buildPreview(src, name, outDir string, width int) previewBuild {
+	b := previewBuild{
+		Source:    src,
+		SVG:       name + ".svg",
+		MirrorSVG: name + ".mirror.svg",
+		PNG:       name + ".mirror.png",
+	}
+
+	svg, err := loadSVG(src)
+	if err != nil {
+		b.Err = err.Error()
+		return b
+	}
+	if _, err := plan.InjectLibrarySymbols(svg); err != nil {
+		b.Err = err.Error()
+		return b
+	}
+	if err := saveSVG(svg, filepath.Join(outDir, b.SVG)); err != nil {
+		b.Err = err.Error()
+		return b
+	}
+
+	if err := plan.Mirror(context.Background(), svg); err != nil {
+		b.Err = err.Error()
+		return b
+	}
+	mirrorPath := filepath.Join(outDir, b.MirrorSVG)
+	if err := saveSVG(svg, mirrorPath); err != nil {
+		b.Err = err.Error()
+		return b
+	}
+
+	height := width
+	if _, _, vw, vh, ok := svg.ViewBox(); ok && vw > 0 {
+		height = int(float64(width) * vh / vw)
+	}
+	if err := convertSVGToPNG(mirrorPath, filepath.Join(outDir, b.PNG), width, height); err != nil {
+		b.Err = err.Error()
+	}
+	return b
+}
+
+// watch перевіряє час зміни вхідних файлів і перебудовує плани, коли щось змінилось.
+func (s *previewServer) watch(ctx context.Context) {
+	stamps := s.stamps()
+	ticker := time.NewTicker(servePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := s.stamps()
+			if current != stamps {
+				stamps = current
+				s.rebuild()
+			}
+		}
+	}
+}
+
+// stamps повертає відбиток часу зміни і розміру всіх вхідних файлів.
+func (s *previewServer) stamps() string {
+	var sb strings.Builder
+	for _, src := range s.sources {
+		if info, err := os.Stat(src); err == nil {
+			fmt.Fprintf(&sb, "%s:%d:%d;", src, info.ModTime().UnixNano(), info.Size())
+		} else {
+			fmt.Fprintf(&sb, "%s:missing;", src)
+		}
+	}
+	return sb.String()
+}
+
+// handler повертає маршрути сервера перегляду.
+func (s *previewServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/events", s.handleEvents)
+	mux.Handle("/files/", http.StripPrefix("/files/", noCache(http.FileServer(http.Dir(s.outDir)))))
+	return mux
+}
+
+// noCache забороняє браузеру кешувати згенеровані файли.
+func noCache(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		h.ServeHTTP(w, r)
+	})
+}
+
+// previewPage - сторінка з трьома панелями на кожен план і автооновленням через SSE.
+var previewPage = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="uk">
+<head>
+    <meta charset="UTF-8">
+    <title>Перегляд планів</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 20px; background: #f4f4f4; }
+        .plan { margin-bottom: 40px; }
+        .panels { display: flex; gap: 16px; }
+        .panel { flex: 1; background: #FFF; border: 1px solid #ccc; padding: 8px; }
+        .panel img { width: 100%; height: auto; }
+        .error { color: #df0404; white-space: pre-wrap; }
+    </style>
+</head>
+<body>
+    {{range .Builds}}
+    <div class="plan">
+        <h2>{{.Source}}</h2>
+        {{if .Err}}<p class="error">{{.Err}}</p>{{end}}
+        <div class="panels">
+            <div class="panel"><h3>Витягнутий SVG</h3><img src="/files/{{.SVG}}?v={{$.Version}}" alt="{{.SVG}}"></div>
+            <div class="panel"><h3>Дзеркальний SVG</h3><img src="/files/{{.MirrorSVG}}?v={{$.Version}}" alt="{{.MirrorSVG}}"></div>
+            <div class="panel"><h3>PNG</h3><img src="/files/{{.PNG}}?v={{$.Version}}" alt="{{.PNG}}"></div>
+        </div>
+    </div>
+    {{end}}
+    <script>
+        new EventSource("/events").addEventListener("reload", () => location.reload());
+    </script>
+</body>
+</html>
+`))
+
+// handleIndex віддає сторінку перегляду.
+func (s *previewServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.RLock()
+	data := struct {
+		Builds  []previewBuild
+		Version int
+	}{s.builds, s.version}
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := previewPage.Execute(w, data); err != nil {
+		slog.Error("помилка рендерингу сторінки", "err", err)
+	}
+}
+
+// handleEvents тримає SSE-з'єднання і надсилає подію reload після кожної перебудови.
+func (s *previewServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "потоковий вивід не підтримується", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := s.hub.subscribe()
+	defer s.hub.unsubscribe(ch)
+
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			fmt.Fprint(w, "event: reload\ndata: {}\n\n")
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// previewPlan - найменший план для сервера перегляду.
+const previewPlan = `<html><body><svg viewBox="0 0 100 50">
+	<style>.wall { stroke: #000; stroke-width: 2; }</style>
+	<line class="wall" x1="10" y1="10" x2="%s" y2="10"/>
+</svg></body></html>`
+
+// writePreviewPlan записує план зі стіною до x2 у файл.
+func writePreviewPlan(t *testing.T, path, x2 string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Replace(previewPlan, "%s", x2, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPreviewRebuildUniqueNames(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a", "plan.html"), filepath.Join(dir, "b", "plan.html")
+	writePreviewPlan(t, a, "40")
+	writePreviewPlan(t, b, "90")
+
+	s, err := newPreviewServer([]string{a, b}, filepath.Join(dir, "out"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.rebuild()
+
+	if s.version != 1 || len(s.builds) != 2 {
+		t.Fatalf("після перебудови версія %d, планів %d", s.version, len(s.builds))
+	}
+	// Однакові імена файлів з різних каталогів не перезаписують результати одне одного
+	seen := make(map[string]bool)
+	for i, build := range s.builds {
+		if build.Err != "" {
+			t.Fatalf("%s: %s", build.Source, build.Err)
+		}
+		for _, name := range []string{build.SVG, build.MirrorSVG, build.PNG} {
+			if seen[name] {
+				t.Errorf("результат %s повторюється", name)
+			}
+			seen[name] = true
+			if _, err := os.Stat(filepath.Join(s.outDir, name)); err != nil {
+				t.Errorf("план %d: %v", i, err)
+			}
+		}
+	}
+	for i, want := range []string{`x2="40"`, `x2="90"`} {
+		data, err := os.ReadFile(filepath.Join(s.outDir, s.builds[i].SVG))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s не відповідає плану %s", s.builds[i].SVG, s.builds[i].Source)
+		}
+	}
+
+	// Помилка одного плану показується на сторінці, а не зупиняє сервер
+	if err := os.WriteFile(b, []byte("<html><body>без плану</body></html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s.rebuild()
+	if s.builds[0].Err != "" || s.builds[1].Err == "" {
+		t.Errorf("помилки після перебудови: %q, %q", s.builds[0].Err, s.builds[1].Err)
+	}
+}
+
+// readEvent читає рядки SSE до першої події або коментаря з префіксом prefix.
+func readEvent(t *testing.T, r *bufio.Reader, prefix string) {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("очікувався рядок %q: %v", prefix, err)
+		}
+		if strings.HasPrefix(line, prefix) {
+			return
+		}
+	}
+}
+
+func TestPreviewWatchBroadcastsReload(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "plan.html")
+	writePreviewPlan(t, src, "40")
+	s, err := newPreviewServer([]string{src}, filepath.Join(dir, "out"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.rebuild()
+
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type %q", ct)
+	}
+	events := bufio.NewReader(resp.Body)
+	readEvent(t, events, ": connected")
+
+	go s.watch(ctx)
+	// Спостерігач запам'ятовує початковий стан файлів до першої перевірки
+	time.Sleep(servePollInterval / 2)
+	// Зміна файлу (інший розмір) перебудовує план і надсилає reload
+	writePreviewPlan(t, src, "100")
+	readEvent(t, events, "event: reload")
+
+	s.mu.RLock()
+	version, svgName := s.version, s.builds[0].SVG
+	s.mu.RUnlock()
+	if version != 2 {
+		t.Errorf("версія після зміни файлу %d, очікувалось 2", version)
+	}
+	data, err := os.ReadFile(filepath.Join(s.outDir, svgName))
+	if err != nil || !strings.Contains(string(data), `x2="100"`) {
+		t.Errorf("SVG не перебудовано після зміни файлу: %v", err)
+	}
+
+	// Сторінка посилається на нову версію файлів
+	page, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer page.Body.Close()
+	body := new(strings.Builder)
+	if _, err := bufio.NewReader(page.Body).WriteTo(body); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body.String(), "/files/"+svgName+"?v=2") {
+		t.Errorf("сторінка не посилається на %s?v=2", svgName)
+	}
+}